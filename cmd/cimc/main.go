@@ -80,13 +80,23 @@ func demoMain(c *cli.Context) error {
 		fmt.Printf("system is powered %s\n", pstate)
 	}
 
-	if rfish, active, max, err := cs.RedfishInfo(ctx); err != nil {
+	if rfish, err := cs.RedfishInfo(ctx); err != nil {
 		log.Fatalf("bad news: %v", err)
 	} else {
-		fmt.Printf("Redfish enabled=%t active=%d max=%d\n", rfish, active, max)
+		fmt.Printf("Redfish enabled=%t active=%d max=%d port=%d timeout=%ds\n",
+			rfish.Enabled, rfish.ActiveSessions, rfish.MaxSessions, rfish.Port, rfish.SessionTimeout)
+		if warn := rfish.Warning(); warn != "" {
+			log.Warnf("%s", warn)
+		}
+	}
+
+	if sol, err := cs.GetSOL(ctx); err != nil {
+		log.Fatalf("failed to read sol settings: %v", err)
+	} else {
+		fmt.Printf("SOL enabled=%t baud=%d com=%s ssh-port=%d\n", sol.Enabled, sol.BaudRate, sol.ComPort, sol.SSHPort)
 	}
 
-	for _, cmd := range []string{"/show sol", "/show http", "/bios/show"} {
+	for _, cmd := range []string{"/show http", "/bios/show"} {
 		fmt.Printf("> %s\n", cmd)
 		ret, err := cs.SendCmd(ctx, cmd)
 		if err != nil {
@@ -2,6 +2,8 @@ package cimc
 
 import (
 	"context"
+	"crypto/x509"
+	"time"
 
 	goexpect "github.com/google/goexpect"
 )
@@ -18,7 +20,16 @@ func (p PowerState) String() string {
 	return []string{"Unknown", "Off", "On"}[p]
 }
 
-type CIMCSession interface {
+// BMC - a session to a baseboard management controller of any vendor, as
+// Connect returns it.  Type assert it to the capability interfaces below
+// for what it can do.
+type BMC interface {
+	// Close closes the session
+	Close(context.Context) error
+}
+
+// PowerController - power control of the host.
+type PowerController interface {
 	// PowerOn powers on the connected host
 	PowerOn(context.Context) error
 	// PowerOff powers off the connected host
@@ -27,18 +38,150 @@ type CIMCSession interface {
 	PowerCycle(context.Context) error
 	// GetPowerState gets the power state of the connected host
 	GetPowerState(context.Context) (PowerState, error)
+}
+
+// ConsoleProvider - the serial console of the host.
+type ConsoleProvider interface {
 	// OpenConsole opens a console to the connected host
 	OpenConsole(context.Context) (*goexpect.GExpect, error)
-	// CloseConsole opens a console to the connected host
+	// CloseConsole closes the console to the connected host
 	CloseConsole(context.Context) error
+}
+
+// InventoryProvider - what hardware and firmware the server has.
+type InventoryProvider interface {
+	// Inventory returns the model, serial number and such of the server
+	Inventory(context.Context) (Inventory, error)
+	// FirmwareVersions returns the firmware versions of cimc, bios, adapters and storage
+	FirmwareVersions(context.Context) ([]Firmware, error)
+}
+
+// ConfigCommander - the command line of the bmc.
+type ConfigCommander interface {
 	// SendCmd sends a command to the connected host
 	SendCmd(context.Context, string) (string, error)
-	// Close closes the session
-	Close(context.Context) error
+}
+
+// CIMCSession - everything a Cisco cimc does, over its command line, as
+// Session does it.  Sessions over other transports implement the
+// capability interfaces they can.
+type CIMCSession interface {
+	BMC
+	PowerController
+	ConsoleProvider
+	InventoryProvider
+	ConfigCommander
+
 	// RedfishEnable turns on Redfish
 	RedfishEnable(context.Context) error
 	// RedfishEnable turns off Redfish
 	RedfishDisable(context.Context) error
-	// RedfishEnable returns info on state of Redfish
-	RedfishInfo(context.Context) (bool, int, int, error)
+	// RedfishInfo returns the state of Redfish
+	RedfishInfo(context.Context) (RedfishStatus, error)
+	// UpdateFirmware updates and activates cimc or bios firmware from a url
+	UpdateFirmware(context.Context, FirmwareComponent, string, UpdateProgressFunc) error
+	// BIOSTokens returns all bios tokens with current and allowed values
+	BIOSTokens(context.Context) ([]BIOSToken, error)
+	// SetBIOSTokens sets bios tokens in one commit, returns true if a host reboot is needed
+	SetBIOSTokens(context.Context, map[string]string) (bool, error)
+	// GetNetwork returns the cimc management network settings
+	GetNetwork(context.Context) (NetworkConfig, error)
+	// SetNetwork changes the cimc management network settings, optionally reconnecting
+	SetNetwork(context.Context, NetworkConfig, bool) error
+	// GetNTP returns the ntp settings
+	GetNTP(context.Context) (NTPConfig, error)
+	// SetNTP sets the ntp servers and whether ntp is enabled
+	SetNTP(context.Context, NTPConfig) error
+	// GetTimezone returns the timezone of the cimc
+	GetTimezone(context.Context) (string, error)
+	// SetTimezone sets the timezone of the cimc
+	SetTimezone(context.Context, string) error
+	// ClockSkew returns how far the cimc clock is ahead of the local clock
+	ClockSkew(context.Context) (time.Duration, error)
+	// Users returns the local user accounts
+	Users(context.Context) ([]User, error)
+	// CreateUser creates an enabled local user account
+	CreateUser(context.Context, string, UserRole, string) (User, error)
+	// EnableUser enables a local user account
+	EnableUser(context.Context, string) error
+	// DisableUser disables a local user account
+	DisableUser(context.Context, string) error
+	// DeleteUser deletes a local user account
+	DeleteUser(context.Context, string) error
+	// SetUserRole changes the role of a local user account
+	SetUserRole(context.Context, string, UserRole) error
+	// SetUserPassword changes the password of a local user account
+	SetUserPassword(context.Context, string, string) error
+	// GetLDAP returns the ldap authentication settings
+	GetLDAP(context.Context) (LDAPConfig, error)
+	// SetLDAP changes the ldap authentication settings
+	SetLDAP(context.Context, LDAPConfig) error
+	// TestLDAPLogin logs in as a directory user in a second session
+	TestLDAPLogin(context.Context, string, string) error
+	// GetSyslog returns the remote syslog settings
+	GetSyslog(context.Context) (SyslogConfig, error)
+	// SetSyslog changes the remote syslog settings
+	SetSyslog(context.Context, SyslogConfig) error
+	// VerifySyslog checks that a test syslog message reaches this host
+	VerifySyslog(context.Context) error
+	// GetSNMP returns the snmp settings
+	GetSNMP(context.Context) (SNMPConfig, error)
+	// SetSNMP changes the snmp settings
+	SetSNMP(context.Context, SNMPConfig) error
+	// VerifySNMPTraps checks that a test trap reaches this host
+	VerifySNMPTraps(context.Context) error
+	// GenerateCSR returns a PEM certificate signing request for a new key
+	GenerateCSR(context.Context, CertificateSubject) ([]byte, error)
+	// UploadCertificate installs a PEM certificate for the new key
+	UploadCertificate(context.Context, []byte) error
+	// ServedCertificate returns the certificate of the cimc web server
+	ServedCertificate(context.Context) (*x509.Certificate, error)
+	// VMediaMappings returns the virtual media mappings
+	VMediaMappings(context.Context) ([]VMediaMapping, error)
+	// VMediaMapping returns the virtual media mapping of a volume
+	VMediaMapping(context.Context, string) (VMediaMapping, error)
+	// MapVMedia maps a virtual media image and waits for it to mount
+	MapVMedia(context.Context, VMediaMount) (VMediaMapping, error)
+	// UnmapVMedia removes a virtual media mapping
+	UnmapVMedia(context.Context, string) error
+	// SetOneTimeBoot sets the boot device for the next host boot only
+	SetOneTimeBoot(context.Context, string) error
+	// GetSOL returns the serial over lan settings
+	GetSOL(context.Context) (SOLConfig, error)
+	// SetSOL changes the serial over lan settings
+	SetSOL(context.Context, SOLConfig) error
+	// GetIPMI returns the ipmi over lan settings
+	GetIPMI(context.Context) (IPMIConfig, error)
+	// SetIPMI changes the ipmi over lan settings
+	SetIPMI(context.Context, IPMIConfig) error
+	// VerifyIPMI checks that the cimc answers an rmcp ping on a udp port
+	VerifyIPMI(context.Context, int) error
+	// CollectTechSupport fetches a tech-support bundle to a local directory
+	CollectTechSupport(context.Context, string) (string, int64, error)
+	// StorageControllers returns the storage controllers
+	StorageControllers(context.Context) ([]StorageController, error)
+	// PhysicalDrives returns the physical drives of a storage controller
+	PhysicalDrives(context.Context, string) ([]PhysicalDrive, error)
+	// VirtualDrives returns the virtual drives of a storage controller
+	VirtualDrives(context.Context, string) ([]VirtualDrive, error)
+	// CreateVirtualDrive creates a virtual drive on a storage controller
+	CreateVirtualDrive(context.Context, string, VirtualDriveSpec) (VirtualDrive, error)
+	// DeleteVirtualDrive deletes a virtual drive of a storage controller
+	DeleteVirtualDrive(context.Context, string, int) error
+	// SetBootDrive makes a virtual drive the boot drive
+	SetBootDrive(context.Context, string, int) error
+	// ClearForeignConfig clears the foreign configuration of the drives of a storage controller
+	ClearForeignConfig(context.Context, string) error
+	// VNICs returns the vNICs of a VIC adapter
+	VNICs(context.Context, string) ([]VNIC, error)
+	// SetVNIC creates or changes a vNIC of a VIC adapter
+	SetVNIC(context.Context, string, VNIC) (VNIC, error)
+	// RebootCIMC reboots the cimc and returns a new session once it is back
+	RebootCIMC(context.Context) (CIMCSession, error)
+	// FactoryDefault resets the cimc to factory default and returns a new session as user, password
+	FactoryDefault(context.Context, string, string) (CIMCSession, error)
+	// ExportConfig exports the cimc configuration to a local file
+	ExportConfig(context.Context, string, ConfigProgressFunc) (int64, error)
+	// ImportConfig imports the cimc configuration from a local file
+	ImportConfig(context.Context, string, ConfigProgressFunc) error
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/anuvu/axepect/pkg/cimc"
 	"github.com/anuvu/axepect/pkg/test"
@@ -13,17 +14,36 @@ import (
 )
 
 var port int
+var mock *test.MockCIMC
 
 func TestMain(m *testing.M) {
-	var err error
-	port, err = test.NewMockServer()
-	if err != nil {
+	cimc.PollInterval = 10 * time.Millisecond
+
+	mock = test.NewMockCIMC()
+	if err := mock.Start(); err != nil {
 		os.Exit(1)
 	}
+	port = mock.Port
 
 	os.Exit(m.Run())
 }
 
+// connect - return a session to the mock cimc.
+func connect() (cimc.CIMCSession, error) {
+	return cimc.NewSession(fmt.Sprintf("127.0.0.1:%d", port), "test", "test123")
+}
+
+// newMock - start a mock cimc of its own and return a session to it, for
+// tests that change the cimc in ways other tests should not see.
+func newMock() (*test.MockCIMC, cimc.CIMCSession, error) {
+	m := test.NewMockCIMC()
+	if err := m.Start(); err != nil {
+		return nil, nil, err
+	}
+	sess, err := cimc.NewSession(fmt.Sprintf("127.0.0.1:%d", m.Port), "test", "test123")
+	return m, sess, err
+}
+
 func TestAPIs(t *testing.T) {
 	addr := fmt.Sprintf("127.0.0.1:%d", port)
 	ctx := context.TODO()
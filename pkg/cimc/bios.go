@@ -0,0 +1,140 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// biosScopes - the scopes under /bios that hold bios tokens.
+var biosScopes = []string{"advanced", "main", "server-management"}
+
+// BIOSToken - a bios setting.
+//   Scope is the scope under /bios that holds it, for example "advanced".
+type BIOSToken struct {
+	Scope   string
+	Name    string
+	Value   string
+	Allowed []string
+}
+
+// BIOSTokens - return all bios tokens with their current and allowed values.
+func (cs *Session) BIOSTokens(ctx context.Context) ([]BIOSToken, error) {
+	tokens, err := biosTokenValues(ctx, cs)
+	if err != nil {
+		return tokens, err
+	}
+
+	scope := ""
+	for i, tok := range tokens {
+		// 'set <token> ?' lists the values the token accepts, one per line.
+		cmd := "set " + tok.Name + " ?"
+		if tok.Scope != scope {
+			scope = tok.Scope
+			cmd = "/bios/" + scope + "/" + cmd
+		}
+		resp, err := cs.SendCmd(ctx, cmd)
+		if err != nil {
+			return tokens, fmt.Errorf("failed to read allowed values of bios token %s: %v", tok.Name, err)
+		}
+		tokens[i].Allowed = []string{}
+		for _, line := range strings.Split(resp, "\n") {
+			if v := strings.TrimSpace(line); v != "" {
+				tokens[i].Allowed = append(tokens[i].Allowed, v)
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+// SetBIOSTokens - set bios tokens (name to value) and commit them together.
+//   Tokens already at the requested value are left alone.  Returns true if
+//   the host needs a reboot for the changes to take effect.  The host is not
+//   rebooted.
+func (cs *Session) SetBIOSTokens(ctx context.Context, values map[string]string) (bool, error) {
+	tokens, err := biosTokenValues(ctx, cs)
+	if err != nil {
+		return false, err
+	}
+	byName := map[string]BIOSToken{}
+	for _, tok := range tokens {
+		byName[tok.Name] = tok
+	}
+
+	names := []string{}
+	for name, value := range values {
+		tok, ok := byName[name]
+		if !ok {
+			return false, fmt.Errorf("unknown bios token '%s'", name)
+		}
+		if tok.Value != value {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	// group the changes by scope, commit does all of them at once.
+	sort.Slice(names, func(i, j int) bool {
+		a, b := byName[names[i]], byName[names[j]]
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		return a.Name < b.Name
+	})
+	scope := ""
+	for _, name := range names {
+		cmd := "set " + name + " " + quoteValue(values[name])
+		if tok := byName[name]; tok.Scope != scope {
+			scope = tok.Scope
+			cmd = "/bios/" + scope + "/" + cmd
+		}
+		if _, err := cs.SendCmd(ctx, cmd); err != nil {
+			cs.SendCmd(ctx, "discard")
+			return false, fmt.Errorf("failed to set bios token %s=%s: %v", name, values[name], err)
+		}
+	}
+
+	// the cimc offers to reboot the host now, we decline and report it.
+	// Only the line after the question says a reboot is pending, the
+	// question itself mentions one either way.
+	resp, err := cs.sendCmd(ctx, "commit", "n")
+	if err != nil {
+		return false, fmt.Errorf("failed to commit bios tokens: %v", err)
+	}
+	for _, line := range strings.Split(resp, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), biosRebootPending) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// biosRebootPending - what commit of bios tokens says when the host needs a
+// reboot, and we declined to do it now.
+const biosRebootPending = "Changes will be applied on next reboot"
+
+// biosTokenValues - return the bios tokens of all bios scopes, without
+// their allowed values, ordered by scope and name.
+func biosTokenValues(ctx context.Context, cs *Session) ([]BIOSToken, error) {
+	tokens := []BIOSToken{}
+	for _, scope := range biosScopes {
+		resp, err := cs.SendCmd(ctx, "/bios/"+scope+"/show detail")
+		if err != nil {
+			return tokens, fmt.Errorf("failed to read bios tokens in %s: %v", scope, err)
+		}
+		dets := parseDetail(resp)
+		names := []string{}
+		for name := range dets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			tokens = append(tokens, BIOSToken{Scope: scope, Name: name, Value: dets[name]})
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,75 @@
+package cimc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBIOSTokens(t *testing.T) {
+	ctx := context.TODO()
+	Convey("Given a CIMC session", t, func() {
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("BIOSTokens() lists tokens of all bios scopes", func() {
+			tokens, err := sess.BIOSTokens(ctx)
+			So(err, ShouldBeNil)
+			byName := map[string]cimc.BIOSToken{}
+			for _, tok := range tokens {
+				byName[tok.Name] = tok
+			}
+			So(byName["IntelVTD"], ShouldResemble, cimc.BIOSToken{
+				Scope: "advanced", Name: "IntelVTD", Value: "Enabled", Allowed: []string{"Enabled", "Disabled"}})
+			So(byName["CPUPerformance"].Allowed, ShouldContain, "High Throughput")
+			So(byName["TPMAdminCtrl"].Scope, ShouldEqual, "main")
+			So(byName["BaudRate"].Scope, ShouldEqual, "server-management")
+		})
+
+		Convey("SetBIOSTokens() commits tokens of several scopes", func() {
+			reboot, err := sess.SetBIOSTokens(ctx, map[string]string{
+				"IntelVTD":       "Disabled",
+				"CPUPerformance": "High Throughput",
+				"BaudRate":       "9600",
+				"SrIov":          "Enabled",
+			})
+			So(err, ShouldBeNil)
+			So(reboot, ShouldBeTrue)
+			So(m.Get("bios/advanced", "IntelVTD"), ShouldEqual, "Disabled")
+			So(m.Get("bios/advanced", "CPUPerformance"), ShouldEqual, "High Throughput")
+			So(m.Get("bios/server-management", "BaudRate"), ShouldEqual, "9600")
+		})
+
+		Convey("SetBIOSTokens() does nothing for tokens already set", func() {
+			reboot, err := sess.SetBIOSTokens(ctx, map[string]string{"IntelVTD": "Enabled"})
+			So(err, ShouldBeNil)
+			So(reboot, ShouldBeFalse)
+		})
+
+		Convey("SetBIOSTokens() does not take the reboot question for a pending reboot", func() {
+			m.Do(func(m *test.MockCIMC) {
+				m.Root.Child("bios").OnCommit = func(t *test.Term, changed map[string]string) {
+					t.Ask("Do you want to reboot the system?")
+				}
+			})
+			reboot, err := sess.SetBIOSTokens(ctx, map[string]string{"IntelVTD": "Disabled"})
+			So(err, ShouldBeNil)
+			So(reboot, ShouldBeFalse)
+			So(m.Get("bios/advanced", "IntelVTD"), ShouldEqual, "Disabled")
+		})
+
+		Convey("SetBIOSTokens() fails on bad values and changes nothing", func() {
+			_, err := sess.SetBIOSTokens(ctx, map[string]string{"IntelVTD": "Disabled", "BaudRate": "300"})
+			So(err, ShouldNotBeNil)
+			So(m.Get("bios/advanced", "IntelVTD"), ShouldEqual, "Enabled")
+
+			_, err = sess.SetBIOSTokens(ctx, map[string]string{"NoSuchToken": "Enabled"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,186 @@
+package cimc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const ctrlD = "\x04"
+
+// match the questions of 'generate-csr', like "Common Name (CN):".
+var csrFieldRe = regexp.MustCompile(`([A-Za-z][A-Za-z ()]*):[ ]*$`)
+
+// CertificateSubject - the subject of a certificate signing request.
+//   CommonName is required, Country is a two letter code.
+type CertificateSubject struct {
+	CommonName         string
+	Organization       string
+	OrganizationalUnit string
+	Locality           string
+	State              string
+	Country            string
+	Email              string
+}
+
+// answers - the answer to each 'generate-csr' question, by the start of
+// the question.
+func (s CertificateSubject) answers() map[string]string {
+	return map[string]string{
+		"Common Name":       s.CommonName,
+		"Organization Name": s.Organization,
+		"Organization Unit": s.OrganizationalUnit,
+		"Locality":          s.Locality,
+		"StateName":         s.State,
+		"Country Code":      s.Country,
+		"Email":             s.Email,
+	}
+}
+
+// GenerateCSR - have the cimc generate a new key and a certificate signing
+// request for it with subject.  Return the request as PEM.
+//   Questions we have no answer for are left at the cimc's default.
+func (cs *Session) GenerateCSR(ctx context.Context, subject CertificateSubject) ([]byte, error) {
+	if subject.CommonName == "" {
+		return nil, errors.New("a certificate signing request needs a common name")
+	}
+	if err := cs.enterScope(ctx, "certificate"); err != nil {
+		return nil, err
+	}
+	if err := cs.exp.Send("generate-csr\n"); err != nil {
+		return nil, err
+	}
+
+	answers := subject.answers()
+	questionRe := regexp.MustCompile(cs.promptRe.String() + "|" + confirmReStr + "|" + csrFieldRe.String())
+	out := ""
+	for {
+		data, _, err := cs.exp.Expect(questionRe, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate csr: %v", err)
+		}
+		data = strings.Replace(data, ctrlM, "", -1)
+		out += data
+		if cs.promptRe.MatchString(data) {
+			break
+		}
+		answer := "y"
+		if !confirmRe.MatchString(data) {
+			answer = ""
+			q := csrFieldRe.FindStringSubmatch(data)[1]
+			for start, a := range answers {
+				if strings.HasPrefix(strings.TrimSpace(q), start) {
+					answer = a
+					break
+				}
+			}
+		}
+		if err := cs.exp.Send(answer + "\n"); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "Error:") {
+			return nil, errors.New(strings.TrimSpace(line))
+		}
+	}
+	block, _ := pem.Decode([]byte(out))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("no certificate request in cimc response: %s", out)
+	}
+	if _, err := x509.ParseCertificateRequest(block.Bytes); err != nil {
+		return nil, fmt.Errorf("bad certificate request from cimc: %v", err)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// UploadCertificate - install the signed certificate certPEM, for the key
+// of the last GenerateCSR, by pasting it to the cimc.  Then wait until the
+// cimc web server serves it, until ctx is done.
+func (cs *Session) UploadCertificate(ctx context.Context, certPEM []byte) error {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("no PEM certificate to upload")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("bad certificate: %v", err)
+	}
+
+	if err := cs.enterScope(ctx, "certificate"); err != nil {
+		return err
+	}
+	if err := cs.exp.Send("paste-certificate\n"); err != nil {
+		return err
+	}
+	if _, _, err := cs.exp.Expect(regexp.MustCompile(`(?i)CTRL\+D`), timeout); err != nil {
+		return fmt.Errorf("cimc did not ask for the certificate: %v", err)
+	}
+	if err := cs.exp.Send(string(pem.EncodeToMemory(block)) + ctrlD); err != nil {
+		return err
+	}
+	resp, _, err := cs.exp.Expect(cs.promptRe, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to upload certificate: %v", err)
+	}
+	for _, line := range strings.Split(strings.Replace(resp, ctrlM, "", -1), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "Error:") {
+			return fmt.Errorf("failed to upload certificate: %s", strings.TrimSpace(line))
+		}
+	}
+
+	for {
+		served, err := cs.ServedCertificate(ctx)
+		if err == nil && served.Equal(cert) {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("serves '%s', serial %s", served.Subject, served.SerialNumber)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cimc web server does not serve the uploaded certificate: %v", err)
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// ServedCertificate - return the certificate the cimc web server serves.
+//   The certificate is not verified.
+func (cs *Session) ServedCertificate(ctx context.Context) (*x509.Certificate, error) {
+	resp, err := cs.SendCmd(ctx, "/https/show detail")
+	if err != nil {
+		return nil, err
+	}
+	port := parseDetail(resp)["HTTPS Port"]
+	if port == "" {
+		return nil, fmt.Errorf("did not find 'HTTPS Port' in %s", resp)
+	}
+	host, _, err := net.SplitHostPort(cs.addr)
+	if err != nil {
+		host = cs.addr
+	}
+
+	d := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config:    &tls.Config{InsecureSkipVerify: true},
+	}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s served no certificate", conn.RemoteAddr())
+	}
+	return certs[0], nil
+}
@@ -0,0 +1,130 @@
+package cimc_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testCA - a certificate authority that signs csrs.
+type testCA struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestCA() (*testCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	return &testCA{key: key, cert: cert}, err
+}
+
+func (ca *testCA) sign(csr *x509.CertificateRequest) ([]byte, error) {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func TestCertificate(t *testing.T) {
+	Convey("Given a CIMC session and a CA", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		ca, err := newTestCA()
+		So(err, ShouldBeNil)
+
+		Convey("the cimc serves a self-signed certificate", func() {
+			cert, err := sess.ServedCertificate(ctx)
+			So(err, ShouldBeNil)
+			So(cert.Subject.Organization, ShouldResemble, []string{"Cisco Self Signed"})
+		})
+
+		Convey("GenerateCSR() returns a csr with the subject", func() {
+			subject := cimc.CertificateSubject{
+				CommonName:   "cimc1.example.com",
+				Organization: "Example Inc",
+				Locality:     "San Jose",
+				State:        "CA",
+				Country:      "US",
+			}
+			csrPEM, err := sess.GenerateCSR(ctx, subject)
+			So(err, ShouldBeNil)
+			block, _ := pem.Decode(csrPEM)
+			So(block, ShouldNotBeNil)
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			So(err, ShouldBeNil)
+			So(csr.Subject.CommonName, ShouldEqual, "cimc1.example.com")
+			So(csr.Subject.Organization, ShouldResemble, []string{"Example Inc"})
+			So(csr.Subject.Country, ShouldResemble, []string{"US"})
+			So(csr.CheckSignature(), ShouldBeNil)
+
+			Convey("UploadCertificate() installs the signed certificate", func() {
+				certPEM, err := ca.sign(csr)
+				So(err, ShouldBeNil)
+				So(sess.UploadCertificate(ctx, certPEM), ShouldBeNil)
+
+				served, err := sess.ServedCertificate(ctx)
+				So(err, ShouldBeNil)
+				So(served.Subject.CommonName, ShouldEqual, "cimc1.example.com")
+				So(served.CheckSignatureFrom(ca.cert), ShouldBeNil)
+			})
+
+			Convey("UploadCertificate() refuses a certificate for another key", func() {
+				other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				So(err, ShouldBeNil)
+				csr.PublicKey = other.Public()
+				certPEM, err := ca.sign(csr)
+				So(err, ShouldBeNil)
+				So(sess.UploadCertificate(ctx, certPEM), ShouldNotBeNil)
+			})
+		})
+
+		Convey("GenerateCSR() refuses a bad country code", func() {
+			_, err := sess.GenerateCSR(ctx, cimc.CertificateSubject{CommonName: "cimc1", Country: "USA"})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("UploadCertificate() refuses data that is not a certificate", func() {
+			So(sess.UploadCertificate(ctx, []byte("junk")), ShouldNotBeNil)
+		})
+	})
+}
@@ -9,8 +9,6 @@ import (
 	"time"
 
 	goexpect "github.com/google/goexpect"
-	"github.com/google/goterm/term"
-	"golang.org/x/crypto/ssh"
 )
 
 const (
@@ -22,16 +20,30 @@ const (
 var noMoreCmds = []string{"commit", "top", "scope", "set", "power"}
 
 // match the 'confirm' prompt with either y or n as default ([y|N] or [Y|n])
-var confirmReStr = regexp.QuoteMeta("Do you want to continue?[") + "([yY]\\|[nN])" + regexp.QuoteMeta("]")
+// The question varies: "Do you want to continue?[y|N]",
+// "Do you want to reboot the system?[y|N]", "Continue?[y|N]"
+var confirmReStr = `[?][ ]*\[([yY]\|[nN])\]`
 var confirmRe = regexp.MustCompile(confirmReStr)
 
+// match the prompts for a password, "Please enter password:" and
+// "Please confirm password:"
+var secretPromptReStr = `(?i)(enter|confirm) [a-z ]*password:[ ]*$`
+var secretPromptRe = regexp.MustCompile(secretPromptReStr)
+
+// PollInterval - how often long running operations, like firmware
+// updates, poll the cimc for progress or retry connecting to it.
+var PollInterval = 5 * time.Second
+
 // Session - object holding info for the cimc session.
 type Session struct {
-	sshClient         *ssh.Client
-	exp               *goexpect.GExpect
-	desc              string
-	promptRe          *regexp.Regexp
-	promptOrConfirmRe *regexp.Regexp
+	shell
+	desc string
+
+	// what we need to connect again after the cimc reboots.
+	addr string
+	user string
+	pass string
+	opts []goexpect.Option
 }
 
 // NewSession - return a Session, logging in with password and user@addr
@@ -45,52 +57,54 @@ func NewSession(addr, user, pass string) (CIMCSession, error) {
 //     NewSessionOpts("10.0.0.1", "admin", "password",
 //        []goexpect.Option{goexpect.Verbose(true), goexpect.VerboseWriter(os.Stderr)})
 func NewSessionOpts(addr, user, pass string, opts []goexpect.Option) (CIMCSession, error) {
-	sess := &Session{}
+	sess := &Session{addr: addr, user: user, pass: pass, opts: opts}
 	fmt.Printf("Connecting to %s@%s\n", user, addr)
-	sshClt, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
-		User:            user,
-		Auth:            []ssh.AuthMethod{ssh.Password(pass)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	})
-
-	sess.desc = user + "@" + addr
-	sess.sshClient = sshClt
-
-	if err != nil {
-		return sess, err
-	}
-
-	// we can't simply use the SpawnSSH for connecting to a cimc because
-	// the cimc basically refuses to send content if ssh.ECHO is set to 0
-	// Presumably that is to attempt to force people to use it interactively.
-	tios := term.Termios{}
-	tios.Raw()
-	tios.Wz.WsCol, tios.Wz.WsRow = 0, 32768
-	tios.Lflag |= term.ECHO
-
-	e, _, err := goexpect.SpawnSSHPTY(sshClt, timeout, tios, opts...)
-	if err != nil {
-		return sess, err
-	}
-
-	sess.exp = e
+	return sess, sess.connect(context.Background())
+}
 
+func (cs *Session) connect(ctx context.Context) error {
 	// on connect we expect to see 'serial#'
 	// later prompt is :
 	//    <serial> path #
 	// or, if path is "top"
 	//    <serial>#
-	promptRe := regexp.MustCompile(`([-0-9a-zA-Z]*)# `)
-	_, subs, err := e.Expect(promptRe, timeout)
+	cs.desc = cs.user + "@" + cs.addr
+	_, subs, err := cs.dial(ctx, cs.addr, cs.user, cs.pass, cs.opts, cimcFirstPromptRe)
 	if err != nil {
-		return sess, err
+		return err
 	}
+	cs.start(subs[1])
+	return nil
+}
+
+// cimcFirstPromptRe - the prompt right after login, 'serial# '.
+var cimcFirstPromptRe = regexp.MustCompile(`([-0-9a-zA-Z]*)# `)
 
-	sess.desc = fmt.Sprintf("%s@%s [%s]", user, addr, subs[1])
-	promptReStr := `(` + regexp.QuoteMeta(subs[1]) + `)([ ](/[^ ]*)[ ]){0,1}([*]*)(#) `
-	sess.promptRe = regexp.MustCompile(promptReStr)
-	sess.promptOrConfirmRe = regexp.MustCompile(promptReStr + "|" + confirmReStr)
-	return sess, nil
+// start - take the command line of the cimc with serial number serial.
+func (cs *Session) start(serial string) {
+	cs.desc = fmt.Sprintf("%s@%s [%s]", cs.user, cs.addr, serial)
+	cs.setPrompt(`(` + regexp.QuoteMeta(serial) + `)([ ](/[^ ]*)[ ]){0,1}([*]*)(#) `)
+}
+
+// reconnect - drop the current connection and connect again, retrying
+// every PollInterval until ctx is done.  Used after the cimc reboots.
+func (cs *Session) reconnect(ctx context.Context) error {
+	cs.disconnect()
+
+	var err error
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up reconnecting to %s: %v (last error: %v)", cs.addr, ctx.Err(), err)
+		case <-time.After(PollInterval):
+		}
+		if err = cs.connect(ctx); err == nil {
+			return nil
+		}
+		if cs.sshClient != nil {
+			cs.sshClient.Close()
+		}
+	}
 }
 
 func (cs Session) String() string {
@@ -99,6 +113,9 @@ func (cs Session) String() string {
 
 // Close - close the ssh session.
 func (cs *Session) Close(ctx context.Context) error {
+	if cs.exp == nil {
+		return fmt.Errorf("%s is not connected", cs.desc)
+	}
 	if err := cs.exp.Close(); err != nil {
 		return err
 	}
@@ -110,7 +127,16 @@ func (cs *Session) Close(ctx context.Context) error {
 }
 
 // SendCmd - send a command to the cimc command line interface.  Return its response.
+//   Confirm prompts are answered with 'y'.
 func (cs *Session) SendCmd(ctx context.Context, msg string) (string, error) {
+	return cs.sendCmd(ctx, msg, "y")
+}
+
+// sendCmd - SendCmd, answering a confirm prompt with 'answer'.
+func (cs *Session) sendCmd(ctx context.Context, msg, answer string) (string, error) {
+	if cs.exp == nil {
+		return "", fmt.Errorf("%s is not connected", cs.desc)
+	}
 	fields := strings.Fields(msg)
 	cmd := fields[0]
 	if strings.HasPrefix(msg, "/") {
@@ -125,9 +151,11 @@ func (cs *Session) SendCmd(ctx context.Context, msg string) (string, error) {
 			return "", err
 		}
 
-		_, err = cs.SendCmd(ctx, "scope "+scope)
-		if err != nil {
-			return "", err
+		if scope != "" {
+			_, err = cs.SendCmd(ctx, "scope "+scope)
+			if err != nil {
+				return "", err
+			}
 		}
 
 		msg = cmd
@@ -149,61 +177,98 @@ func (cs *Session) SendCmd(ctx context.Context, msg string) (string, error) {
 		}
 	}
 
-	if err := cs.exp.Send(send + "\n"); err != nil {
-		return "", err
-	}
-
-	// data has
-	//  * the command we sent (due to ECHO)
-	//  * multi line response
-	//  * prompt line
-	data, _, err := cs.exp.Expect(cs.promptOrConfirmRe, timeout)
+	dataLines, err := cs.converse(send, answer)
 	if err != nil {
 		return "", err
 	}
-	if confirmRe.MatchString(data) {
-		// Confirm prompt
-		if err := cs.exp.Send("y\n"); err != nil {
-			return "", fmt.Errorf("failed to send 'y' to a confirm response: %s", err)
-		}
-		afterConfirm, _, err := cs.exp.Expect(cs.promptRe, timeout)
-		if err != nil {
-			return "", fmt.Errorf("error after confirming operation: %s", err)
+	response := strings.Join(dataLines, "\n")
+	if len(dataLines) > 0 {
+		lastLine := dataLines[len(dataLines)-1]
+		if strings.HasPrefix(lastLine, "Error:") {
+			return response + "\n", errors.New(lastLine)
 		}
-		data += afterConfirm
 	}
 
-	fulldata := strings.Replace(data, ctrlM, "", -1)
-	lines := strings.Split(fulldata, "\n")
+	return response + "\n", nil
+}
 
-	if len(lines) < 2 {
-		return "", fmt.Errorf("Failed to parse response from '%s': %s", send, data)
+// isCIMCError - whether err is what the cimc said, rather than a failure to
+// talk to it.
+func isCIMCError(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if strings.HasPrefix(err.Error(), "Error:") {
+			return true
+		}
 	}
+	return false
+}
 
-	promptLine := strings.TrimSpace(lines[len(lines)-1])
-
-	// sometimes we get multiple prompt lines in response.
-	dataLines := []string{}
-	for _, line := range lines[1 : len(lines)-1] {
-		if strings.TrimSpace(line) == promptLine {
-			continue
-		}
-		dataLines = append(dataLines, line)
+// enterScope - go to the given scopes from the top.  For scopes that take
+// an argument, like "user 3", which SendCmd("/path/cmd") can not express.
+func (cs *Session) enterScope(ctx context.Context, scopes ...string) error {
+	if _, err := cs.SendCmd(ctx, "top"); err != nil {
+		return err
 	}
-	response := strings.Join(dataLines, "\n")
-	if len(dataLines) > 0 {
-		lastLine := dataLines[len(dataLines)-1]
-		if strings.HasPrefix("Error:", lastLine) {
-			return response + "\n", errors.New(lastLine)
+	for _, scope := range scopes {
+		if _, err := cs.SendCmd(ctx, "scope "+scope); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	return response + "\n", nil
+// setSecret - 'set <name>' in the current scope, answering the cimc's
+// password prompts with secret.  The secret is never logged, even with
+// goexpect.Verbose.
+func (cs *Session) setSecret(ctx context.Context, name, secret string) error {
+	return cs.sendSecret(ctx, "set "+name, secret)
+}
+
+// sendSecret - send cmd in the current scope, answering the cimc's
+// password prompts with secret, like setSecret.
+func (cs *Session) sendSecret(ctx context.Context, cmd, secret string) error {
+	if cs.exp == nil {
+		return fmt.Errorf("%s is not connected", cs.desc)
+	}
+	if err := cs.exp.Send(cmd + "\n"); err != nil {
+		return err
+	}
+
+	secretOrPromptRe := regexp.MustCompile(cs.promptRe.String() + "|" + secretPromptReStr)
+	for {
+		data, _, err := cs.exp.Expect(secretOrPromptRe, timeout)
+		if err != nil {
+			return err
+		}
+		if !secretPromptRe.MatchString(data) {
+			lines := strings.Split(strings.TrimSpace(strings.Replace(data, ctrlM, "", -1)), "\n")
+			for _, line := range lines {
+				if strings.HasPrefix(strings.TrimSpace(line), "Error:") {
+					return errors.New(strings.TrimSpace(line))
+				}
+			}
+			return nil
+		}
+
+		prev := cs.exp.Options(goexpect.Verbose(false))
+		err = cs.exp.Send(secret + "\n")
+		cs.exp.Options(prev)
+		if err != nil {
+			return err
+		}
+	}
 }
 
 // OpenConsole - return a expect.GExpect that is hooked up to the host's console.
 // as you would get if you typed 'connect host'
+//   Fails right away if serial over lan is disabled.
 func (cs *Session) OpenConsole(ctx context.Context) (*goexpect.GExpect, error) {
+	if err := cs.checkSOL(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := cs.SendCmd(ctx, "top"); err != nil {
+		return nil, err
+	}
 	exp := cs.exp
 	if err := exp.Send("connect host\n"); err != nil {
 		return nil, err
@@ -0,0 +1,140 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigProgress - progress of a configuration export or import, as
+// passed to a ConfigProgressFunc.
+//   Operation is EXPORT or IMPORT.  Status is what the cimc says, like
+//   IN-PROGRESS, COMPLETED or FAILED, and Error why it failed.
+type ConfigProgress struct {
+	Operation string
+	Percent   int
+	Status    string
+	Error     string
+}
+
+func (p ConfigProgress) String() string {
+	s := fmt.Sprintf("%s %d%% %s", p.Operation, p.Percent, p.Status)
+	if p.Error != "" {
+		s += ": " + p.Error
+	}
+	return s
+}
+
+// ConfigProgressFunc - called by ExportConfig and ImportConfig as they go on.
+type ConfigProgressFunc func(ConfigProgress)
+
+// ExportConfig - export the whole cimc configuration to the file path on
+// this host, over http from a server started for it.  progress, if not
+// nil, is called as the export goes on.  Return the size of the file.
+func (cs *Session) ExportConfig(ctx context.Context, path string, progress ConfigProgressFunc) (int64, error) {
+	ts, err := cs.startTransfer(path)
+	if err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+
+	cmd := fmt.Sprintf("/cimc/import-export/export-config http %s:%d %s",
+		ts.Host(), ts.Port(), ts.Path())
+	if err := runConfigTransfer(ctx, cs, "EXPORT", cmd, progress); err != nil {
+		return 0, err
+	}
+	u, err := ts.wait(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("exported configuration did not arrive: %v", err)
+	}
+	return u.size, nil
+}
+
+// ImportConfig - import a configuration exported by ExportConfig from the
+// file path on this host, over http from a server started for it.
+// progress, if not nil, is called as the import goes on.
+func (cs *Session) ImportConfig(ctx context.Context, path string, progress ConfigProgressFunc) error {
+	if st, err := os.Stat(path); err != nil || st.IsDir() {
+		return fmt.Errorf("'%s' is not a configuration file", path)
+	}
+	ts, err := cs.startTransfer(path)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+
+	cmd := fmt.Sprintf("/cimc/import-export/import-config http %s:%d %s",
+		ts.Host(), ts.Port(), ts.Path())
+	return runConfigTransfer(ctx, cs, "IMPORT", cmd, progress)
+}
+
+// runConfigTransfer - start an export-config or import-config with cmd and
+// poll until the cimc says operation op, EXPORT or IMPORT, is done, or ctx
+// is done.
+//   The cimc keeps showing the last operation for a while after cmd, so a
+//   status of another operation, or COMPLETED before op was seen running,
+//   is not ours.
+func runConfigTransfer(ctx context.Context, cs *Session, op, cmd string, progress ConfigProgressFunc) error {
+	if progress == nil {
+		progress = func(ConfigProgress) {}
+	}
+	if _, err := cs.SendCmd(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to start %s: %v", strings.Fields(cmd)[0], err)
+	}
+
+	started := false
+	for {
+		p, err := configTransferStatus(ctx, cs)
+		if err != nil {
+			return err
+		}
+		completed := strings.HasPrefix(p.Status, "COMPLETED")
+		if p.Operation == op && (started || !completed) {
+			started = true
+			progress(p)
+			switch {
+			case completed:
+				return nil
+			case strings.Contains(p.Status, "FAIL") || strings.Contains(p.Status, "ERROR"):
+				return fmt.Errorf("configuration %s failed: %s", strings.ToLower(op), p)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up on configuration %s at %s: %v", strings.ToLower(op), p, ctx.Err())
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// configTransferStatus - parse the state of the last export or import.
+// Expected input looks like this:
+// Import Export:
+//    Operation: EXPORT
+//    Status: COMPLETED
+//    Progress (%): 100
+//    Error Code: 100 (No Error)
+//    Diagnostic Message: NONE
+func configTransferStatus(ctx context.Context, cs *Session) (ConfigProgress, error) {
+	resp, err := cs.SendCmd(ctx, "/cimc/import-export/show detail")
+	if err != nil {
+		return ConfigProgress{}, err
+	}
+	dets := parseDetail(resp)
+	status, ok := dets["Status"]
+	if !ok {
+		return ConfigProgress{}, fmt.Errorf("did not find import-export 'Status' in %s", resp)
+	}
+	p := ConfigProgress{Operation: dets["Operation"], Status: strings.ToUpper(status)}
+	p.Percent, _ = strconv.Atoi(dets["Progress (%)"])
+	if code := dets["Error Code"]; code != "" && !strings.Contains(code, "No Error") {
+		p.Error = code
+		if msg := dets["Diagnostic Message"]; msg != "" && msg != "NONE" {
+			p.Error += ", " + msg
+		}
+	}
+	return p, nil
+}
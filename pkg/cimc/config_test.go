@@ -0,0 +1,81 @@
+package cimc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigExportImport(t *testing.T) {
+	Convey("Given a CIMC session and a directory", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		dir, err := ioutil.TempDir("", "cimc-config")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "backup.xml")
+
+		Convey("ExportConfig() writes the configuration and reports progress", func() {
+			seen := []cimc.ConfigProgress{}
+			size, err := sess.ExportConfig(ctx, path, func(p cimc.ConfigProgress) { seen = append(seen, p) })
+			So(err, ShouldBeNil)
+			So(size, ShouldBeGreaterThan, 0)
+
+			data, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(int64(len(data)), ShouldEqual, size)
+			So(string(data), ShouldContainSubstring, `<prop name="hostname">C220-WZP2326007Q</prop>`)
+
+			So(len(seen), ShouldBeGreaterThan, 1)
+			So(seen[0].Operation, ShouldEqual, "EXPORT")
+			So(seen[len(seen)-1].Status, ShouldEqual, "COMPLETED")
+			So(seen[len(seen)-1].Percent, ShouldEqual, 100)
+
+			Convey("ImportConfig() restores it", func() {
+				m.Set("cimc/network", "hostname", "replaced-board")
+				m.Set("sol", "baud-rate", "9600")
+				So(sess.ImportConfig(ctx, path, nil), ShouldBeNil)
+				So(m.Get("cimc/network", "hostname"), ShouldEqual, "C220-WZP2326007Q")
+				So(m.Get("sol", "baud-rate"), ShouldEqual, "115200")
+			})
+
+			Convey("ImportConfig() waits out the completed export the cimc still shows", func() {
+				So(m.Get("cimc/import-export", "Operation"), ShouldEqual, "EXPORT")
+				So(m.Get("cimc/import-export", "Status"), ShouldEqual, "COMPLETED")
+				m.Set("cimc/network", "hostname", "replaced-board")
+				m.Do(func(m *test.MockCIMC) { m.ConfigStartShows = 2 })
+
+				seen := []cimc.ConfigProgress{}
+				So(sess.ImportConfig(ctx, path, func(p cimc.ConfigProgress) { seen = append(seen, p) }), ShouldBeNil)
+				So(m.Get("cimc/network", "hostname"), ShouldEqual, "C220-WZP2326007Q")
+				So(seen[0].Operation, ShouldEqual, "IMPORT")
+				So(seen[0].Status, ShouldEqual, "IN-PROGRESS")
+			})
+		})
+
+		Convey("ImportConfig() reports a bad file", func() {
+			So(ioutil.WriteFile(path, []byte("<not-a-config"), 0644), ShouldBeNil)
+			err := sess.ImportConfig(ctx, path, nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Invalid configuration file")
+			So(m.Get("cimc/import-export", "Status"), ShouldEqual, "FAILED")
+		})
+
+		Convey("ImportConfig() needs an existing file", func() {
+			So(sess.ImportConfig(ctx, filepath.Join(dir, "missing.xml"), nil), ShouldNotBeNil)
+		})
+	})
+}
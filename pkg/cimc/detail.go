@@ -0,0 +1,71 @@
+package cimc
+
+import (
+	"strings"
+)
+
+// detailBlock - one titled block of 'show detail' output.
+type detailBlock struct {
+	Title string
+	Props map[string]string
+}
+
+// parse '/chassis/show detail' output.
+// Expected input looks like this:
+// Chassis:
+//    Power: on
+//    Serial Number: WZP2326007Q
+//    Product Name:
+//    PID : APIC-SERVER-L3
+//    UUID: 13AA6335-143A-4FBE-AD2D-20487959A59B
+//    Locator LED: off
+//    Description:
+//    Asset Tag: Unknown
+func parseDetail(data string) map[string]string {
+	lines := strings.Split(data, "\n")
+	ret := map[string]string{}
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, " ") {
+			continue
+		}
+		toks := strings.SplitN(line, ":", 2)
+		if len(toks) != 2 {
+			continue
+		}
+		ret[strings.TrimSpace(toks[0])] = strings.TrimSpace(toks[1])
+	}
+	return ret
+}
+
+// parse 'show <object> detail' output listing several objects.
+// Expected input looks like this:
+// PCI Slot MLOM:
+//    Product Name: UCS VIC 1457
+//    Current FW Version: 5.1(2d)
+// PCI Slot 2:
+//    Product Name: UCS VIC 1455
+//    Current FW Version: 5.1(2d)
+func parseDetailList(data string) []detailBlock {
+	blocks := []detailBlock{}
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			if strings.HasSuffix(line, ":") {
+				blocks = append(blocks, detailBlock{
+					Title: strings.TrimSuffix(line, ":"),
+					Props: map[string]string{},
+				})
+			}
+			continue
+		}
+		toks := strings.SplitN(line, ":", 2)
+		if len(toks) != 2 || len(blocks) == 0 {
+			continue
+		}
+		blocks[len(blocks)-1].Props[strings.TrimSpace(toks[0])] = strings.TrimSpace(toks[1])
+	}
+	return blocks
+}
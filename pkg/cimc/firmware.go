@@ -0,0 +1,182 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FirmwareComponent - the kind of component a Firmware belongs to.
+type FirmwareComponent string
+
+const (
+	CIMCFirmware    FirmwareComponent = "cimc"
+	BIOSFirmware    FirmwareComponent = "bios"
+	AdapterFirmware FirmwareComponent = "adapter"
+	StorageFirmware FirmwareComponent = "storage"
+)
+
+// Firmware - running and backup firmware versions of one component.
+//   Slot is the PCI slot for adapters and storage controllers, empty otherwise.
+//   Backup is empty if the component has no backup image.
+type Firmware struct {
+	Component FirmwareComponent
+	Slot      string
+	Name      string
+	Running   string
+	Backup    string
+}
+
+func (f Firmware) String() string {
+	return fmt.Sprintf("%s running=%s backup=%s", f.id(), f.Running, f.Backup)
+}
+
+func (f Firmware) id() string {
+	if f.Slot == "" {
+		return string(f.Component)
+	}
+	return string(f.Component) + " " + f.Slot
+}
+
+// FirmwareDrift - a component whose firmware differs between two inventories.
+//   Base or Other is nil if the component is missing from that inventory.
+type FirmwareDrift struct {
+	Component FirmwareComponent
+	Slot      string
+	Base      *Firmware
+	Other     *Firmware
+}
+
+func (d FirmwareDrift) String() string {
+	ver := func(f *Firmware) string {
+		if f == nil {
+			return "(missing)"
+		}
+		return f.Running + "/" + f.Backup
+	}
+	id := Firmware{Component: d.Component, Slot: d.Slot}.id()
+	return fmt.Sprintf("%s: %s -> %s", id, ver(d.Base), ver(d.Other))
+}
+
+// FirmwareVersions - return the firmware versions of the cimc, bios,
+// adapters and storage controllers.
+func (cs *Session) FirmwareVersions(ctx context.Context) ([]Firmware, error) {
+	fws := []Firmware{}
+
+	resp, err := cs.SendCmd(ctx, "/cimc/firmware/show detail")
+	if err != nil {
+		return fws, err
+	}
+	fw := imageVersions(parseDetail(resp))
+	fw.Component = CIMCFirmware
+	if fw.Running == "" {
+		return fws, fmt.Errorf("did not find cimc firmware version in %s", resp)
+	}
+	fws = append(fws, fw)
+
+	resp, err = cs.SendCmd(ctx, "/bios/show detail")
+	if err != nil {
+		return fws, err
+	}
+	dets := parseDetail(resp)
+	fw = Firmware{Component: BIOSFirmware, Running: dets["BIOS Version"], Backup: dets["Backup BIOS Version"]}
+	if fw.Running == "" {
+		return fws, fmt.Errorf("did not find bios version in %s", resp)
+	}
+	fws = append(fws, fw)
+
+	resp, err = cs.SendCmd(ctx, "/chassis/show adapter detail")
+	if err != nil {
+		return fws, err
+	}
+	for _, b := range parseDetailList(resp) {
+		fw = imageVersions(b.Props)
+		fw.Component = AdapterFirmware
+		fw.Slot = strings.TrimPrefix(b.Title, "PCI Slot ")
+		fw.Name = b.Props["Product Name"]
+		fws = append(fws, fw)
+	}
+
+	resp, err = cs.SendCmd(ctx, "/chassis/show storageadapter detail")
+	if err != nil {
+		return fws, err
+	}
+	for _, b := range parseDetailList(resp) {
+		fws = append(fws, Firmware{
+			Component: StorageFirmware,
+			Slot:      strings.TrimPrefix(b.Title, "PCI Slot "),
+			Name:      b.Props["Product Name"],
+			Running:   b.Props["Firmware Package Build"],
+		})
+	}
+
+	return fws, nil
+}
+
+// CompareFirmware - return the components whose running or backup
+// versions differ between base and other, ordered by component and slot.
+func CompareFirmware(base, other []Firmware) []FirmwareDrift {
+	byID := func(fws []Firmware) map[string]*Firmware {
+		ret := map[string]*Firmware{}
+		for i := range fws {
+			ret[fws[i].id()] = &fws[i]
+		}
+		return ret
+	}
+	baseIDs, otherIDs := byID(base), byID(other)
+
+	drifts := []FirmwareDrift{}
+	add := func(b, o *Firmware) {
+		f := b
+		if f == nil {
+			f = o
+		}
+		drifts = append(drifts, FirmwareDrift{Component: f.Component, Slot: f.Slot, Base: b, Other: o})
+	}
+
+	for id, b := range baseIDs {
+		o, ok := otherIDs[id]
+		if !ok {
+			add(b, nil)
+		} else if b.Running != o.Running || b.Backup != o.Backup {
+			add(b, o)
+		}
+	}
+	for id, o := range otherIDs {
+		if _, ok := baseIDs[id]; !ok {
+			add(nil, o)
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Component != drifts[j].Component {
+			return drifts[i].Component < drifts[j].Component
+		}
+		return drifts[i].Slot < drifts[j].Slot
+	})
+	return drifts
+}
+
+// imageVersions - pick running and backup versions out of the
+// 'FW Image N Version' / 'FW Image N State' pairs that the cimc and
+// adapters show, falling back to 'Current FW Version'.
+func imageVersions(dets map[string]string) Firmware {
+	fw := Firmware{}
+	for i := 1; ; i++ {
+		ver, ok := dets[fmt.Sprintf("FW Image %d Version", i)]
+		if !ok {
+			break
+		}
+		state := dets[fmt.Sprintf("FW Image %d State", i)]
+		if strings.Contains(state, "RUNNING") {
+			fw.Running = ver
+		} else if strings.Contains(state, "BACKUP") {
+			fw.Backup = ver
+		}
+	}
+	if fw.Running == "" {
+		fw.Running = dets["Current FW Version"]
+	}
+	return fw
+}
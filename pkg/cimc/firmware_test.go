@@ -0,0 +1,59 @@
+package cimc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFirmware(t *testing.T) {
+	ctx := context.TODO()
+	Convey("Given a CIMC session", t, func() {
+		sess, err := connect()
+		So(err, ShouldBeNil)
+		defer sess.Close(ctx)
+
+		Convey("FirmwareVersions() lists every component", func() {
+			fws, err := sess.FirmwareVersions(ctx)
+			So(err, ShouldBeNil)
+			So(fws, ShouldResemble, []cimc.Firmware{
+				{Component: cimc.CIMCFirmware, Running: "4.1(2f)", Backup: "4.1(1d)"},
+				{Component: cimc.BIOSFirmware, Running: "C220M5.4.1.2a.0.0613200524", Backup: "C220M5.4.1.1c.0.0202200518"},
+				{Component: cimc.AdapterFirmware, Slot: "MLOM", Name: "UCS VIC 1457", Running: "5.1(2d)", Backup: "5.1(1f)"},
+				{Component: cimc.StorageFirmware, Slot: "MRAID", Name: "Cisco 12G Modular Raid Controller with 2GB cache", Running: "51.10.0-3151"},
+			})
+		})
+	})
+}
+
+func TestCompareFirmware(t *testing.T) {
+	Convey("CompareFirmware()", t, func() {
+		base := []cimc.Firmware{
+			{Component: cimc.CIMCFirmware, Running: "4.1(2f)", Backup: "4.1(1d)"},
+			{Component: cimc.BIOSFirmware, Running: "4.1.2a", Backup: "4.1.1c"},
+			{Component: cimc.AdapterFirmware, Slot: "MLOM", Running: "5.1(2d)"},
+		}
+
+		Convey("reports nothing for equal inventories", func() {
+			So(cimc.CompareFirmware(base, base), ShouldBeEmpty)
+		})
+
+		Convey("reports changed, missing and added components", func() {
+			other := []cimc.Firmware{
+				{Component: cimc.CIMCFirmware, Running: "4.1(3b)", Backup: "4.1(2f)"},
+				{Component: cimc.BIOSFirmware, Running: "4.1.2a", Backup: "4.1.1c"},
+				{Component: cimc.AdapterFirmware, Slot: "2", Running: "5.1(2d)"},
+			}
+			drift := cimc.CompareFirmware(base, other)
+			So(len(drift), ShouldEqual, 3)
+			So(drift[0].Slot, ShouldEqual, "2")
+			So(drift[0].Base, ShouldBeNil)
+			So(drift[1].Slot, ShouldEqual, "MLOM")
+			So(drift[1].Other, ShouldBeNil)
+			So(drift[2].Component, ShouldEqual, cimc.CIMCFirmware)
+			So(drift[2].String(), ShouldEqual, "cimc: 4.1(2f)/4.1(1d) -> 4.1(3b)/4.1(2f)")
+		})
+	})
+}
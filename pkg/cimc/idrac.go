@@ -0,0 +1,143 @@
+package cimc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	goexpect "github.com/google/goexpect"
+)
+
+// racadmPromptReStr - the prompt of the racadm command line of an iDRAC.
+const racadmPromptReStr = `racadm>>`
+
+var racadmPromptRe = regexp.MustCompile(racadmPromptReStr)
+
+// ctrlBackslash - ends 'console com2' on an iDRAC.
+const ctrlBackslash = "\x1c"
+
+// racadmConsoleRe - what 'console com2' answers, the way out of the
+// console or an error up to the next prompt.
+var racadmConsoleRe = regexp.MustCompile(`To end type: \^\\|(ERROR:[^\r\n]*)(?s:.*)` + racadmPromptReStr)
+
+// IDRACSession - a BMC on the racadm command line of a Dell iDRAC, over
+// ssh.
+//   It is a PowerController, a ConsoleProvider on the host serial port
+//   (console com2) and a ConfigCommander of racadm commands.
+type IDRACSession struct {
+	shell
+	cliPower
+	desc string
+}
+
+// NewIDRACSession - return an IDRACSession, logging in with password and
+// user@addr
+//   For example NewIDRACSession("10.0.0.2:22", "root", "calvin")
+func NewIDRACSession(addr, user, pass string) (*IDRACSession, error) {
+	return NewIDRACSessionOpts(addr, user, pass, []goexpect.Option{})
+}
+
+// NewIDRACSessionOpts - return an IDRACSession, with provided
+// goexpect.Option list.
+func NewIDRACSessionOpts(addr, user, pass string, opts []goexpect.Option) (*IDRACSession, error) {
+	sh := shell{}
+	if _, _, err := sh.dial(context.Background(), addr, user, pass, opts, racadmPromptRe); err != nil {
+		sh.disconnect()
+		return nil, fmt.Errorf("%s@%s: %v", user, addr, err)
+	}
+	return newIDRACSession(sh, addr, user), nil
+}
+
+// newIDRACSession - return an IDRACSession on sh, logged in and at the
+// first racadm prompt.
+func newIDRACSession(sh shell, addr, user string) *IDRACSession {
+	is := &IDRACSession{shell: sh, desc: user + "@" + addr + " [idrac]"}
+	is.setPrompt(racadmPromptReStr)
+	is.cliPower = cliPower{
+		on:    "serveraction powerup",
+		off:   "serveraction powerdown",
+		cycle: "serveraction powercycle",
+		state: is.GetPowerState,
+		send:  is.SendCmd,
+	}
+	return is
+}
+
+func (is IDRACSession) String() string {
+	return is.desc
+}
+
+// Close - log out of the iDRAC.
+func (is *IDRACSession) Close(ctx context.Context) error {
+	return is.logout(is.desc)
+}
+
+// SendCmd - send a racadm command, like "getversion" or "racadm get
+// iDRAC.NIC.MACAddress".  Return its response.
+//   An 'ERROR:' line in the response is returned as the error.
+func (is *IDRACSession) SendCmd(ctx context.Context, msg string) (string, error) {
+	if is.exp == nil {
+		return "", fmt.Errorf("%s is not connected", is.desc)
+	}
+	lines, err := is.converse(msg, "y")
+	if err != nil {
+		return "", err
+	}
+	response := strings.Join(lines, "\n") + "\n"
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "ERROR:") {
+			return response, errors.New(line)
+		}
+	}
+	return response, nil
+}
+
+// GetPowerState - return the power state of the server, from 'serveraction
+// powerstatus'.
+func (is *IDRACSession) GetPowerState(ctx context.Context) (PowerState, error) {
+	resp, err := is.SendCmd(ctx, "serveraction powerstatus")
+	if err != nil {
+		return Unknown, err
+	}
+	for _, line := range strings.Split(resp, "\n") {
+		toks := strings.SplitN(line, ":", 2)
+		if len(toks) != 2 || strings.TrimSpace(toks[0]) != "Server power status" {
+			continue
+		}
+		switch strings.TrimSpace(toks[1]) {
+		case "ON":
+			return On, nil
+		case "OFF":
+			return Off, nil
+		}
+		return Unknown, fmt.Errorf("bad power state '%s'", strings.TrimSpace(toks[1]))
+	}
+	return Unknown, fmt.Errorf("did not find power state in %s", resp)
+}
+
+// OpenConsole - attach to the host serial console with 'console com2'.
+//   Return the expecter of the session; CloseConsole detaches.
+func (is *IDRACSession) OpenConsole(ctx context.Context) (*goexpect.GExpect, error) {
+	if is.exp == nil {
+		return nil, fmt.Errorf("%s is not connected", is.desc)
+	}
+	if err := is.exp.Send("console com2\n"); err != nil {
+		return nil, err
+	}
+	_, subs, err := is.exp.Expect(racadmConsoleRe, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if subs[1] != "" {
+		// back at the racadm prompt.
+		return nil, fmt.Errorf("%s: console com2: %s", is.desc, subs[1])
+	}
+	return is.exp, nil
+}
+
+// CloseConsole - detach from the host console with ctrl-\, back to racadm.
+func (is *IDRACSession) CloseConsole(ctx context.Context) error {
+	return is.detach(is.desc, ctrlBackslash)
+}
@@ -0,0 +1,127 @@
+package cimc_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/loginshell"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIDRACSession(t *testing.T) {
+	Convey("Given a mock iDRAC", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m := test.NewMockIDRAC()
+		So(m.Start(), ShouldBeNil)
+		defer m.Close()
+		addr := fmt.Sprintf("127.0.0.1:%d", m.Port)
+
+		Convey("NewIDRACSession() needs the right password", func() {
+			_, err := cimc.NewIDRACSession(addr, test.IDRACUser, "wrong")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Given an iDRAC session", func() {
+			is, err := cimc.NewIDRACSession(addr, test.IDRACUser, test.IDRACPassword)
+			So(err, ShouldBeNil)
+			defer is.Close(ctx)
+
+			Convey("Power operations change the power state", func() {
+				state, err := is.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.On)
+				So(is.PowerOn(ctx), ShouldBeNil)
+
+				So(is.PowerOff(ctx), ShouldBeNil)
+				So(m.Get("Power"), ShouldEqual, "OFF")
+				state, err = is.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.Off)
+				So(is.PowerOff(ctx), ShouldBeNil)
+
+				So(is.PowerCycle(ctx), ShouldBeNil)
+				So(m.Get("Power"), ShouldEqual, "ON")
+				So(is.PowerCycle(ctx), ShouldBeNil)
+				So(m.Get("Power"), ShouldEqual, "ON")
+			})
+
+			Convey("SendCmd() runs racadm commands", func() {
+				resp, err := is.SendCmd(ctx, "getversion")
+				So(err, ShouldBeNil)
+				So(resp, ShouldContainSubstring, "iDRAC Version                    = 5.10.00.00")
+
+				resp, err = is.SendCmd(ctx, "racadm set iDRAC.Serial.BaudRate 57600")
+				So(err, ShouldBeNil)
+				So(resp, ShouldContainSubstring, "Object value modified successfully")
+				So(m.Get("iDRAC.Serial.BaudRate"), ShouldEqual, "57600")
+
+				resp, err = is.SendCmd(ctx, "get iDRAC.Serial.BaudRate")
+				So(err, ShouldBeNil)
+				So(resp, ShouldEqual, "[Key=iDRAC.Embedded.1#Serial.1]\nBaudRate=57600\n")
+
+				_, err = is.SendCmd(ctx, "no-such-command")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "ERROR: Invalid subcommand specified.")
+			})
+
+			Convey("It has only the capabilities of racadm", func() {
+				var b cimc.BMC = is
+				_, ok := b.(cimc.InventoryProvider)
+				So(ok, ShouldBeFalse)
+				_, ok = b.(cimc.CIMCSession)
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("OpenConsole() fails while serial redirection is disabled", func() {
+				m.Set("iDRAC.SerialRedirection.Enable", "Disabled")
+				_, err := is.OpenConsole(ctx)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "Serial redirection is disabled")
+
+				// the session is still usable.
+				_, err = is.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+			})
+
+			Convey("loginshell logs in on the console", func() {
+				exp, err := is.OpenConsole(ctx)
+				So(err, ShouldBeNil)
+
+				shell, err := loginshell.Login(exp, test.HostUser, test.HostPassword)
+				So(err, ShouldBeNil)
+				ret := shell.Run("echo hello from the host")
+				So(ret.RC, ShouldEqual, 0)
+				So(ret.Output, ShouldEqual, "hello from the host")
+				So(shell.Logout(), ShouldBeNil)
+				So(is.CloseConsole(ctx), ShouldBeNil)
+
+				// back at racadm.
+				state, err := is.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.On)
+
+				exp, err = is.OpenConsole(ctx)
+				So(err, ShouldBeNil)
+				So(exp.Send("\n"), ShouldBeNil)
+				_, _, err = exp.Expect(regexp.MustCompile("login:"), 5*time.Second)
+				So(err, ShouldBeNil)
+				So(is.CloseConsole(ctx), ShouldBeNil)
+			})
+
+			Convey("A closed session is not connected", func() {
+				So(is.Close(ctx), ShouldBeNil)
+				err := is.CloseConsole(ctx)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "is not connected")
+				So(is.Close(ctx), ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+)
+
+// Inventory - what the bmc tells about the server.
+//   Processors, ProcessorModel and MemoryGiB are 0 and empty where the bmc
+//   does not tell, like on the cimc command line.
+type Inventory struct {
+	Manufacturer   string
+	Model          string
+	SerialNumber   string
+	UUID           string
+	BIOSVersion    string
+	Processors     int
+	ProcessorModel string
+	MemoryGiB      float64
+	PowerState     PowerState
+}
+
+// Inventory - return the model, serial number, uuid, bios version and
+// power state of the server.
+func (cs *Session) Inventory(ctx context.Context) (Inventory, error) {
+	resp, err := cs.SendCmd(ctx, "/chassis/show detail")
+	if err != nil {
+		return Inventory{}, err
+	}
+	chassis := parseDetail(resp)
+	if resp, err = cs.SendCmd(ctx, "/bios/show detail"); err != nil {
+		return Inventory{}, err
+	}
+	bios := parseDetail(resp)
+
+	inv := Inventory{
+		Manufacturer: "Cisco Systems Inc",
+		Model:        chassis["Product Name"],
+		SerialNumber: chassis["Serial Number"],
+		UUID:         chassis["UUID"],
+		BIOSVersion:  bios["BIOS Version"],
+	}
+	switch chassis["Power"] {
+	case "on":
+		inv.PowerState = On
+	case "off":
+		inv.PowerState = Off
+	default:
+		return inv, fmt.Errorf("bad power state '%s'", chassis["Power"])
+	}
+	return inv, nil
+}
@@ -0,0 +1,131 @@
+package cimc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// IPMIPort - the standard udp port of ipmi over lan (RMCP).
+const IPMIPort = 623
+
+// IPMIConfig - ipmi over lan settings of the cimc (/ipmi).
+//   PrivilegeLimit is the highest privilege an ipmi session gets: readonly,
+//   user or admin.  EncryptionKey is the key (kg) for RMCP+ sessions, an
+//   even number of hex digits up to 40, all zeros for none.
+type IPMIConfig struct {
+	Enabled        bool   `cimc:"enabled,Enabled"`
+	PrivilegeLimit string `cimc:"privilege-level,Privilege Level Limit"`
+	EncryptionKey  string `cimc:"encryption-key,Encryption Key"`
+}
+
+// GetIPMI - return the ipmi over lan settings.
+func (cs *Session) GetIPMI(ctx context.Context) (IPMIConfig, error) {
+	cfg := IPMIConfig{}
+	resp, err := cs.SendCmd(ctx, "/ipmi/show detail")
+	if err != nil {
+		return cfg, err
+	}
+	err = decodeDetail(parseDetail(resp), &cfg)
+	return cfg, err
+}
+
+// SetIPMI - change the ipmi over lan settings to cfg.
+func (cs *Session) SetIPMI(ctx context.Context, cfg IPMIConfig) error {
+	if k := cfg.EncryptionKey; k != "" {
+		if _, err := hex.DecodeString(k); err != nil || len(k) > 40 {
+			return fmt.Errorf("bad ipmi encryption key, want an even number of hex digits, up to 40")
+		}
+	}
+	cur, err := cs.GetIPMI(ctx)
+	if err != nil {
+		return err
+	}
+	return commitCmds(ctx, cs, "/ipmi", setChanges(cur, cfg))
+}
+
+// VerifyIPMI - check that ipmi over lan is enabled, and that the cimc
+// answers an RMCP ping from this host on udp port, usually IPMIPort.
+func (cs *Session) VerifyIPMI(ctx context.Context, port int) error {
+	cfg, err := cs.GetIPMI(ctx)
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled {
+		return fmt.Errorf("ipmi over lan is disabled on %s", cs.desc)
+	}
+	host, _, err := net.SplitHostPort(cs.addr)
+	if err != nil {
+		host = cs.addr
+	}
+	return PingRMCP(ctx, net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+const (
+	asfIANA     = 4542
+	asfPing     = 0x80
+	asfPong     = 0x40
+	rmcpVersion = 0x06
+	rmcpASF     = 0x06
+)
+
+// PingRMCP - send an RMCP (ASF presence) ping to addr, host:port, and wait
+// for the pong, retrying every second until ctx is done, or for at most
+// 'timeout'.  Fails if the pong does not say ipmi is supported.
+func PingRMCP(ctx context.Context, addr string) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	tag := byte(time.Now().UnixNano())
+	// RMCP header (version, reserved, sequence 0xff: no ack, class ASF),
+	// then the ASF message header, without data.
+	ping := []byte{rmcpVersion, 0, 0xff, rmcpASF, 0, 0, 0, 0, asfPing, tag, 0, 0}
+	binary.BigEndian.PutUint32(ping[4:8], asfIANA)
+
+	buf := make([]byte, 512)
+	for time.Now().Before(deadline) {
+		if _, err := conn.Write(ping); err != nil {
+			return err
+		}
+		wait := time.Now().Add(time.Second)
+		if wait.After(deadline) {
+			wait = deadline
+		}
+		conn.SetReadDeadline(wait)
+		n, err := conn.Read(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return fmt.Errorf("rmcp ping to %s failed: %v", addr, err)
+		}
+		return parsePong(buf[:n], tag)
+	}
+	return fmt.Errorf("no rmcp pong from %s", addr)
+}
+
+// parsePong - check an ASF presence pong.  Its data is the IANA and OEM
+// numbers, then the supported entities, whose top bit is ipmi.
+func parsePong(pong []byte, tag byte) error {
+	if len(pong) < 21 || pong[0] != rmcpVersion || pong[3] != rmcpASF {
+		return fmt.Errorf("bad rmcp pong %x", pong)
+	}
+	if binary.BigEndian.Uint32(pong[4:8]) != asfIANA || pong[8] != asfPong || pong[9] != tag {
+		return fmt.Errorf("rmcp pong %x does not answer our ping", pong)
+	}
+	if pong[20]&0x80 == 0 {
+		return fmt.Errorf("rmcp pong %x does not announce ipmi", pong)
+	}
+	return nil
+}
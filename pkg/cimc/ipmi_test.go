@@ -0,0 +1,54 @@
+package cimc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIPMI(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("GetIPMI() returns the defaults", func() {
+			cfg, err := sess.GetIPMI(ctx)
+			So(err, ShouldBeNil)
+			So(cfg, ShouldResemble, cimc.IPMIConfig{
+				Enabled: false, PrivilegeLimit: "admin", EncryptionKey: "0000000000000000000000000000000000000000"})
+		})
+
+		Convey("VerifyIPMI() fails while ipmi is disabled", func() {
+			So(sess.VerifyIPMI(ctx, m.IPMIPort), ShouldNotBeNil)
+
+			short, cancel := context.WithTimeout(ctx, 1500*time.Millisecond)
+			defer cancel()
+			So(cimc.PingRMCP(short, fmt.Sprintf("127.0.0.1:%d", m.IPMIPort)), ShouldNotBeNil)
+		})
+
+		Convey("SetIPMI() enables ipmi, which answers pings", func() {
+			want := cimc.IPMIConfig{Enabled: true, PrivilegeLimit: "user", EncryptionKey: "0123456789abcdef0123456789abcdef01234567"}
+			So(sess.SetIPMI(ctx, want), ShouldBeNil)
+			cfg, err := sess.GetIPMI(ctx)
+			So(err, ShouldBeNil)
+			So(cfg, ShouldResemble, want)
+			So(sess.VerifyIPMI(ctx, m.IPMIPort), ShouldBeNil)
+		})
+
+		Convey("SetIPMI() refuses a bad encryption key", func() {
+			So(sess.SetIPMI(ctx, cimc.IPMIConfig{Enabled: true, PrivilegeLimit: "admin", EncryptionKey: "xyz"}), ShouldNotBeNil)
+			err := sess.SetIPMI(ctx, cimc.IPMIConfig{Enabled: true, PrivilegeLimit: "admin", EncryptionKey: "abc"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "even number of hex digits")
+		})
+	})
+}
@@ -0,0 +1,717 @@
+package cimc
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	goexpect "github.com/google/goexpect"
+)
+
+// IPMISession - a BMC over IPMI v2.0 RMCP+, for servers that only speak
+// ipmi over lan.
+//   It is a PowerController and a ConsoleProvider, over serial over lan.
+//   Sessions use cipher suite 3: RAKP-HMAC-SHA1, HMAC-SHA1-96 integrity
+//   and AES-CBC-128 encryption.
+type IPMISession struct {
+	conn *net.UDPConn
+	desc string
+	user []byte
+	// kuid is the password, kg the bmc key, both padded to 20 bytes.
+	kuid, kg []byte
+
+	// mu serializes requests, there is one outstanding at a time.
+	mu      sync.Mutex
+	rqSeq   byte
+	replies chan ipmiPacket
+	done    chan struct{}
+
+	// keyMu guards the session keys and numbers, set once RAKP is done.
+	keyMu     sync.Mutex
+	consoleID uint32
+	bmcID     uint32
+	seq       uint32
+	k1, k2    []byte
+	// inSeq is the highest session sequence number received, inSeen has
+	// bit i set if inSeq-i was received too.
+	inSeq  uint32
+	inSeen uint32
+
+	solMu sync.Mutex
+	sol   *solConsole
+}
+
+// ipmiPacket - a payload received, decrypted and checked.
+type ipmiPacket struct {
+	ptype byte
+	data  []byte
+}
+
+const (
+	rmcpIPMI = 0x07
+
+	authRMCPPlus = 0x06
+
+	payloadIPMI        = 0x00
+	payloadSOL         = 0x01
+	payloadOpenRequest = 0x10
+	payloadOpenReply   = 0x11
+	payloadRAKP1       = 0x12
+	payloadRAKP2       = 0x13
+	payloadRAKP3       = 0x14
+	payloadRAKP4       = 0x15
+
+	netFnChassis = 0x00
+	netFnApp     = 0x06
+
+	roleUser     = 0x02
+	roleOperator = 0x03
+	roleAdmin    = 0x04
+)
+
+// ipmiRetry - how long a request waits for its reply before it is sent
+// again, udp may lose either.
+var ipmiRetry = time.Second
+
+// ipmiSeqWindow - how far behind the highest session sequence number
+// received a packet may be, udp may reorder them.  Older ones and
+// duplicates are dropped, so a packet can not be replayed.
+const ipmiSeqWindow = 32
+
+// rakpStatus - RMCP+ status codes of session setup.
+var rakpStatus = map[byte]string{
+	0x01: "insufficient resources to create a session",
+	0x02: "invalid session id",
+	0x09: "unauthorized role or privilege level requested",
+	0x0d: "unauthorized name",
+	0x0f: "invalid integrity check value",
+	0x11: "no cipher suite match with proposed security algorithms",
+}
+
+// errRole - the bmc refused the role asked for, a lower one may do.
+var errRole = errors.New(rakpStatus[0x09])
+
+// completionCodes - ipmi completion codes, for errors.
+var completionCodes = map[byte]string{
+	0xc0: "node busy",
+	0xc1: "invalid command",
+	0xc3: "timeout",
+	0xc7: "request data length invalid",
+	0xcc: "invalid data field in request",
+	0xd4: "insufficient privilege level",
+	0xd5: "command not supported in present state",
+	0xff: "unspecified error",
+}
+
+// NewIPMISession - return an IPMISession to the bmc at addr, host or
+// host:port, logging in with user and pass.  The bmc key (kg) is all zeros.
+func NewIPMISession(addr, user, pass string) (*IPMISession, error) {
+	return NewIPMISessionKey(addr, user, pass, "")
+}
+
+// NewIPMISessionKey - like NewIPMISession for a bmc with an encryption key
+// (kg), an even number of hex digits up to 40 like IPMIConfig.EncryptionKey.
+//   The session gets the highest privilege the user and the bmc allow, out
+//   of administrator, operator and user.  Power needs operator.
+func NewIPMISessionKey(addr, user, pass, key string) (*IPMISession, error) {
+	return newIPMISession(context.Background(), addr, user, pass, key)
+}
+
+// newIPMISession - NewIPMISessionKey, giving up on the login when ctx is
+// done.
+func newIPMISession(ctx context.Context, addr, user, pass, key string) (*IPMISession, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(IPMIPort))
+	}
+	if len(user) > 16 || len(pass) > 20 {
+		return nil, fmt.Errorf("ipmi user names are at most 16 characters, passwords 20")
+	}
+	kg, err := hex.DecodeString(key)
+	if err != nil || len(kg) > 20 {
+		return nil, fmt.Errorf("bad ipmi encryption key, want an even number of hex digits, up to 40")
+	}
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+	s := &IPMISession{
+		conn:    conn,
+		desc:    user + "@" + addr + " [ipmi]",
+		user:    []byte(user),
+		kuid:    pad20([]byte(pass)),
+		kg:      pad20(kg),
+		replies: make(chan ipmiPacket, 16),
+		done:    make(chan struct{}),
+	}
+	if bytes.Equal(s.kg, make([]byte, 20)) {
+		s.kg = s.kuid
+	}
+	fmt.Printf("Connecting to %s\n", s.desc)
+	go s.receive()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := s.open(ctx); err != nil {
+		s.stop()
+		return nil, fmt.Errorf("ipmi login to %s failed: %v", addr, err)
+	}
+	return s, nil
+}
+
+func pad20(b []byte) []byte {
+	p := make([]byte, 20)
+	copy(p, b)
+	return p
+}
+
+// open - check the bmc does ipmi v2.0, then set up the session, asking for
+// a lower role whenever the bmc refuses one.
+func (s *IPMISession) open(ctx context.Context) error {
+	caps, err := s.request(ctx, netFnApp, 0x38, []byte{0x8e, roleAdmin})
+	if err != nil {
+		return err
+	}
+	if len(caps) < 4 || caps[1]&0x80 == 0 || caps[3]&0x02 == 0 {
+		return fmt.Errorf("bmc does not support ipmi v2.0")
+	}
+	for _, role := range []byte{roleAdmin, roleOperator, roleUser} {
+		if err = s.rakp(ctx, role); err != errRole {
+			break
+		}
+	}
+	return err
+}
+
+// rakp - open a session and authenticate with RAKP messages 1 to 4, then
+// derive the keys.
+func (s *IPMISession) rakp(ctx context.Context, role byte) error {
+	consoleID := randUint32()
+	req := make([]byte, 32)
+	req[0] = byte(consoleID)
+	req[1] = role
+	binary.LittleEndian.PutUint32(req[4:8], consoleID)
+	// authentication, integrity and confidentiality algorithms.
+	copy(req[8:], []byte{0x00, 0, 0, 0x08, 0x01, 0, 0, 0})
+	copy(req[16:], []byte{0x01, 0, 0, 0x08, 0x01, 0, 0, 0})
+	copy(req[24:], []byte{0x02, 0, 0, 0x08, 0x01, 0, 0, 0})
+	resp, err := s.exchange(ctx, payloadOpenRequest, req, payloadOpenReply, 36)
+	if err != nil {
+		return err
+	}
+	bmcID := binary.LittleEndian.Uint32(resp[8:12])
+
+	// RAKP 1: who we are, and our random number.
+	rm := make([]byte, 16)
+	rand.Read(rm)
+	rakp1 := make([]byte, 28, 28+len(s.user))
+	rakp1[0] = req[0] + 1
+	binary.LittleEndian.PutUint32(rakp1[4:8], bmcID)
+	copy(rakp1[8:24], rm)
+	rakp1[24] = role
+	rakp1[27] = byte(len(s.user))
+	rakp1 = append(rakp1, s.user...)
+	resp, err = s.exchange(ctx, payloadRAKP1, rakp1, payloadRAKP2, 60)
+	if err != nil {
+		return err
+	}
+
+	// RAKP 2: the bmc random number and guid, proving it knows the password.
+	rc, guid := resp[8:24], resp[24:40]
+	ids := make([]byte, 8)
+	binary.LittleEndian.PutUint32(ids[0:4], consoleID)
+	binary.LittleEndian.PutUint32(ids[4:8], bmcID)
+	name := append([]byte{role, byte(len(s.user))}, s.user...)
+	if !hmac.Equal(resp[40:60], hmacSHA1(s.kuid, ids, rm, rc, guid, name)) {
+		return fmt.Errorf("wrong password for %s", s.user)
+	}
+
+	// RAKP 3: prove we know it too.
+	sik := hmacSHA1(s.kg, rm, rc, name)
+	rakp3 := make([]byte, 8, 28)
+	rakp3[0] = req[0] + 2
+	binary.LittleEndian.PutUint32(rakp3[4:8], bmcID)
+	rakp3 = append(rakp3, hmacSHA1(s.kuid, rc, ids[0:4], name)...)
+	resp, err = s.exchange(ctx, payloadRAKP3, rakp3, payloadRAKP4, 20)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(resp[8:20], hmacSHA1(sik, rm, ids[4:8], guid)[:12]) {
+		return fmt.Errorf("bad integrity check from bmc, is the encryption key right?")
+	}
+
+	s.keyMu.Lock()
+	s.consoleID, s.bmcID = consoleID, bmcID
+	s.k1 = hmacSHA1(sik, bytes.Repeat([]byte{1}, 20))
+	s.k2 = hmacSHA1(sik, bytes.Repeat([]byte{2}, 20))
+	s.keyMu.Unlock()
+	return nil
+}
+
+func hmacSHA1(key []byte, parts ...[]byte) []byte {
+	h := hmac.New(sha1.New, key)
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+func randUint32() uint32 {
+	b := make([]byte, 4)
+	rand.Read(b)
+	// zero is no session.
+	return binary.LittleEndian.Uint32(b) | 1
+}
+
+// exchange - send a session setup message and return the reply, which is
+// at least size long, with the same tag and a good status.
+func (s *IPMISession) exchange(ctx context.Context, ptype byte, msg []byte, want byte, size int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.roundTrip(ctx, ptype, msg, true, func(p ipmiPacket) bool {
+		return p.ptype == want && len(p.data) >= 2 && p.data[0] == msg[0]
+	}, func(p ipmiPacket) ([]byte, error) {
+		if st := p.data[1]; st == 0x09 {
+			return nil, errRole
+		} else if st != 0 {
+			if text, ok := rakpStatus[st]; ok {
+				return nil, fmt.Errorf("%s", text)
+			}
+			return nil, fmt.Errorf("session setup failed with status %#x", st)
+		}
+		if len(p.data) < size {
+			return nil, fmt.Errorf("short session setup reply %x", p.data)
+		}
+		return p.data, nil
+	})
+}
+
+// request - send an ipmi request and return the data of its response,
+// after the completion code, which must be 0.  The request is sent again
+// if no response comes, so it must be one that can be done twice.
+func (s *IPMISession) request(ctx context.Context, netFn, cmd byte, data []byte) ([]byte, error) {
+	return s.call(ctx, netFn, cmd, data, true)
+}
+
+// requestOnce - like request, for a request that must not be done twice.
+// It is sent once, and waits for its response until ctx is done.
+func (s *IPMISession) requestOnce(ctx context.Context, netFn, cmd byte, data []byte) ([]byte, error) {
+	return s.call(ctx, netFn, cmd, data, false)
+}
+
+func (s *IPMISession) call(ctx context.Context, netFn, cmd byte, data []byte, resend bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rqSeq = (s.rqSeq + 1) & 0x3f
+	seq := s.rqSeq
+
+	msg := []byte{0x20, netFn << 2, 0, 0x81, seq << 2, cmd}
+	msg[2] = checksum(msg[0:2])
+	msg = append(msg, data...)
+	msg = append(msg, checksum(msg[3:]))
+
+	return s.roundTrip(ctx, payloadIPMI, msg, resend, func(p ipmiPacket) bool {
+		d := p.data
+		return p.ptype == payloadIPMI && len(d) >= 8 && d[1]>>2 == netFn+1 && d[4]>>2 == seq && d[5] == cmd
+	}, func(p ipmiPacket) ([]byte, error) {
+		d := p.data
+		if cc := d[6]; cc != 0 {
+			text, ok := completionCodes[cc]
+			if !ok {
+				text = fmt.Sprintf("completion code %#x", cc)
+			}
+			return d[7 : len(d)-1], &ipmiError{netFn: netFn, cmd: cmd, code: cc, text: text}
+		}
+		return d[7 : len(d)-1], nil
+	})
+}
+
+// ipmiError - an ipmi command that failed with a completion code.
+type ipmiError struct {
+	netFn, cmd, code byte
+	text             string
+}
+
+func (e *ipmiError) Error() string {
+	return fmt.Sprintf("ipmi command %#x/%#x failed: %s", e.netFn, e.cmd, e.text)
+}
+
+func checksum(b []byte) byte {
+	var c byte
+	for _, x := range b {
+		c += x
+	}
+	return -c
+}
+
+// roundTrip - send msg until a reply matches, or just once if not resend,
+// then return what check says of it.  The caller holds mu.
+func (s *IPMISession) roundTrip(ctx context.Context, ptype byte, msg []byte, resend bool,
+	match func(ipmiPacket) bool, check func(ipmiPacket) ([]byte, error)) ([]byte, error) {
+	for {
+		if err := s.send(ptype, msg); err != nil {
+			return nil, err
+		}
+		var retry <-chan time.Time
+		if resend {
+			retry = time.After(ipmiRetry)
+		}
+	wait:
+		for {
+			select {
+			case p := <-s.replies:
+				if match(p) {
+					return check(p)
+				}
+			case <-retry:
+				break wait
+			case <-s.done:
+				return nil, fmt.Errorf("ipmi session %s is closed", s.desc)
+			case <-ctx.Done():
+				return nil, fmt.Errorf("no ipmi reply from %s: %v", s.desc, ctx.Err())
+			}
+		}
+	}
+}
+
+// send - send a payload.  Before the session is set up it goes in the
+// clear, ipmi messages in ipmi v1.5 format.  After, it is encrypted and
+// signed.
+func (s *IPMISession) send(ptype byte, payload []byte) error {
+	pkt := []byte{rmcpVersion, 0, 0xff, rmcpIPMI}
+
+	s.keyMu.Lock()
+	k1, k2, bmcID := s.k1, s.k2, s.bmcID
+	s.seq++
+	seq := s.seq
+	s.keyMu.Unlock()
+
+	switch {
+	case k1 == nil && ptype == payloadIPMI:
+		// auth type none, session sequence and id 0.
+		pkt = append(pkt, 0, 0, 0, 0, 0, 0, 0, 0, 0, byte(len(payload)))
+		pkt = append(pkt, payload...)
+	case k1 == nil:
+		pkt = append(pkt, authRMCPPlus, ptype, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint16(pkt[14:16], uint16(len(payload)))
+		pkt = append(pkt, payload...)
+	default:
+		enc, err := encrypt(k2[:16], payload)
+		if err != nil {
+			return err
+		}
+		pkt = append(pkt, authRMCPPlus, ptype|0xc0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(pkt[6:10], bmcID)
+		binary.LittleEndian.PutUint32(pkt[10:14], seq)
+		binary.LittleEndian.PutUint16(pkt[14:16], uint16(len(enc)))
+		pkt = append(pkt, enc...)
+		pkt = appendAuthCode(pkt, k1)
+	}
+	_, err := s.conn.Write(pkt)
+	return err
+}
+
+// appendAuthCode - pad the session trailer, then sign from the auth type
+// on.
+func appendAuthCode(pkt, k1 []byte) []byte {
+	n := (4 - (len(pkt)-4+2)%4) % 4
+	pkt = append(pkt, bytes.Repeat([]byte{0xff}, n)...)
+	pkt = append(pkt, byte(n), rmcpIPMI)
+	return append(pkt, hmacSHA1(k1, pkt[4:])[:12]...)
+}
+
+// encrypt - AES-CBC-128 with a random iv in front, and the ipmi padding.
+func encrypt(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	n := (aes.BlockSize - (len(data)+1)%aes.BlockSize) % aes.BlockSize
+	plain := append([]byte{}, data...)
+	for i := 1; i <= n; i++ {
+		plain = append(plain, byte(i))
+	}
+	plain = append(plain, byte(n))
+	out := make([]byte, aes.BlockSize+len(plain))
+	rand.Read(out[:aes.BlockSize])
+	cipher.NewCBCEncrypter(block, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], plain)
+	return out, nil
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("bad encrypted payload length %d", len(data))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(plain, data[aes.BlockSize:])
+	n := int(plain[len(plain)-1])
+	if n >= len(plain) {
+		return nil, fmt.Errorf("bad confidentiality pad")
+	}
+	return plain[:len(plain)-1-n], nil
+}
+
+// receive - read packets until the session is closed, check them, and hand
+// sol payloads to the console, anything else to the request waiting.
+func (s *IPMISession) receive() {
+	buf := make([]byte, 1024)
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		p, err := s.parse(append([]byte{}, buf[:n]...))
+		if err != nil {
+			continue
+		}
+		if p.ptype == payloadSOL {
+			s.solMu.Lock()
+			if s.sol != nil {
+				s.sol.receive(p.data)
+			}
+			s.solMu.Unlock()
+			continue
+		}
+		select {
+		case s.replies <- p:
+		default:
+			// nobody waits for it.
+		}
+	}
+}
+
+// parse - return the payload of pkt, checked and decrypted.
+func (s *IPMISession) parse(pkt []byte) (ipmiPacket, error) {
+	if len(pkt) < 14 || pkt[0] != rmcpVersion || pkt[3] != rmcpIPMI {
+		return ipmiPacket{}, fmt.Errorf("not an ipmi packet")
+	}
+	if pkt[4] == 0 {
+		// ipmi v1.5 without authentication.
+		n := int(pkt[13])
+		if len(pkt) < 14+n {
+			return ipmiPacket{}, fmt.Errorf("short packet")
+		}
+		return ipmiPacket{ptype: payloadIPMI, data: pkt[14 : 14+n]}, nil
+	}
+	if pkt[4] != authRMCPPlus || len(pkt) < 16 {
+		return ipmiPacket{}, fmt.Errorf("unexpected auth type %#x", pkt[4])
+	}
+	ptype := pkt[5]
+	n := int(binary.LittleEndian.Uint16(pkt[14:16]))
+	if len(pkt) < 16+n {
+		return ipmiPacket{}, fmt.Errorf("short packet")
+	}
+	data := pkt[16 : 16+n]
+	if ptype&0xc0 == 0 {
+		return ipmiPacket{ptype: ptype, data: data}, nil
+	}
+
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	if s.k1 == nil || ptype&0xc0 != 0xc0 || binary.LittleEndian.Uint32(pkt[6:10]) != s.consoleID {
+		return ipmiPacket{}, fmt.Errorf("packet not for this session")
+	}
+	if len(pkt) < 12 || !hmac.Equal(pkt[len(pkt)-12:], hmacSHA1(s.k1, pkt[4:len(pkt)-12])[:12]) {
+		return ipmiPacket{}, fmt.Errorf("bad auth code")
+	}
+	if err := s.checkSeq(binary.LittleEndian.Uint32(pkt[10:14])); err != nil {
+		return ipmiPacket{}, err
+	}
+	data, err := decrypt(s.k2[:16], data)
+	if err != nil {
+		return ipmiPacket{}, err
+	}
+	return ipmiPacket{ptype: ptype & 0x3f, data: data}, nil
+}
+
+// checkSeq - note the session sequence number seq of a signed packet,
+// unless it is a duplicate or too old.  The caller holds keyMu.
+func (s *IPMISession) checkSeq(seq uint32) error {
+	switch {
+	case seq == 0:
+		return fmt.Errorf("session sequence number 0")
+	case seq > s.inSeq:
+		if seq-s.inSeq >= ipmiSeqWindow {
+			s.inSeen = 0
+		} else {
+			s.inSeen <<= seq - s.inSeq
+		}
+		s.inSeen |= 1
+		s.inSeq = seq
+	case s.inSeq-seq >= ipmiSeqWindow:
+		return fmt.Errorf("old session sequence number %d, last was %d", seq, s.inSeq)
+	case s.inSeen&(1<<(s.inSeq-seq)) != 0:
+		return fmt.Errorf("duplicate session sequence number %d", seq)
+	default:
+		s.inSeen |= 1 << (s.inSeq - seq)
+	}
+	return nil
+}
+
+// GetPowerState - return the power state, from the chassis status.
+func (s *IPMISession) GetPowerState(ctx context.Context) (PowerState, error) {
+	resp, err := s.request(ctx, netFnChassis, 0x01, nil)
+	if err != nil {
+		return Unknown, err
+	}
+	if len(resp) < 1 {
+		return Unknown, fmt.Errorf("short chassis status %x", resp)
+	}
+	if resp[0]&0x01 != 0 {
+		return On, nil
+	}
+	return Off, nil
+}
+
+// PowerOff - turn power off, if on.
+func (s *IPMISession) PowerOff(ctx context.Context) error {
+	return s.chassisControl(ctx, 0x00, Off)
+}
+
+// PowerOn - turn power on, if off.
+func (s *IPMISession) PowerOn(ctx context.Context) error {
+	return s.chassisControl(ctx, 0x01, On)
+}
+
+// PowerCycle - turn power off, if on, and then back on.
+func (s *IPMISession) PowerCycle(ctx context.Context) error {
+	state, err := s.GetPowerState(ctx)
+	if err != nil {
+		return err
+	}
+	if state == Off {
+		return s.PowerOn(ctx)
+	}
+	return s.chassisControl(ctx, 0x02, Unknown)
+}
+
+// chassisControl - do chassis control op, which is sent only once, a power
+// cycle sent again would cycle twice.  If there is no response to power on
+// or off in ipmiRetry, op is sent again only if the power is not in state
+// yet.
+func (s *IPMISession) chassisControl(ctx context.Context, op byte, state PowerState) error {
+	for {
+		try, cancel := ctx, context.CancelFunc(func() {})
+		if state != Unknown {
+			try, cancel = context.WithTimeout(ctx, ipmiRetry)
+		}
+		_, err := s.requestOnce(try, netFnChassis, 0x02, []byte{op})
+		lost := err != nil && try.Err() != nil && ctx.Err() == nil
+		cancel()
+		if !lost {
+			return err
+		}
+		cur, err := s.GetPowerState(ctx)
+		if err != nil {
+			return err
+		}
+		if cur == state {
+			return nil
+		}
+	}
+}
+
+// OpenConsole - return a expect.GExpect hooked up to the host's serial
+// console over sol, like the one of Session.OpenConsole.
+func (s *IPMISession) OpenConsole(ctx context.Context) (*goexpect.GExpect, error) {
+	s.solMu.Lock()
+	open := s.sol != nil
+	s.solMu.Unlock()
+	if open {
+		return nil, fmt.Errorf("the console of %s is already open", s.desc)
+	}
+
+	resp, err := s.request(ctx, netFnApp, 0x48, []byte{payloadSOL, 1, 0xc0, 0, 0, 0})
+	if err != nil {
+		if ie, ok := err.(*ipmiError); ok {
+			switch ie.code {
+			case 0x80:
+				return nil, fmt.Errorf("sol is already active on %s, in another session", s.desc)
+			case 0x81:
+				return nil, fmt.Errorf("sol is disabled on %s", s.desc)
+			}
+		}
+		return nil, err
+	}
+	if len(resp) < 6 {
+		return nil, fmt.Errorf("short activate payload response %x", resp)
+	}
+	max := int(binary.LittleEndian.Uint16(resp[4:6])) - 4
+	sol := newSOLConsole(s, max)
+
+	s.solMu.Lock()
+	s.sol = sol
+	s.solMu.Unlock()
+
+	exp, _, err := goexpect.SpawnGeneric(&goexpect.GenOptions{
+		In:    sol,
+		Out:   sol.out,
+		Wait:  sol.wait,
+		Close: sol.Close,
+		Check: sol.alive,
+	}, timeout)
+	if err != nil {
+		s.CloseConsole(ctx)
+		return nil, err
+	}
+	sol.exp = exp
+	return exp, nil
+}
+
+// CloseConsole - deactivate sol and close the expect handle of
+// OpenConsole.
+func (s *IPMISession) CloseConsole(ctx context.Context) error {
+	s.solMu.Lock()
+	sol := s.sol
+	s.sol = nil
+	s.solMu.Unlock()
+	if sol == nil {
+		return nil
+	}
+	sol.stop()
+	if sol.exp != nil {
+		sol.exp.Close()
+	}
+	_, err := s.request(ctx, netFnApp, 0x49, []byte{payloadSOL, 1, 0, 0, 0, 0})
+	return err
+}
+
+// Close - close the console, if open, and the session.
+func (s *IPMISession) Close(ctx context.Context) error {
+	s.CloseConsole(ctx)
+	s.keyMu.Lock()
+	id := make([]byte, 4)
+	binary.LittleEndian.PutUint32(id, s.bmcID)
+	s.keyMu.Unlock()
+	_, err := s.request(ctx, netFnApp, 0x3c, id)
+	s.stop()
+	return err
+}
+
+func (s *IPMISession) stop() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+		s.conn.Close()
+	}
+}
@@ -0,0 +1,147 @@
+package cimc_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/loginshell"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIPMISession(t *testing.T) {
+	Convey("Given a CIMC with ipmi over lan enabled", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+		So(sess.SetIPMI(ctx, cimc.IPMIConfig{Enabled: true, PrivilegeLimit: "admin"}), ShouldBeNil)
+		addr := fmt.Sprintf("127.0.0.1:%d", m.IPMIPort)
+
+		Convey("NewIPMISession() needs the right password", func() {
+			_, err := cimc.NewIPMISession(addr, "test", "wrong")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "wrong password")
+
+			_, err = cimc.NewIPMISession(addr, "nobody", "test123")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unauthorized name")
+		})
+
+		Convey("NewIPMISessionKey() needs the bmc key, once set", func() {
+			key := "0123456789abcdef0123456789abcdef01234567"
+			So(sess.SetIPMI(ctx, cimc.IPMIConfig{Enabled: true, PrivilegeLimit: "admin", EncryptionKey: key}), ShouldBeNil)
+			_, err := cimc.NewIPMISession(addr, "test", "test123")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "encryption key")
+
+			is, err := cimc.NewIPMISessionKey(addr, "test", "test123", key)
+			So(err, ShouldBeNil)
+			_, err = is.GetPowerState(ctx)
+			So(err, ShouldBeNil)
+			So(is.Close(ctx), ShouldBeNil)
+		})
+
+		Convey("Given an ipmi session", func() {
+			is, err := cimc.NewIPMISession(addr, "test", "test123")
+			So(err, ShouldBeNil)
+			defer is.Close(ctx)
+
+			Convey("Power operations change the power state", func() {
+				So(is.PowerOff(ctx), ShouldBeNil)
+				So(m.Get("chassis", "Power"), ShouldEqual, "off")
+				state, err := is.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.Off)
+
+				So(is.PowerCycle(ctx), ShouldBeNil)
+				state, err = is.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.On)
+				So(is.PowerCycle(ctx), ShouldBeNil)
+				So(is.PowerOn(ctx), ShouldBeNil)
+			})
+
+			Convey("A lost response does not power cycle twice", func() {
+				m.Do(func(m *test.MockCIMC) { m.IPMILostReplies = 1 })
+				short, cancel := context.WithTimeout(ctx, 3*time.Second)
+				defer cancel()
+				So(is.PowerCycle(short), ShouldNotBeNil)
+				n := 0
+				m.Do(func(m *test.MockCIMC) { n = m.IPMIChassisControls })
+				So(n, ShouldEqual, 1)
+			})
+
+			Convey("A lost response of power off is checked with the power state", func() {
+				m.Do(func(m *test.MockCIMC) { m.IPMILostReplies = 1 })
+				So(is.PowerOff(ctx), ShouldBeNil)
+				So(m.Get("chassis", "Power"), ShouldEqual, "off")
+				n := 0
+				m.Do(func(m *test.MockCIMC) { n = m.IPMIChassisControls })
+				So(n, ShouldEqual, 1)
+			})
+
+			Convey("It has only the capabilities of ipmi", func() {
+				var b cimc.BMC = is
+				_, ok := b.(cimc.ConfigCommander)
+				So(ok, ShouldBeFalse)
+				_, ok = b.(cimc.InventoryProvider)
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("OpenConsole() fails while SOL is disabled", func() {
+				_, err := is.OpenConsole(ctx)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "sol is disabled")
+			})
+
+			Convey("With SOL enabled, loginshell logs in on the console", func() {
+				cfg, err := sess.GetSOL(ctx)
+				So(err, ShouldBeNil)
+				cfg.Enabled = true
+				So(sess.SetSOL(ctx, cfg), ShouldBeNil)
+
+				exp, err := is.OpenConsole(ctx)
+				So(err, ShouldBeNil)
+				_, err = is.OpenConsole(ctx)
+				So(err, ShouldNotBeNil)
+
+				shell, err := loginshell.Login(exp, test.HostUser, test.HostPassword)
+				So(err, ShouldBeNil)
+				ret := shell.Run("echo hello from the host")
+				So(ret.RC, ShouldEqual, 0)
+				So(ret.Output, ShouldEqual, "hello from the host")
+				So(shell.Run("no-such-command").RC, ShouldEqual, 127)
+				So(shell.Logout(), ShouldBeNil)
+				So(is.CloseConsole(ctx), ShouldBeNil)
+
+				// the console is free again.
+				exp, err = is.OpenConsole(ctx)
+				So(err, ShouldBeNil)
+				So(exp.Send("\n"), ShouldBeNil)
+				_, _, err = exp.Expect(regexp.MustCompile("login:"), 5*time.Second)
+				So(err, ShouldBeNil)
+				So(is.CloseConsole(ctx), ShouldBeNil)
+			})
+		})
+
+		Convey("A read-only user can not change the power", func() {
+			So(sess.SetIPMI(ctx, cimc.IPMIConfig{Enabled: true, PrivilegeLimit: "readonly"}), ShouldBeNil)
+			is, err := cimc.NewIPMISession(addr, "test", "test123")
+			So(err, ShouldBeNil)
+			defer is.Close(ctx)
+			_, err = is.GetPowerState(ctx)
+			So(err, ShouldBeNil)
+			err = is.PowerOff(ctx)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "insufficient privilege")
+		})
+	})
+}
@@ -0,0 +1,173 @@
+package cimc
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	goexpect "github.com/google/goexpect"
+)
+
+// solRetries - how often a sol packet is sent before giving up on its ack.
+const solRetries = 5
+
+// solConsole - the host console over an active sol payload of an
+// IPMISession.
+//   Packets of either side carry a sequence number 1 to 15, the other side
+//   acks each with the number and how many characters it took.  Packets
+//   the bmc sends again, as its ack got lost, are acked but not passed on.
+type solConsole struct {
+	s   *IPMISession
+	max int
+	exp *goexpect.GExpect
+
+	// out is what expect reads, data feeds it from the receiver.
+	out  *io.PipeReader
+	pw   *io.PipeWriter
+	data chan []byte
+	acks chan byte
+
+	// seq is the number of the last packet sent, lastRx of the last one
+	// received.
+	wmu    sync.Mutex
+	seq    byte
+	lastRx byte
+
+	done chan struct{}
+	once sync.Once
+}
+
+func newSOLConsole(s *IPMISession, max int) *solConsole {
+	if max <= 0 || max > 250 {
+		max = 250
+	}
+	pr, pw := io.Pipe()
+	c := &solConsole{
+		s:    s,
+		max:  max,
+		out:  pr,
+		pw:   pw,
+		data: make(chan []byte, 64),
+		acks: make(chan byte, 16),
+		done: make(chan struct{}),
+	}
+	go c.pump()
+	return c
+}
+
+// pump - pass what the host says to expect.
+func (c *solConsole) pump() {
+	for {
+		select {
+		case d := <-c.data:
+			if _, err := c.pw.Write(d); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// receive - take a sol packet from the bmc.  Called by the session
+// receiver, so it must not block.
+func (c *solConsole) receive(pkt []byte) {
+	if len(pkt) < 4 {
+		return
+	}
+	seq, ack, data := pkt[0], pkt[1], pkt[4:]
+	if ack != 0 {
+		select {
+		case c.acks <- ack:
+		default:
+		}
+	}
+	if seq == 0 {
+		return
+	}
+	if seq != c.lastRx && len(data) > 0 {
+		select {
+		case c.data <- append([]byte{}, data...):
+		default:
+			// full, no ack makes the bmc send it again.
+			return
+		}
+	}
+	c.lastRx = seq
+	c.s.send(payloadSOL, []byte{0, seq, byte(len(data)), 0})
+}
+
+// Write - send p to the host, waiting for the bmc to ack every packet.
+func (c *solConsole) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	sent := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > c.max {
+			n = c.max
+		}
+		if err := c.sendAcked(p[:n]); err != nil {
+			return sent, err
+		}
+		sent += n
+		p = p[n:]
+	}
+	return sent, nil
+}
+
+func (c *solConsole) sendAcked(data []byte) error {
+	c.seq = c.seq%15 + 1
+	pkt := append([]byte{c.seq, 0, 0, 0}, data...)
+	for i := 0; i < solRetries; i++ {
+		if err := c.s.send(payloadSOL, pkt); err != nil {
+			return err
+		}
+		timer := time.NewTimer(ipmiRetry)
+	wait:
+		for {
+			select {
+			case ack := <-c.acks:
+				if ack == c.seq {
+					timer.Stop()
+					return nil
+				}
+			case <-timer.C:
+				break wait
+			case <-c.done:
+				timer.Stop()
+				return io.ErrClosedPipe
+			}
+		}
+	}
+	return fmt.Errorf("no sol ack from %s", c.s.desc)
+}
+
+func (c *solConsole) wait() error {
+	<-c.done
+	return nil
+}
+
+func (c *solConsole) alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// stop - end the console, expect reads EOF.
+func (c *solConsole) stop() {
+	c.once.Do(func() {
+		close(c.done)
+		c.pw.Close()
+	})
+}
+
+// Close - as expect closes the console.
+func (c *solConsole) Close() error {
+	c.stop()
+	return nil
+}
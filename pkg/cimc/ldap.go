@@ -0,0 +1,238 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// LDAPConfig - ldap authentication settings of the cimc (/ldap).
+//   BindMethod is one of anonymous, configured-credentials or
+//   login-credentials.  BindPassword is only used with
+//   configured-credentials; it is never read back, and only set if not empty.
+//   Servers are host:port, port defaults to 389 (3268 for global catalog).
+type LDAPConfig struct {
+	Enabled        bool   `cimc:"enabled,Enabled"`
+	Domain         string `cimc:"domain,Domain"`
+	BaseDN         string `cimc:"base-dn,BaseDN"`
+	Timeout        int    `cimc:"timeout,Timeout"`
+	Encrypted      bool   `cimc:"encrypted,Encrypted"`
+	Filter         string `cimc:"filter,Filter"`
+	GroupAttribute string `cimc:"group-attribute,Group Attribute"`
+	GroupAuth      bool   `cimc:"group-auth,Group Authorization"`
+	BindMethod     string `cimc:"bind-method,Bind Method"`
+	BindDN         string `cimc:"bind-dn,Bind DN"`
+	BindPassword   string
+	Servers        []string
+	Groups         []LDAPGroup
+}
+
+// LDAPGroup - maps members of a directory group to a cimc role.
+type LDAPGroup struct {
+	Name   string
+	Domain string
+	Role   UserRole
+}
+
+// GetLDAP - return the ldap settings.  BindPassword is always empty.
+func (cs *Session) GetLDAP(ctx context.Context) (LDAPConfig, error) {
+	cfg, _, _, err := getLDAP(ctx, cs)
+	return cfg, err
+}
+
+// getLDAP - GetLDAP, and all server and role group slots, used or not.
+func getLDAP(ctx context.Context, cs *Session) (LDAPConfig, []string, []LDAPGroup, error) {
+	cfg := LDAPConfig{Servers: []string{}, Groups: []LDAPGroup{}}
+	resp, err := cs.SendCmd(ctx, "/ldap/show detail")
+	if err != nil {
+		return cfg, nil, nil, err
+	}
+	if err := decodeDetail(parseDetail(resp), &cfg); err != nil {
+		return cfg, nil, nil, err
+	}
+
+	servers, err := ldapServers(ctx, cs)
+	if err != nil {
+		return cfg, nil, nil, err
+	}
+	for _, srv := range servers {
+		if srv != "" {
+			cfg.Servers = append(cfg.Servers, srv)
+		}
+	}
+
+	groups, err := ldapGroups(ctx, cs)
+	if err != nil {
+		return cfg, nil, nil, err
+	}
+	for _, g := range groups {
+		if g.Name != "" {
+			cfg.Groups = append(cfg.Groups, g)
+		}
+	}
+	return cfg, servers, groups, nil
+}
+
+// SetLDAP - change the ldap settings to cfg.  Only what differs is sent.
+//   Servers and Groups fill the cimc slots in order, the rest are cleared.
+func (cs *Session) SetLDAP(ctx context.Context, cfg LDAPConfig) error {
+	cur, servers, groups, err := getLDAP(ctx, cs)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Servers) > len(servers) {
+		return fmt.Errorf("cimc takes at most %d ldap servers, got %d", len(servers), len(cfg.Servers))
+	}
+	if len(cfg.Groups) > len(groups) {
+		return fmt.Errorf("cimc takes at most %d ldap groups, got %d", len(groups), len(cfg.Groups))
+	}
+
+	if cmds := setChanges(cur, cfg); len(cmds) != 0 || cfg.BindPassword != "" {
+		if err := cs.enterScope(ctx, "ldap"); err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			if _, err := cs.SendCmd(ctx, cmd); err != nil {
+				cs.SendCmd(ctx, "discard")
+				return fmt.Errorf("failed to %s: %v", cmd, err)
+			}
+		}
+		if cfg.BindPassword != "" {
+			if err := cs.setSecret(ctx, "password", cfg.BindPassword); err != nil {
+				cs.SendCmd(ctx, "discard")
+				return fmt.Errorf("failed to set ldap bind password: %v", err)
+			}
+		}
+		if _, err := cs.SendCmd(ctx, "commit"); err != nil {
+			return fmt.Errorf("failed to commit ldap settings: %v", err)
+		}
+	}
+
+	cmds := []string{}
+	for i := range servers {
+		want := ""
+		if i < len(cfg.Servers) {
+			want = cfg.Servers[i]
+		}
+		host, port, err := splitLDAPServer(want)
+		if err != nil {
+			return err
+		}
+		if (want == "" && servers[i] == "") || (want != "" && net.JoinHostPort(host, port) == servers[i]) {
+			continue
+		}
+		cmds = append(cmds,
+			fmt.Sprintf("set ldap-server-%d %s", i+1, quoteValue(host)),
+			fmt.Sprintf("set ldap-server-%d-port %s", i+1, port))
+	}
+	if err := commitCmds(ctx, cs, "/ldap/ldap-server", cmds); err != nil {
+		return err
+	}
+
+	for i := range groups {
+		want := LDAPGroup{}
+		if i < len(cfg.Groups) {
+			want = cfg.Groups[i]
+		}
+		if want == groups[i] {
+			continue
+		}
+		if want.Role == "" {
+			want.Role = RoleReadOnly
+		}
+		if err := cs.enterScope(ctx, "ldap", "role-group "+strconv.Itoa(i+1)); err != nil {
+			return err
+		}
+		cmds := []string{
+			"set name " + quoteValue(want.Name),
+			"set domain " + quoteValue(want.Domain),
+			"set role " + string(want.Role),
+		}
+		for _, cmd := range cmds {
+			if _, err := cs.SendCmd(ctx, cmd); err != nil {
+				cs.SendCmd(ctx, "discard")
+				return fmt.Errorf("failed to %s for ldap role group %d: %v", cmd, i+1, err)
+			}
+		}
+		if _, err := cs.SendCmd(ctx, "commit"); err != nil {
+			return fmt.Errorf("failed to commit ldap role group %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// TestLDAPLogin - log in to the cimc of this session as a directory user
+// in a second session, to check the ldap settings end to end.  The
+// password is not logged.
+func (cs *Session) TestLDAPLogin(ctx context.Context, user, pass string) error {
+	sess := &Session{addr: cs.addr, user: user, pass: pass, opts: cs.opts}
+	if err := sess.connect(ctx); err != nil {
+		sess.disconnect()
+		return fmt.Errorf("ldap login of '%s' to %s failed: %v", user, cs.addr, err)
+	}
+	return sess.Close(ctx)
+}
+
+// ldapServers - return all ldap server slots as host:port, "" if unused.
+// Expected input looks like this:
+// LDAP Servers:
+//    LDAP Server 1: 10.0.0.5
+//    LDAP Server 1 Port: 389
+func ldapServers(ctx context.Context, cs *Session) ([]string, error) {
+	servers := []string{}
+	resp, err := cs.SendCmd(ctx, "/ldap/ldap-server/show detail")
+	if err != nil {
+		return servers, err
+	}
+	dets := parseDetail(resp)
+	for i := 1; ; i++ {
+		host, ok := dets[fmt.Sprintf("LDAP Server %d", i)]
+		if !ok {
+			break
+		}
+		if host == "" || host == "0.0.0.0" {
+			servers = append(servers, "")
+			continue
+		}
+		servers = append(servers, net.JoinHostPort(host, dets[fmt.Sprintf("LDAP Server %d Port", i)]))
+	}
+	return servers, nil
+}
+
+// ldapGroups - return all role group slots, with an empty name if unused.
+// Expected input looks like this:
+// Role Group 1:
+//    Name: lab-admins
+//    Domain: example.com
+//    Role: admin
+func ldapGroups(ctx context.Context, cs *Session) ([]LDAPGroup, error) {
+	groups := []LDAPGroup{}
+	resp, err := cs.SendCmd(ctx, "/ldap/show role-group detail")
+	if err != nil {
+		return groups, err
+	}
+	for _, b := range parseDetailList(resp) {
+		g := LDAPGroup{Name: b.Props["Name"], Domain: b.Props["Domain"], Role: UserRole(b.Props["Role"])}
+		if g.Name == "" {
+			g = LDAPGroup{}
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+func splitLDAPServer(server string) (string, string, error) {
+	if server == "" {
+		return "", "389", nil
+	}
+	if !strings.Contains(server, ":") || strings.HasSuffix(server, "]") {
+		return strings.Trim(server, "[]"), "389", nil
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return "", "", fmt.Errorf("bad ldap server '%s': %v", server, err)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,104 @@
+package cimc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLDAP(t *testing.T) {
+	Convey("Given a CIMC session and a directory", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		// the directory does not speak ldap, the mock cimc asks it directly.
+		// So these tests cover the cli settings and how the mock reads
+		// them, not that a real cimc can bind to a real ldap server.
+		dir, err := test.NewDirectory("dc=example,dc=com")
+		So(err, ShouldBeNil)
+		defer dir.Close()
+		dir.BindDN = "cn=cimc,dc=example,dc=com"
+		dir.BindPassword = "Bind-Secret"
+		dir.AddUser("jdoe", "Directory-1", "lab-admins")
+		dir.AddUser("guest", "Directory-2", "visitors")
+
+		Convey("GetLDAP() returns the defaults", func() {
+			cfg, err := sess.GetLDAP(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Enabled, ShouldBeFalse)
+			So(cfg.Timeout, ShouldEqual, 60)
+			So(cfg.BindMethod, ShouldEqual, "login-credentials")
+			So(cfg.Servers, ShouldBeEmpty)
+			So(cfg.Groups, ShouldBeEmpty)
+			So(sess.TestLDAPLogin(ctx, "jdoe", "Directory-1"), ShouldNotBeNil)
+		})
+
+		Convey("SetLDAP() configures ldap logins", func() {
+			want := cimc.LDAPConfig{
+				Enabled:        true,
+				Domain:         "example.com",
+				BaseDN:         "dc=example,dc=com",
+				Timeout:        30,
+				Filter:         "sAMAccountName",
+				GroupAttribute: "memberOf",
+				GroupAuth:      true,
+				BindMethod:     "configured-credentials",
+				BindDN:         "cn=cimc,dc=example,dc=com",
+				BindPassword:   "Bind-Secret",
+				Servers:        []string{dir.Addr},
+				Groups:         []cimc.LDAPGroup{{Name: "lab-admins", Domain: "example.com", Role: cimc.RoleAdmin}},
+			}
+			So(sess.SetLDAP(ctx, want), ShouldBeNil)
+
+			cfg, err := sess.GetLDAP(ctx)
+			So(err, ShouldBeNil)
+			want.BindPassword = ""
+			So(cfg, ShouldResemble, want)
+
+			So(sess.TestLDAPLogin(ctx, "jdoe", "Directory-1"), ShouldBeNil)
+			So(sess.TestLDAPLogin(ctx, "jdoe@example.com", "Directory-1"), ShouldBeNil)
+			So(sess.TestLDAPLogin(ctx, "jdoe", "wrong"), ShouldNotBeNil)
+
+			Convey("group authorization refuses unmapped groups", func() {
+				So(sess.TestLDAPLogin(ctx, "guest", "Directory-2"), ShouldNotBeNil)
+				cfg.GroupAuth = false
+				So(sess.SetLDAP(ctx, cfg), ShouldBeNil)
+				So(sess.TestLDAPLogin(ctx, "guest", "Directory-2"), ShouldBeNil)
+			})
+
+			Convey("a wrong bind password breaks logins", func() {
+				cfg.BindPassword = "Not-The-Secret"
+				So(sess.SetLDAP(ctx, cfg), ShouldBeNil)
+				So(sess.TestLDAPLogin(ctx, "jdoe", "Directory-1"), ShouldNotBeNil)
+			})
+
+			Convey("clearing servers and groups frees their slots", func() {
+				cfg.Servers = nil
+				cfg.Groups = nil
+				So(sess.SetLDAP(ctx, cfg), ShouldBeNil)
+				cfg, err := sess.GetLDAP(ctx)
+				So(err, ShouldBeNil)
+				So(cfg.Servers, ShouldBeEmpty)
+				So(cfg.Groups, ShouldBeEmpty)
+				So(m.Get("ldap/ldap-server", "ldap-server-1"), ShouldEqual, "")
+				So(m.Get("ldap/role-group 1", "role"), ShouldEqual, "read-only")
+			})
+		})
+
+		Convey("SetLDAP() refuses more servers than slots", func() {
+			cfg, err := sess.GetLDAP(ctx)
+			So(err, ShouldBeNil)
+			cfg.Servers = []string{"a", "b", "c", "d", "e", "f", "g"}
+			So(sess.SetLDAP(ctx, cfg), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,156 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// NetworkConfig - settings of the cimc management network (/cimc/network).
+//   NICMode is one of dedicated, shared_lom, shared_lom_10g, shared_lom_ext
+//   or cisco_card.  NICRedundancy is one of none, active-active or
+//   active-standby.
+type NetworkConfig struct {
+	Hostname      string `cimc:"hostname,Hostname"`
+	DHCP          bool   `cimc:"dhcp-enabled,DHCP Enabled"`
+	IPv4Address   string `cimc:"v4-addr,IPv4 Address"`
+	IPv4Netmask   string `cimc:"v4-netmask,IPv4 Netmask"`
+	IPv4Gateway   string `cimc:"v4-gateway,IPv4 Gateway"`
+	DNSFromDHCP   bool   `cimc:"dns-use-dhcp,Obtain DNS Server by DHCP"`
+	PreferredDNS  string `cimc:"preferred-dns-server,Preferred DNS"`
+	AlternateDNS  string `cimc:"alternate-dns-server,Alternate DNS"`
+	IPv6Enabled   bool   `cimc:"v6-enabled,IPv6 Enabled"`
+	IPv6DHCP      bool   `cimc:"v6-dhcp-enabled,IPV6 DHCP Enabled"`
+	IPv6Address   string `cimc:"v6-addr,IPv6 Address"`
+	IPv6Prefix    int    `cimc:"v6-prefix,IPv6 Prefix"`
+	IPv6Gateway   string `cimc:"v6-gateway,IPv6 Gateway"`
+	VLANEnabled   bool   `cimc:"vlan-enabled,VLAN Enabled"`
+	VLANID        int    `cimc:"vlan-id,VLAN ID"`
+	VLANPriority  int    `cimc:"vlan-priority,VLAN Priority"`
+	NICMode       string `cimc:"mode,NIC Mode"`
+	NICRedundancy string `cimc:"redundancy,NIC Redundancy"`
+	MACAddress    string `cimc:",MAC Address"`
+}
+
+// disrupts - true if changing from old to n can drop connections to the cimc.
+func (n NetworkConfig) disrupts(old NetworkConfig) bool {
+	return n.DHCP != old.DHCP ||
+		n.IPv4Address != old.IPv4Address ||
+		n.IPv4Netmask != old.IPv4Netmask ||
+		n.IPv4Gateway != old.IPv4Gateway ||
+		n.IPv6Enabled != old.IPv6Enabled ||
+		n.IPv6DHCP != old.IPv6DHCP ||
+		n.IPv6Address != old.IPv6Address ||
+		n.IPv6Prefix != old.IPv6Prefix ||
+		n.IPv6Gateway != old.IPv6Gateway ||
+		n.VLANEnabled != old.VLANEnabled ||
+		n.VLANID != old.VLANID ||
+		n.NICMode != old.NICMode ||
+		n.NICRedundancy != old.NICRedundancy
+}
+
+// GetNetwork - return the cimc management network settings.
+func (cs *Session) GetNetwork(ctx context.Context) (NetworkConfig, error) {
+	cfg := NetworkConfig{}
+	resp, err := cs.SendCmd(ctx, "/cimc/network/show detail")
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, decodeDetail(parseDetail(resp), &cfg)
+}
+
+// SetNetwork - change the cimc management network settings to cfg.
+//   Only settings that differ from the current ones are sent, in one commit.
+//
+//   Changes to addressing, vlan or nic mode make the cimc drop the ssh
+//   session.  With reconnect, SetNetwork then connects again, at the new
+//   address of the family the session is over if there is one, and checks
+//   the settings were applied.
+//   Without it, the session is closed and a new one must be opened.
+func (cs *Session) SetNetwork(ctx context.Context, cfg NetworkConfig, reconnect bool) error {
+	cur, err := cs.GetNetwork(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmds := setChanges(cur, cfg)
+	if len(cmds) == 0 {
+		return nil
+	}
+	if !cfg.disrupts(cur) {
+		return commitCmds(ctx, cs, "/cimc/network", cmds)
+	}
+
+	if cs.sshClient == nil {
+		return fmt.Errorf("%s is not connected", cs.desc)
+	}
+	dropped := make(chan struct{})
+	go func(clt *ssh.Client) {
+		clt.Wait()
+		close(dropped)
+	}(cs.sshClient)
+
+	// the cimc may well drop us before commit returns, which is the only
+	// error we expect.
+	if err := commitCmds(ctx, cs, "/cimc/network", cmds); err != nil {
+		if isCIMCError(err) {
+			return err
+		}
+		// after a move to a new address or vlan the old connection often
+		// hangs rather than closes, until expect gives up on it.  It is
+		// gone either way.
+		cs.sshClient.Close()
+		werr := resetPhase(ctx, "network change", ResetDown, func(ctx context.Context) error {
+			select {
+			case <-dropped:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if werr != nil {
+			return fmt.Errorf("%v (%v)", err, werr)
+		}
+	}
+	if !reconnect {
+		cs.disconnect()
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(cs.addr)
+	if err != nil {
+		host, port = cs.addr, "22"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		if cfg.IPv6Enabled && !cfg.IPv6DHCP && cfg.IPv6Address != "" && cfg.IPv6Address != cur.IPv6Address {
+			cs.addr = net.JoinHostPort(cfg.IPv6Address, port)
+		}
+	} else if !cfg.DHCP && cfg.IPv4Address != "" && cfg.IPv4Address != cur.IPv4Address {
+		cs.addr = net.JoinHostPort(cfg.IPv4Address, port)
+	}
+	if err := cs.reconnect(ctx); err != nil {
+		return fmt.Errorf("failed to reconnect after network change: %v", err)
+	}
+
+	got, err := cs.GetNetwork(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.DHCP {
+		// addresses come from dhcp, we can't know them beforehand.
+		cfg.IPv4Address, cfg.IPv4Netmask, cfg.IPv4Gateway = got.IPv4Address, got.IPv4Netmask, got.IPv4Gateway
+	}
+	if cfg.IPv6DHCP {
+		cfg.IPv6Address, cfg.IPv6Prefix, cfg.IPv6Gateway = got.IPv6Address, got.IPv6Prefix, got.IPv6Gateway
+	}
+	if cfg.DNSFromDHCP {
+		cfg.PreferredDNS, cfg.AlternateDNS = got.PreferredDNS, got.AlternateDNS
+	}
+	if diff := setChanges(got, cfg); len(diff) != 0 {
+		return fmt.Errorf("network settings not applied, still need: %s", strings.Join(diff, "; "))
+	}
+	return nil
+}
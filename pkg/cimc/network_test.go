@@ -0,0 +1,100 @@
+package cimc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNetwork(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		cfg, err := sess.GetNetwork(ctx)
+		So(err, ShouldBeNil)
+
+		Convey("GetNetwork() returns typed settings", func() {
+			So(cfg.IPv4Address, ShouldEqual, "127.0.0.1")
+			So(cfg.DHCP, ShouldBeFalse)
+			So(cfg.IPv6Prefix, ShouldEqual, 64)
+			So(cfg.NICMode, ShouldEqual, "dedicated")
+			So(cfg.MACAddress, ShouldEqual, "70:0F:6A:D4:3B:12")
+			So(sess.Close(ctx), ShouldBeNil)
+		})
+
+		Convey("SetNetwork() changes settings in place", func() {
+			cfg.Hostname = "lab-rack3-u12"
+			cfg.PreferredDNS = "10.0.0.53"
+			So(sess.SetNetwork(ctx, cfg, false), ShouldBeNil)
+			So(m.Get("cimc/network", "hostname"), ShouldEqual, "lab-rack3-u12")
+			So(m.Get("cimc/network", "preferred-dns-server"), ShouldEqual, "10.0.0.53")
+			So(sess.Close(ctx), ShouldBeNil)
+		})
+
+		Convey("SetNetwork() reconnects at the new address", func() {
+			cfg.IPv4Address = "127.0.0.2"
+			So(sess.SetNetwork(ctx, cfg, true), ShouldBeNil)
+			So(fmt.Sprintf("%s", sess), ShouldContainSubstring, "127.0.0.2")
+
+			got, err := sess.GetNetwork(ctx)
+			So(err, ShouldBeNil)
+			So(got.IPv4Address, ShouldEqual, "127.0.0.2")
+			So(sess.Close(ctx), ShouldBeNil)
+		})
+
+		Convey("SetNetwork() reconnects at the new IPv6 address of a session over IPv6", func() {
+			So(sess.Close(ctx), ShouldBeNil)
+			v6, err := cimc.NewSession(fmt.Sprintf("[::1]:%d", m.Port), "test", "test123")
+			if err != nil {
+				// no IPv6 loopback here.
+				return
+			}
+			cfg.IPv6Enabled = true
+			cfg.IPv6Address = "::1"
+			So(v6.SetNetwork(ctx, cfg, true), ShouldBeNil)
+			So(fmt.Sprintf("%s", v6), ShouldContainSubstring, "::1")
+			So(m.Get("cimc/network", "v6-addr"), ShouldEqual, "::1")
+			So(v6.Close(ctx), ShouldBeNil)
+		})
+
+		Convey("SetNetwork() without reconnect closes the session", func() {
+			cfg.VLANEnabled = true
+			cfg.VLANID = 100
+			So(sess.SetNetwork(ctx, cfg, false), ShouldBeNil)
+			So(m.Get("cimc/network", "vlan-id"), ShouldEqual, "100")
+			_, err := sess.SendCmd(ctx, "/cimc/network/show detail")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("SetNetwork() without reconnect returns an error of the commit", func() {
+			m.Do(func(m *test.MockCIMC) {
+				m.Root.Child("cimc").Child("network").OnCommit = func(t *test.Term, changed map[string]string) {
+					t.Errorf("Invalid VLAN configuration")
+				}
+			})
+			cfg.VLANEnabled = true
+			cfg.VLANID = 100
+			err := sess.SetNetwork(ctx, cfg, false)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Invalid VLAN configuration")
+			So(sess.Close(ctx), ShouldBeNil)
+		})
+
+		Convey("SetNetwork() rejects bad values", func() {
+			cfg.NICMode = "no-such-mode"
+			So(sess.SetNetwork(ctx, cfg, false), ShouldNotBeNil)
+			So(m.Get("cimc/network", "mode"), ShouldEqual, "dedicated")
+			So(sess.Close(ctx), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,166 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxNTPServers - how many ntp servers the cimc takes.
+const maxNTPServers = 4
+
+// cimcTimeLayout - how the cimc shows 'Current Time (UTC)'.
+const cimcTimeLayout = time.ANSIC
+
+// NTPConfig - ntp settings of the cimc (/cimc/network/ntp).
+//   Status is read-only, what the cimc says about synchronization.
+type NTPConfig struct {
+	Enabled bool
+	Servers []string
+	Status  string
+}
+
+// GetNTP - return the ntp settings.
+func (cs *Session) GetNTP(ctx context.Context) (NTPConfig, error) {
+	cfg, _, err := getNTP(ctx, cs)
+	return cfg, err
+}
+
+// getNTP - return the ntp settings, and all server slots, "" if unused.
+//   cfg.Servers leaves out unused slots, so the index of a server in it
+//   is not always its slot.
+func getNTP(ctx context.Context, cs *Session) (NTPConfig, []string, error) {
+	cfg := NTPConfig{Servers: []string{}}
+	resp, err := cs.SendCmd(ctx, "/cimc/network/ntp/show detail")
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	dets := parseDetail(resp)
+	val, ok := dets["NTP Enabled"]
+	if !ok {
+		return cfg, nil, fmt.Errorf("did not find 'NTP Enabled' in %s", resp)
+	}
+	if cfg.Enabled, err = parseBool(val); err != nil {
+		return cfg, nil, fmt.Errorf("Failed to parse 'NTP Enabled' setting: %v", err)
+	}
+	slots := make([]string, maxNTPServers)
+	for i := range slots {
+		slots[i] = dets[fmt.Sprintf("NTP Server %d", i+1)]
+		if slots[i] != "" {
+			cfg.Servers = append(cfg.Servers, slots[i])
+		}
+	}
+	cfg.Status = dets["Status"]
+	return cfg, slots, nil
+}
+
+// SetNTP - set the ntp servers and whether ntp is enabled.
+func (cs *Session) SetNTP(ctx context.Context, cfg NTPConfig) error {
+	if len(cfg.Servers) > maxNTPServers {
+		return fmt.Errorf("cimc takes at most %d ntp servers, got %d", maxNTPServers, len(cfg.Servers))
+	}
+	cur, slots, err := getNTP(ctx, cs)
+	if err != nil {
+		return err
+	}
+
+	cmds := []string{}
+	if cur.Enabled != cfg.Enabled {
+		cmds = append(cmds, "set enabled "+formatBool(cfg.Enabled))
+	}
+	for i := 0; i < maxNTPServers; i++ {
+		want := ""
+		if i < len(cfg.Servers) {
+			want = cfg.Servers[i]
+		}
+		if want != slots[i] {
+			cmds = append(cmds, fmt.Sprintf("set server-%d %s", i+1, quoteValue(want)))
+		}
+	}
+	return commitCmds(ctx, cs, "/cimc/network/ntp", cmds)
+}
+
+// GetTimezone - return the timezone of the cimc, for example "America/Los_Angeles".
+func (cs *Session) GetTimezone(ctx context.Context) (string, error) {
+	resp, err := cs.SendCmd(ctx, "/time/show detail")
+	if err != nil {
+		return "", err
+	}
+	tz, ok := parseDetail(resp)["Timezone"]
+	if !ok {
+		return "", fmt.Errorf("did not find 'Timezone' in %s", resp)
+	}
+	return tz, nil
+}
+
+// SetTimezone - set the timezone of the cimc, an IANA name like
+// "America/Chicago" or "UTC".
+func (cs *Session) SetTimezone(ctx context.Context, tz string) error {
+	// time.LoadLocation takes these for UTC and the zone of this machine,
+	// neither is a zone the cimc knows.
+	if tz == "" || tz == "Local" {
+		return fmt.Errorf("bad timezone '%s': want an IANA name like America/Chicago", tz)
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("bad timezone '%s': %v", tz, err)
+	}
+	return commitCmds(ctx, cs, "/time", []string{"set timezone " + tz})
+}
+
+// ClockSkew - return how far the cimc clock is ahead of the local clock
+// (negative if behind).  The cimc shows whole seconds, so expect up to a
+// second of error.
+func (cs *Session) ClockSkew(ctx context.Context) (time.Duration, error) {
+	before := time.Now()
+	resp, err := cs.SendCmd(ctx, "/cimc/show detail")
+	if err != nil {
+		return 0, err
+	}
+	after := time.Now()
+
+	val, ok := parseDetail(resp)["Current Time (UTC)"]
+	if !ok {
+		return 0, fmt.Errorf("did not find 'Current Time (UTC)' in %s", resp)
+	}
+	cimcTime, err := time.ParseInLocation(cimcTimeLayout, val, time.UTC)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse 'Current Time (UTC)' setting: '%s'", val)
+	}
+
+	// the cimc truncates to the second, take the middle of that second.
+	cimcTime = cimcTime.Add(500 * time.Millisecond)
+	local := before.Add(after.Sub(before) / 2)
+	return cimcTime.Sub(local).Round(time.Second), nil
+}
+
+// CheckClock - check that the cimc uses ntp with at least one server and
+// that its clock is within maxSkew of the local clock.  The error lists
+// everything that is wrong.
+func CheckClock(ctx context.Context, cs CIMCSession, maxSkew time.Duration) error {
+	problems := []string{}
+
+	ntp, err := cs.GetNTP(ctx)
+	if err != nil {
+		return err
+	}
+	if !ntp.Enabled {
+		problems = append(problems, "ntp is disabled")
+	} else if len(ntp.Servers) == 0 {
+		problems = append(problems, "ntp has no servers")
+	}
+
+	skew, err := cs.ClockSkew(ctx)
+	if err != nil {
+		return err
+	}
+	if skew > maxSkew || skew < -maxSkew {
+		problems = append(problems, fmt.Sprintf("clock is off by %s (max %s)", skew, maxSkew))
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("%s: %s", cs, strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package cimc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNTP(t *testing.T) {
+	ctx := context.TODO()
+	Convey("Given a CIMC session", t, func() {
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("SetNTP() sets servers and GetNTP() reads them back", func() {
+			cfg, err := sess.GetNTP(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Enabled, ShouldBeFalse)
+			So(cfg.Servers, ShouldBeEmpty)
+
+			err = sess.SetNTP(ctx, cimc.NTPConfig{Enabled: true, Servers: []string{"10.0.0.1", "10.0.0.2"}})
+			So(err, ShouldBeNil)
+			cfg, err = sess.GetNTP(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Enabled, ShouldBeTrue)
+			So(cfg.Servers, ShouldResemble, []string{"10.0.0.1", "10.0.0.2"})
+
+			err = sess.SetNTP(ctx, cimc.NTPConfig{Enabled: true, Servers: []string{"10.0.0.3"}})
+			So(err, ShouldBeNil)
+			So(m.Get("cimc/network/ntp", "server-1"), ShouldEqual, "10.0.0.3")
+			So(m.Get("cimc/network/ntp", "server-2"), ShouldEqual, "")
+
+			err = sess.SetNTP(ctx, cimc.NTPConfig{Servers: []string{"a", "b", "c", "d", "e"}})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("SetNTP() sets every slot, even after a gap", func() {
+			m.Set("cimc/network/ntp", "server-2", "10.0.0.2")
+			cfg, err := sess.GetNTP(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Servers, ShouldResemble, []string{"10.0.0.2"})
+
+			So(sess.SetNTP(ctx, cimc.NTPConfig{Servers: []string{"10.0.0.1"}}), ShouldBeNil)
+			So(m.Get("cimc/network/ntp", "server-1"), ShouldEqual, "10.0.0.1")
+			So(m.Get("cimc/network/ntp", "server-2"), ShouldEqual, "")
+			cfg, err = sess.GetNTP(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Servers, ShouldResemble, []string{"10.0.0.1"})
+		})
+
+		Convey("SetTimezone() sets the timezone", func() {
+			So(sess.SetTimezone(ctx, "America/Los_Angeles"), ShouldBeNil)
+			tz, err := sess.GetTimezone(ctx)
+			So(err, ShouldBeNil)
+			So(tz, ShouldEqual, "America/Los_Angeles")
+			So(sess.SetTimezone(ctx, "Not/AZone"), ShouldNotBeNil)
+			So(sess.SetTimezone(ctx, ""), ShouldNotBeNil)
+			So(sess.SetTimezone(ctx, "Local"), ShouldNotBeNil)
+			tz, err = sess.GetTimezone(ctx)
+			So(err, ShouldBeNil)
+			So(tz, ShouldEqual, "America/Los_Angeles")
+		})
+
+		Convey("ClockSkew() reports the clock offset", func() {
+			skew, err := sess.ClockSkew(ctx)
+			So(err, ShouldBeNil)
+			So(skew, ShouldBeBetweenOrEqual, -time.Second, time.Second)
+
+			m.Do(func(m *test.MockCIMC) { m.ClockOffset = -5 * time.Minute })
+			skew, err = sess.ClockSkew(ctx)
+			So(err, ShouldBeNil)
+			So(skew, ShouldBeBetweenOrEqual, -5*time.Minute-time.Second, -5*time.Minute+time.Second)
+		})
+
+		Convey("CheckClock() reports every problem", func() {
+			m.Do(func(m *test.MockCIMC) { m.ClockOffset = time.Hour })
+			err := cimc.CheckClock(ctx, sess, time.Minute)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "ntp is disabled")
+			So(err.Error(), ShouldContainSubstring, "clock is off by 1h0m0s")
+
+			m.Do(func(m *test.MockCIMC) { m.ClockOffset = 0 })
+			So(sess.SetNTP(ctx, cimc.NTPConfig{Enabled: true, Servers: []string{"10.0.0.1"}}), ShouldBeNil)
+			So(cimc.CheckClock(ctx, sess, time.Minute), ShouldBeNil)
+		})
+	})
+}
@@ -3,7 +3,6 @@ package cimc
 import (
 	"context"
 	"fmt"
-	"strings"
 )
 
 // GetPowerState - return power state of system.
@@ -55,27 +54,49 @@ func powerCmd(ctx context.Context, cs *Session, cmd string) error {
 	return err
 }
 
-// parse '/chassis/show detail' output.
-// Expected input looks like this:
-// Chassis:
-//    Power: on
-//    Serial Number: WZP2326007Q
-//    Product Name:
-//    PID : APIC-SERVER-L3
-//    UUID: 13AA6335-143A-4FBE-AD2D-20487959A59B
-//    Locator LED: off
-//    Description:
-//    Asset Tag: Unknown
-func parseDetail(data string) map[string]string {
-	lines := strings.Split(data, "\n")
-	ret := map[string]string{}
+// cliPower - PowerOn, PowerOff and PowerCycle by commands of a bmc command
+// line, like racadm, which refuse to power on a host that is on and the
+// like.  A command is only sent if the power is not in its state yet.
+type cliPower struct {
+	on, off, cycle string
 
-	for _, line := range lines {
-		if !strings.HasPrefix(line, " ") {
-			continue
+	state func(context.Context) (PowerState, error)
+	send  func(context.Context, string) (string, error)
+}
+
+// PowerOn - turn power on, if off.
+func (p cliPower) PowerOn(ctx context.Context) error {
+	return p.power(ctx, p.on, On)
+}
+
+// PowerOff - turn power off, if on.
+func (p cliPower) PowerOff(ctx context.Context) error {
+	return p.power(ctx, p.off, Off)
+}
+
+// PowerCycle - turn power off and back on, or just on if it was off.
+func (p cliPower) PowerCycle(ctx context.Context) error {
+	state, err := p.state(ctx)
+	if err != nil {
+		return err
+	}
+	if state == Off {
+		return p.power(ctx, p.on, On)
+	}
+	return p.power(ctx, p.cycle, Unknown)
+}
+
+// power - send cmd, unless the power is already in state.
+func (p cliPower) power(ctx context.Context, cmd string, state PowerState) error {
+	if state != Unknown {
+		cur, err := p.state(ctx)
+		if err != nil {
+			return err
+		}
+		if cur == state {
+			return nil
 		}
-		toks := strings.SplitN(line, ":", 2)
-		ret[strings.TrimSpace(toks[0])] = strings.TrimSpace(toks[1])
 	}
-	return ret
+	_, err := p.send(ctx, cmd)
+	return err
 }
@@ -0,0 +1,154 @@
+package cimc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// BIOSProfileVersion - the version of the BIOSProfile format written by this package.
+const BIOSProfileVersion = 1
+
+// BIOSProfile - a snapshot of bios tokens, to compare with or apply to other servers.
+type BIOSProfile struct {
+	Version int               `json:"version"`
+	Source  string            `json:"source,omitempty"`
+	Created time.Time         `json:"created"`
+	Tokens  map[string]string `json:"tokens"`
+}
+
+// BIOSTokenChange - a token whose live value differs from a BIOSProfile.
+//   Scope is empty if the cimc has no such token.
+//   Valid is false if the cimc does not allow the profile's value.
+type BIOSTokenChange struct {
+	Scope   string
+	Name    string
+	Current string
+	Wanted  string
+	Valid   bool
+}
+
+func (c BIOSTokenChange) String() string {
+	if c.Scope == "" {
+		return fmt.Sprintf("%s: unknown token (wanted %s)", c.Name, c.Wanted)
+	}
+	s := fmt.Sprintf("%s/%s: %s -> %s", c.Scope, c.Name, c.Current, c.Wanted)
+	if !c.Valid {
+		s += " (not an allowed value)"
+	}
+	return s
+}
+
+// ExportBIOSProfile - return a BIOSProfile of all bios tokens of cs.
+func ExportBIOSProfile(ctx context.Context, cs CIMCSession) (*BIOSProfile, error) {
+	tokens, err := cs.BIOSTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &BIOSProfile{
+		Version: BIOSProfileVersion,
+		Source:  fmt.Sprintf("%s", cs),
+		Created: time.Now().UTC(),
+		Tokens:  map[string]string{},
+	}
+	for _, tok := range tokens {
+		p.Tokens[tok.Name] = tok.Value
+	}
+	return p, nil
+}
+
+// ReadBIOSProfile - read a BIOSProfile written by BIOSProfile.Write.
+func ReadBIOSProfile(r io.Reader) (*BIOSProfile, error) {
+	p := &BIOSProfile{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, fmt.Errorf("failed to read bios profile: %v", err)
+	}
+	if p.Version < 1 || p.Version > BIOSProfileVersion {
+		return nil, fmt.Errorf("unsupported bios profile version %d (supported: 1 to %d)", p.Version, BIOSProfileVersion)
+	}
+	if len(p.Tokens) == 0 {
+		return nil, fmt.Errorf("bios profile has no tokens")
+	}
+	return p, nil
+}
+
+// Write - write the profile as json.
+func (p *BIOSProfile) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// DiffBIOSProfile - return the tokens of cs that differ from profile p,
+// ordered by scope and name.
+func DiffBIOSProfile(ctx context.Context, cs CIMCSession, p *BIOSProfile) ([]BIOSTokenChange, error) {
+	tokens, err := cs.BIOSTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]BIOSToken{}
+	for _, tok := range tokens {
+		byName[tok.Name] = tok
+	}
+
+	changes := []BIOSTokenChange{}
+	for name, wanted := range p.Tokens {
+		tok, ok := byName[name]
+		if !ok {
+			changes = append(changes, BIOSTokenChange{Name: name, Wanted: wanted})
+			continue
+		}
+		if tok.Value == wanted {
+			continue
+		}
+		valid := len(tok.Allowed) == 0
+		for _, v := range tok.Allowed {
+			if v == wanted {
+				valid = true
+				break
+			}
+		}
+		changes = append(changes, BIOSTokenChange{
+			Scope: tok.Scope, Name: name, Current: tok.Value, Wanted: wanted, Valid: valid})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Scope != changes[j].Scope {
+			return changes[i].Scope < changes[j].Scope
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// ApplyBIOSProfile - set the tokens of cs that differ from profile p in a
+// single commit.  Returns the changes, and true if the host needs a reboot
+// for them to take effect.  With dryRun, only return the changes that
+// would be made.  Nothing is changed if any token is unknown or invalid.
+func ApplyBIOSProfile(ctx context.Context, cs CIMCSession, p *BIOSProfile, dryRun bool) ([]BIOSTokenChange, bool, error) {
+	changes, err := DiffBIOSProfile(ctx, cs, p)
+	if err != nil {
+		return changes, false, err
+	}
+	if dryRun || len(changes) == 0 {
+		return changes, false, nil
+	}
+
+	values := map[string]string{}
+	for _, c := range changes {
+		if c.Scope == "" || !c.Valid {
+			return changes, false, fmt.Errorf("cannot apply bios profile: %s", c)
+		}
+		values[c.Name] = c.Wanted
+	}
+
+	reboot, err := cs.SetBIOSTokens(ctx, values)
+	return changes, reboot, err
+}
@@ -0,0 +1,84 @@
+package cimc_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBIOSProfile(t *testing.T) {
+	ctx := context.TODO()
+	Convey("Given a profile exported from one CIMC", t, func() {
+		src, srcSess, err := newMock()
+		So(err, ShouldBeNil)
+		defer src.Close()
+		defer srcSess.Close(ctx)
+
+		src.Set("bios/advanced", "IntelVTD", "Disabled")
+		src.Set("bios/advanced", "CPUPerformance", "HPC")
+
+		p, err := cimc.ExportBIOSProfile(ctx, srcSess)
+		So(err, ShouldBeNil)
+		So(p.Version, ShouldEqual, cimc.BIOSProfileVersion)
+		So(p.Tokens["IntelVTD"], ShouldEqual, "Disabled")
+
+		Convey("it survives a write and read", func() {
+			buf := bytes.Buffer{}
+			So(p.Write(&buf), ShouldBeNil)
+			p2, err := cimc.ReadBIOSProfile(&buf)
+			So(err, ShouldBeNil)
+			So(p2.Tokens, ShouldResemble, p.Tokens)
+		})
+
+		Convey("profiles of unknown versions are refused", func() {
+			_, err := cimc.ReadBIOSProfile(strings.NewReader(`{"version": 99, "tokens": {"IntelVT": "Enabled"}}`))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("and another CIMC", func() {
+			dst, dstSess, err := newMock()
+			So(err, ShouldBeNil)
+			defer dst.Close()
+			defer dstSess.Close(ctx)
+
+			Convey("DiffBIOSProfile() shows the differences", func() {
+				changes, err := cimc.DiffBIOSProfile(ctx, dstSess, p)
+				So(err, ShouldBeNil)
+				So(changes, ShouldResemble, []cimc.BIOSTokenChange{
+					{Scope: "advanced", Name: "CPUPerformance", Current: "Enterprise", Wanted: "HPC", Valid: true},
+					{Scope: "advanced", Name: "IntelVTD", Current: "Enabled", Wanted: "Disabled", Valid: true},
+				})
+			})
+
+			Convey("ApplyBIOSProfile() with dryRun changes nothing", func() {
+				changes, reboot, err := cimc.ApplyBIOSProfile(ctx, dstSess, p, true)
+				So(err, ShouldBeNil)
+				So(len(changes), ShouldEqual, 2)
+				So(reboot, ShouldBeFalse)
+				So(dst.Get("bios/advanced", "IntelVTD"), ShouldEqual, "Enabled")
+			})
+
+			Convey("ApplyBIOSProfile() applies the differences", func() {
+				changes, reboot, err := cimc.ApplyBIOSProfile(ctx, dstSess, p, false)
+				So(err, ShouldBeNil)
+				So(len(changes), ShouldEqual, 2)
+				So(reboot, ShouldBeTrue)
+
+				changes, err = cimc.DiffBIOSProfile(ctx, dstSess, p)
+				So(err, ShouldBeNil)
+				So(changes, ShouldBeEmpty)
+			})
+
+			Convey("ApplyBIOSProfile() refuses unknown tokens", func() {
+				p.Tokens["NoSuchToken"] = "Enabled"
+				_, _, err := cimc.ApplyBIOSProfile(ctx, dstSess, p, false)
+				So(err, ShouldNotBeNil)
+				So(dst.Get("bios/advanced", "IntelVTD"), ShouldEqual, "Enabled")
+			})
+		})
+	})
+}
@@ -0,0 +1,168 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Typed settings structs map their fields to cimc properties with a tag:
+//   Hostname string `cimc:"hostname,Hostname"`
+// The first part is the name used with 'set', the second the label that
+// 'show detail' prints.  Fields with an empty set name are read-only.
+// Supported field types are string, bool (yes/no) and int.
+
+type propField struct {
+	set   string
+	label string
+	index int
+}
+
+func propFields(t reflect.Type) []propField {
+	fields := []propField{}
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("cimc")
+		if !ok {
+			continue
+		}
+		toks := strings.SplitN(tag, ",", 2)
+		if len(toks) != 2 {
+			panic(fmt.Sprintf("bad cimc tag '%s' on %s.%s", tag, t.Name(), t.Field(i).Name))
+		}
+		fields = append(fields, propField{set: toks[0], label: toks[1], index: i})
+	}
+	return fields
+}
+
+// decodeDetail - fill the tagged fields of the struct pointed to by v from
+// parsed 'show detail' output.  Labels missing from dets are left alone.
+func decodeDetail(dets map[string]string, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	found := 0
+	for _, f := range propFields(rv.Type()) {
+		val, ok := dets[f.label]
+		if !ok {
+			continue
+		}
+		found++
+		field := rv.Field(f.index)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(val)
+		case reflect.Bool:
+			b, err := parseBool(val)
+			if err != nil {
+				return fmt.Errorf("Failed to parse '%s' setting: %v", f.label, err)
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("Failed to parse '%s' setting: '%s'", f.label, val)
+			}
+			field.SetInt(int64(n))
+		default:
+			panic(fmt.Sprintf("unsupported cimc field type %s", field.Kind()))
+		}
+	}
+	if found == 0 {
+		return fmt.Errorf("no %s settings found in '%v'", rv.Type().Name(), dets)
+	}
+	return nil
+}
+
+// setChanges - return the 'set' commands that change the settable fields
+// of old to the values in new.  old and new are structs of the same type.
+func setChanges(old, new interface{}) []string {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	cmds := []string{}
+	for _, f := range propFields(nv.Type()) {
+		if f.set == "" {
+			continue
+		}
+		o, n := formatProp(ov.Field(f.index)), formatProp(nv.Field(f.index))
+		if o != n {
+			cmds = append(cmds, "set "+f.set+" "+quoteValue(n))
+		}
+	}
+	return cmds
+}
+
+func formatProp(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return formatBool(v.Bool())
+	case reflect.Int:
+		return strconv.Itoa(int(v.Int()))
+	}
+	return v.String()
+}
+
+func parseBool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "yes", "enabled", "true", "on":
+		return true, nil
+	case "no", "disabled", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("bad boolean '%s'", val)
+}
+
+// quoteValue - quote a value for 'set' if it is empty or has spaces.
+func quoteValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// commitCmds - send 'set' commands in scope and commit them.  On failure
+// pending changes are discarded.  Does nothing if cmds is empty.
+func commitCmds(ctx context.Context, cs *Session, scope string, cmds []string) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	for i, cmd := range cmds {
+		if i == 0 {
+			cmd = scope + "/" + cmd
+		}
+		if _, err := cs.SendCmd(ctx, cmd); err != nil {
+			cs.SendCmd(ctx, "discard")
+			return fmt.Errorf("failed to %s: %w", cmd, err)
+		}
+	}
+	if _, err := cs.SendCmd(ctx, "commit"); err != nil {
+		return fmt.Errorf("failed to commit %s settings: %w", scope, err)
+	}
+	return nil
+}
+
+// commitIn - commitCmds for scopes that take an argument, like "server 1",
+// which a "/path/cmd" can not express.
+func commitIn(ctx context.Context, cs *Session, scopes []string, cmds []string) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	if err := cs.enterScope(ctx, scopes...); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if _, err := cs.SendCmd(ctx, cmd); err != nil {
+			cs.SendCmd(ctx, "discard")
+			return fmt.Errorf("failed to %s in %s: %v", cmd, strings.Join(scopes, "/"), err)
+		}
+	}
+	if _, err := cs.SendCmd(ctx, "commit"); err != nil {
+		return fmt.Errorf("failed to commit %s settings: %v", strings.Join(scopes, "/"), err)
+	}
+	return nil
+}
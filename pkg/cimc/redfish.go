@@ -3,7 +3,6 @@ package cimc
 import (
 	"context"
 	"fmt"
-	"strconv"
 )
 
 // RedfishEnable - Turn on redfish api.
@@ -17,51 +16,66 @@ func (cs *Session) RedfishDisable(ctx context.Context) error {
 }
 
 // RedfishInfo - query state of redfish.
-func (cs *Session) RedfishInfo(ctx context.Context) (bool, int, int, error) {
+func (cs *Session) RedfishInfo(ctx context.Context) (RedfishStatus, error) {
 	return getRedfish(ctx, cs)
 }
 
-func getRedfish(ctx context.Context, cs *Session) (bool, int, int, error) {
-	var enabled, ok bool
-	var nactive, nmax int
-
-	resp, err := cs.SendCmd(ctx, "/redfish/show detail")
-	if err != nil {
-		return enabled, nactive, nmax, err
-	}
-
-	deets := parseDetail(resp)
+// RedfishSessionMargin - how many free redfish sessions RedfishStatus.Warning
+// still warns about.
+var RedfishSessionMargin = 1
+
+// RedfishStatus - the state of redfish on the cimc.
+//   SessionTimeout is in seconds, Port is where redfish is served.  Values
+//   the cimc does not show are 0.
+type RedfishStatus struct {
+	Enabled        bool `cimc:"enabled,Enabled"`
+	ActiveSessions int  `cimc:",Active Sessions"`
+	MaxSessions    int  `cimc:",Max Sessions"`
+	Port           int  `cimc:",Port"`
+	SessionTimeout int  `cimc:",Session Timeout"`
+}
 
-	val := deets["Enabled"]
-	if val == "no" {
-		enabled = false
-	} else if val == "yes" {
-		enabled = true
-	} else {
-		return enabled, nactive, nmax, fmt.Errorf("Unknown redfish 'Enabled' setting: '%s'", val)
+// Warning - return a warning if redfish is close to its session limit,
+// that is RedfishSessionMargin or fewer sessions are free, "" otherwise.
+func (s RedfishStatus) Warning() string {
+	if s.MaxSessions == 0 {
+		return ""
 	}
-
-	val = deets["Active Sessions"]
-	if val == "" {
-		return enabled, nactive, nmax, fmt.Errorf("Empty 'Active Sessions' setting: '%s'", val)
+	free := s.MaxSessions - s.ActiveSessions
+	switch {
+	case free <= 0:
+		return fmt.Sprintf("redfish session limit reached, %d of %d sessions active", s.ActiveSessions, s.MaxSessions)
+	case free <= RedfishSessionMargin:
+		return fmt.Sprintf("redfish close to its session limit, %d of %d sessions active", s.ActiveSessions, s.MaxSessions)
 	}
+	return ""
+}
 
-	nactive, err = strconv.Atoi(val)
+// getRedfish - parse the redfish settings.
+// Expected input looks like this:
+// REDFISH Settings:
+//    Enabled: yes
+//    Active Sessions: 1
+//    Max Sessions: 4
+//    Port: 443
+//    Session Timeout: 1800
+func getRedfish(ctx context.Context, cs *Session) (RedfishStatus, error) {
+	status := RedfishStatus{}
+	resp, err := cs.SendCmd(ctx, "/redfish/show detail")
 	if err != nil {
-		return enabled, nactive, nmax, fmt.Errorf("Failed to parse 'Active Sessions' setting: '%s'", val)
+		return status, err
 	}
 
-	val, ok = deets["Max Sessions"]
-	if !ok {
-		return enabled, nactive, nmax, fmt.Errorf("No 'Max Sessions' setting: '%s'", val)
+	deets := parseDetail(resp)
+	for _, label := range []string{"Enabled", "Active Sessions", "Max Sessions"} {
+		if deets[label] == "" {
+			return status, fmt.Errorf("No redfish '%s' setting in: %s", label, resp)
+		}
 	}
-
-	nmax, err = strconv.Atoi(val)
-	if err != nil {
-		return enabled, nactive, nmax, fmt.Errorf("Failed to parse 'Max Sessions' setting: '%s'", val)
+	if err := decodeDetail(deets, &status); err != nil {
+		return status, err
 	}
-
-	return enabled, nactive, nmax, nil
+	return status, nil
 }
 
 func setRedfish(ctx context.Context, cs *Session, desired bool) error {
@@ -70,12 +84,12 @@ func setRedfish(ctx context.Context, cs *Session, desired bool) error {
 		val = "yes"
 	}
 
-	enabled, _, _, err := getRedfish(ctx, cs)
+	status, err := getRedfish(ctx, cs)
 	if err != nil {
 		return err
 	}
 
-	if enabled == desired {
+	if status.Enabled == desired {
 		return nil
 	}
 
@@ -88,12 +102,12 @@ func setRedfish(ctx context.Context, cs *Session, desired bool) error {
 		return err
 	}
 
-	enabled, _, _, err = getRedfish(ctx, cs)
+	status, err = getRedfish(ctx, cs)
 	if err != nil {
 		return fmt.Errorf("Failed to verify redfish status after commit: %v", err)
 	}
 
-	if enabled != desired {
+	if status.Enabled != desired {
 		return fmt.Errorf("failed to set redfish enabled=%t", desired)
 	}
 
@@ -0,0 +1,243 @@
+package cimc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType - the kind of a redfish event.
+type EventType string
+
+const (
+	EventStatusChange    EventType = "StatusChange"
+	EventResourceUpdated EventType = "ResourceUpdated"
+	EventResourceAdded   EventType = "ResourceAdded"
+	EventResourceRemoved EventType = "ResourceRemoved"
+	EventAlert           EventType = "Alert"
+)
+
+// Event - a redfish event pushed by the cimc.
+//   Severity is OK, Warning or Critical.  Origin is the path of the resource
+//   the event is about, like the computer system for a power change.
+type Event struct {
+	Type      EventType
+	ID        string
+	Severity  string
+	Message   string
+	MessageID string
+	Origin    string
+	Time      time.Time
+	Context   string
+}
+
+// EventSubscription - an event subscription of the cimc, as listed by
+// EventSubscriptions.
+type EventSubscription struct {
+	ID          string
+	Destination string
+	EventTypes  []EventType
+	Context     string
+}
+
+// EventListener - a local http listener the cimc pushes events to, through
+// a subscription made by SubscribeEvents.  Close it to delete the
+// subscription.
+//   Only posts to the path of the subscription, which has a random token
+//   in it, with the Context of the subscription are taken.
+type EventListener struct {
+	// Events delivers the events, it is closed by Close.
+	Events <-chan Event
+	// Subscription is the url of the subscription on the cimc.
+	Subscription string
+
+	rs      *RedfishSession
+	srv     *http.Server
+	path    string
+	context string
+	events  chan Event
+	done    chan struct{}
+	once    sync.Once
+}
+
+// eventBuffer - how many events an EventListener holds for a slow reader.
+const eventBuffer = 64
+
+// SubscribeEvents - start a local http listener on the address the cimc
+// reaches us on, and subscribe it to events of types, all if none are
+// given.
+func (rs *RedfishSession) SubscribeEvents(ctx context.Context, types ...EventType) (*EventListener, error) {
+	ip, err := rs.localIP()
+	if err != nil {
+		return nil, err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, err
+	}
+	el := &EventListener{
+		rs:      rs,
+		path:    "/events/" + token,
+		context: rs.desc,
+		events:  make(chan Event, eventBuffer),
+		done:    make(chan struct{}),
+	}
+	el.Events = el.events
+	el.srv = &http.Server{Handler: http.HandlerFunc(el.handle)}
+	go el.srv.Serve(ln)
+
+	if len(types) == 0 {
+		types = []EventType{EventStatusChange, EventResourceUpdated, EventResourceAdded, EventResourceRemoved, EventAlert}
+	}
+	sub := map[string]interface{}{
+		"Destination": fmt.Sprintf("http://%s%s", ln.Addr(), el.path),
+		"EventTypes":  types,
+		"Context":     el.context,
+		"Protocol":    "Redfish",
+	}
+	resp, err := rs.request(ctx, http.MethodPost, "/redfish/v1/EventService/Subscriptions", sub)
+	if err != nil {
+		el.stop()
+		return nil, fmt.Errorf("failed to subscribe to events: %v", err)
+	}
+	resp.Body.Close()
+	el.Subscription = resp.Header.Get("Location")
+	if el.Subscription == "" {
+		el.stop()
+		return nil, fmt.Errorf("failed to subscribe to events: no Location of the subscription in the response")
+	}
+	return el, nil
+}
+
+// EventSubscriptions - return the event subscriptions of the cimc, of this
+// and of other clients.
+func (rs *RedfishSession) EventSubscriptions(ctx context.Context) ([]EventSubscription, error) {
+	subs := []EventSubscription{}
+	coll := struct{ Members []odataLink }{}
+	if err := rs.get(ctx, "/redfish/v1/EventService/Subscriptions", &coll); err != nil {
+		return subs, err
+	}
+	for _, m := range coll.Members {
+		sub := struct {
+			Destination string
+			EventTypes  []EventType
+			Context     string
+		}{}
+		if err := rs.get(ctx, m.ID, &sub); err != nil {
+			return subs, err
+		}
+		subs = append(subs, EventSubscription{ID: m.ID, Destination: sub.Destination, EventTypes: sub.EventTypes, Context: sub.Context})
+	}
+	return subs, nil
+}
+
+// DeleteEventSubscription - delete the event subscription id, for example
+// one left behind by a client that went away.
+func (rs *RedfishSession) DeleteEventSubscription(ctx context.Context, id string) error {
+	if err := rs.do(ctx, http.MethodDelete, id, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete event subscription %s: %v", id, err)
+	}
+	return nil
+}
+
+// Close - delete the subscription and stop listening.  Events is closed.
+func (el *EventListener) Close(ctx context.Context) error {
+	err := el.rs.DeleteEventSubscription(ctx, el.Subscription)
+	el.stop()
+	return err
+}
+
+func (el *EventListener) stop() {
+	el.once.Do(func() {
+		close(el.done)
+		// Shutdown waits for handlers, so nothing sends on events after.
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		el.srv.Shutdown(ctx)
+		close(el.events)
+	})
+}
+
+// handle - take the events the cimc posts, like this:
+// {"@odata.type": "#Event.v1_3_0.Event", "Context": "...", "Events": [
+//   {"EventType": "StatusChange", "EventId": "17", "Severity": "OK",
+//    "Message": "...", "MessageId": "...", "EventTimestamp": "...",
+//    "OriginOfCondition": {"@odata.id": "/redfish/v1/Systems/WZP2326007Q"}}]}
+func (el *EventListener) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != el.path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body := struct {
+		Context string
+		Events  []struct {
+			EventType         EventType
+			EventID           string `json:"EventId"`
+			Severity          string
+			Message           string
+			MessageID         string `json:"MessageId"`
+			EventTimestamp    string
+			OriginOfCondition odataLink
+		}
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Context != el.context {
+		http.Error(w, "not the context of the subscription", http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	for _, e := range body.Events {
+		ev := Event{
+			Type:      e.EventType,
+			ID:        e.EventID,
+			Severity:  e.Severity,
+			Message:   e.Message,
+			MessageID: e.MessageID,
+			Origin:    e.OriginOfCondition.ID,
+			Context:   body.Context,
+		}
+		ev.Time, _ = time.Parse(time.RFC3339, e.EventTimestamp)
+		select {
+		case el.events <- ev:
+		case <-el.done:
+			return
+		}
+	}
+}
+
+// localIP - the ip address of this host that connects to the cimc.  No
+// packet is sent to find it.
+func (rs *RedfishSession) localIP() (net.IP, error) {
+	u, err := url.Parse(rs.base)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(strings.Trim(u.Hostname(), "[]"), port))
+	if err != nil {
+		return nil, fmt.Errorf("no route to %s: %v", u.Host, err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
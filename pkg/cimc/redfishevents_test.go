@@ -0,0 +1,160 @@
+package cimc_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedfishEvents(t *testing.T) {
+	Convey("Given a redfish session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+		So(sess.RedfishEnable(ctx), ShouldBeNil)
+
+		srv := httptest.NewTLSServer(m.RedfishHandler())
+		defer srv.Close()
+		roots := x509.NewCertPool()
+		roots.AddCert(srv.Certificate())
+		rs, err := cimc.NewRedfishSession(srv.Listener.Addr().String(), "test", "test123", &tls.Config{RootCAs: roots})
+		So(err, ShouldBeNil)
+		defer rs.Close(ctx)
+
+		next := func(el *cimc.EventListener) (cimc.Event, bool) {
+			select {
+			case ev, ok := <-el.Events:
+				return ev, ok
+			case <-time.After(5 * time.Second):
+				return cimc.Event{}, false
+			}
+		}
+
+		Convey("SubscribeEvents() delivers power changes", func() {
+			el, err := rs.SubscribeEvents(ctx)
+			So(err, ShouldBeNil)
+			defer el.Close(ctx)
+
+			So(rs.PowerOff(ctx), ShouldBeNil)
+			ev, ok := next(el)
+			So(ok, ShouldBeTrue)
+			So(ev.Type, ShouldEqual, cimc.EventStatusChange)
+			So(ev.Message, ShouldContainSubstring, "off")
+			So(ev.Origin, ShouldStartWith, "/redfish/v1/Systems/")
+			So(ev.Time.IsZero(), ShouldBeFalse)
+
+			// a change on the command line is an event too.
+			So(sess.PowerOn(ctx), ShouldBeNil)
+			ev, ok = next(el)
+			So(ok, ShouldBeTrue)
+			So(ev.Message, ShouldContainSubstring, "on")
+		})
+
+		Convey("SubscribeEvents() filters by event type", func() {
+			el, err := rs.SubscribeEvents(ctx, cimc.EventAlert)
+			So(err, ShouldBeNil)
+			defer el.Close(ctx)
+
+			So(rs.PowerOff(ctx), ShouldBeNil)
+			m.RedfishAlert("Critical", "Power supply 2 failed")
+			ev, ok := next(el)
+			So(ok, ShouldBeTrue)
+			So(ev.Type, ShouldEqual, cimc.EventAlert)
+			So(ev.Severity, ShouldEqual, "Critical")
+			So(ev.Message, ShouldEqual, "Power supply 2 failed")
+		})
+
+		Convey("SubscribeEvents() takes posts only to its token with its context", func() {
+			el, err := rs.SubscribeEvents(ctx)
+			So(err, ShouldBeNil)
+			defer el.Close(ctx)
+			subs, err := rs.EventSubscriptions(ctx)
+			So(err, ShouldBeNil)
+			So(len(subs), ShouldEqual, 1)
+			dest, err := url.Parse(subs[0].Destination)
+			So(err, ShouldBeNil)
+			So(dest.Path, ShouldStartWith, "/events/")
+			So(len(dest.Path), ShouldBeGreaterThan, len("/events/"))
+
+			post := func(path, context string) int {
+				body := `{"Context": "` + context + `", "Events": [{"EventType": "Alert", "Message": "forged"}]}`
+				resp, err := http.Post("http://"+dest.Host+path, "application/json", strings.NewReader(body))
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+			So(post("/events", subs[0].Context), ShouldEqual, http.StatusNotFound)
+			So(post("/events/0123", subs[0].Context), ShouldEqual, http.StatusNotFound)
+			So(post(dest.Path, "someone else"), ShouldEqual, http.StatusForbidden)
+
+			m.RedfishAlert("Warning", "Fan 1 slow")
+			ev, ok := next(el)
+			So(ok, ShouldBeTrue)
+			So(ev.Message, ShouldEqual, "Fan 1 slow")
+		})
+
+		Convey("SubscribeEvents() fails without the Location of the subscription", func() {
+			noLoc := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				m.RedfishHandler().ServeHTTP(dropLocation{w}, r)
+			}))
+			defer noLoc.Close()
+			roots := x509.NewCertPool()
+			roots.AddCert(noLoc.Certificate())
+			s, err := cimc.NewRedfishSession(noLoc.Listener.Addr().String(), "test", "test123", &tls.Config{RootCAs: roots})
+			So(err, ShouldBeNil)
+			defer s.Close(ctx)
+
+			_, err = s.SubscribeEvents(ctx)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Location")
+		})
+
+		Convey("Close() deletes the subscription and closes Events", func() {
+			el, err := rs.SubscribeEvents(ctx)
+			So(err, ShouldBeNil)
+			subs, err := rs.EventSubscriptions(ctx)
+			So(err, ShouldBeNil)
+			So(len(subs), ShouldEqual, 1)
+			So(subs[0].ID, ShouldEqual, el.Subscription)
+			So(subs[0].Destination, ShouldStartWith, "http://")
+			So(len(subs[0].EventTypes), ShouldEqual, 5)
+
+			So(el.Close(ctx), ShouldBeNil)
+			_, ok := <-el.Events
+			So(ok, ShouldBeFalse)
+			subs, err = rs.EventSubscriptions(ctx)
+			So(err, ShouldBeNil)
+			So(subs, ShouldBeEmpty)
+
+			err = rs.DeleteEventSubscription(ctx, el.Subscription)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "No subscription")
+		})
+	})
+}
+
+// dropLocation - a ResponseWriter that drops the Location header of
+// responses to subscription posts, like a broken cimc.
+type dropLocation struct {
+	http.ResponseWriter
+}
+
+func (d dropLocation) WriteHeader(code int) {
+	if strings.Contains(d.Header().Get("Location"), "/Subscriptions/") {
+		d.Header().Del("Location")
+	}
+	d.ResponseWriter.WriteHeader(code)
+}
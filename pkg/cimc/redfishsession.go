@@ -0,0 +1,391 @@
+package cimc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	goexpect "github.com/google/goexpect"
+)
+
+// ErrUnsupported - what a session returns, wrapped, for an operation its
+// transport can not do, like OpenConsole over redfish.
+var ErrUnsupported = errors.New("operation not supported")
+
+// RedfishSession - a BMC over the redfish api of the cimc, which
+// RedfishEnable turns on.
+//   It is a PowerController and an InventoryProvider, and does one time
+//   boot and events.  It is not a ConsoleProvider, OpenConsole says so.
+type RedfishSession struct {
+	base   string
+	client *http.Client
+	token  string
+	// session is the url of our session, deleted on Close.
+	session string
+	// system is the path of the computer system.
+	system string
+	desc   string
+}
+
+// redfishBootTargets - the one time boot devices of the cimc command line,
+// to the redfish boot override targets.
+var redfishBootTargets = map[string]string{
+	"PXE":              "Pxe",
+	"HDD":              "Hdd",
+	"CIMC-Mapped-vDVD": "Cd",
+	"KVM-Mapped-vDVD":  "Cd",
+	"EFI":              "UefiShell",
+}
+
+// NewRedfishSession - return a RedfishSession, logging in to the redfish api
+// at https://addr with user and pass.
+//   tlsConfig sets how the cimc certificate is verified, nil verifies it
+//   against the system roots.  The self signed certificate of a new cimc
+//   needs its own RootCAs, or InsecureSkipVerify.
+func NewRedfishSession(addr, user, pass string, tlsConfig *tls.Config) (*RedfishSession, error) {
+	return newRedfishSession(context.Background(), addr, user, pass, tlsConfig)
+}
+
+// newRedfishSession - NewRedfishSession, giving up on the login when ctx
+// is done.
+func newRedfishSession(ctx context.Context, addr, user, pass string, tlsConfig *tls.Config) (*RedfishSession, error) {
+	rs := &RedfishSession{
+		base: "https://" + addr,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		desc: user + "@" + addr + " [redfish]",
+	}
+	fmt.Printf("Connecting to %s\n", rs.desc)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	creds := map[string]string{"UserName": user, "Password": pass}
+	resp, err := rs.request(ctx, http.MethodPost, "/redfish/v1/SessionService/Sessions", creds)
+	if err != nil {
+		return nil, fmt.Errorf("redfish login to %s failed: %v", addr, err)
+	}
+	resp.Body.Close()
+	rs.token = resp.Header.Get("X-Auth-Token")
+	rs.session = resp.Header.Get("Location")
+	if rs.token == "" {
+		return nil, fmt.Errorf("redfish login to %s returned no session token", addr)
+	}
+
+	systems := struct{ Members []odataLink }{}
+	if err := rs.get(ctx, "/redfish/v1/Systems", &systems); err != nil {
+		rs.Close(ctx)
+		return nil, err
+	}
+	if len(systems.Members) == 0 {
+		rs.Close(ctx)
+		return nil, fmt.Errorf("no computer system at %s", addr)
+	}
+	rs.system = systems.Members[0].ID
+	return rs, nil
+}
+
+func (rs RedfishSession) String() string {
+	return rs.desc
+}
+
+// Close - log out, deleting the redfish session.
+func (rs *RedfishSession) Close(ctx context.Context) error {
+	if rs.token == "" {
+		return fmt.Errorf("%s is not connected", rs.desc)
+	}
+	err := rs.do(ctx, http.MethodDelete, rs.session, nil, nil)
+	rs.token = ""
+	return err
+}
+
+// GetPowerState - return power state of system.
+func (rs *RedfishSession) GetPowerState(ctx context.Context) (PowerState, error) {
+	sys := struct{ PowerState string }{}
+	if err := rs.get(ctx, rs.system, &sys); err != nil {
+		return Unknown, err
+	}
+	switch sys.PowerState {
+	case "On":
+		return On, nil
+	case "Off":
+		return Off, nil
+	}
+	return Unknown, fmt.Errorf("bad power state '%s'", sys.PowerState)
+}
+
+// PowerOn - Turn power on, if off
+func (rs *RedfishSession) PowerOn(ctx context.Context) error {
+	return rs.reset(ctx, "On")
+}
+
+// PowerOff - Turn power off, if on
+func (rs *RedfishSession) PowerOff(ctx context.Context) error {
+	return rs.reset(ctx, "ForceOff")
+}
+
+// PowerCycle - Turn power off, if on, and then back on.
+func (rs *RedfishSession) PowerCycle(ctx context.Context) error {
+	state, err := rs.GetPowerState(ctx)
+	if err != nil {
+		return err
+	}
+	if state == Off {
+		return rs.reset(ctx, "On")
+	}
+	return rs.reset(ctx, "PowerCycle")
+}
+
+func (rs *RedfishSession) reset(ctx context.Context, resetType string) error {
+	body := map[string]string{"ResetType": resetType}
+	if err := rs.do(ctx, http.MethodPost, rs.system+"/Actions/ComputerSystem.Reset", body, nil); err != nil {
+		return fmt.Errorf("power %s failed: %v", resetType, err)
+	}
+	return nil
+}
+
+// OpenConsole - fail, the host console is not available over redfish.
+//   The error wraps ErrUnsupported.  Connect over ssh or ipmi for it.
+func (rs *RedfishSession) OpenConsole(ctx context.Context) (*goexpect.GExpect, error) {
+	return nil, fmt.Errorf("OpenConsole over redfish: the host console is only available over ssh or ipmi: %w", ErrUnsupported)
+}
+
+// SetOneTimeBoot - boot the host from device on its next boot only.
+//   device is a one time boot device of the cimc command line, like "PXE"
+//   or "CIMC-Mapped-vDVD", or a redfish boot override target, like "Pxe".
+//   The host is not rebooted.
+func (rs *RedfishSession) SetOneTimeBoot(ctx context.Context, device string) error {
+	target := device
+	if t, ok := redfishBootTargets[device]; ok {
+		target = t
+	}
+	sys := struct {
+		Boot struct {
+			Allowed []string `json:"BootSourceOverrideTarget@Redfish.AllowableValues"`
+		}
+	}{}
+	if err := rs.get(ctx, rs.system, &sys); err != nil {
+		return err
+	}
+	if len(sys.Boot.Allowed) > 0 && !containsString(sys.Boot.Allowed, target) {
+		return fmt.Errorf("one time boot device '%s' is not one of %s", device, strings.Join(sys.Boot.Allowed, ", "))
+	}
+
+	patch := map[string]interface{}{
+		"Boot": map[string]string{
+			"BootSourceOverrideEnabled": "Once",
+			"BootSourceOverrideTarget":  target,
+		},
+	}
+	if err := rs.do(ctx, http.MethodPatch, rs.system, patch, nil); err != nil {
+		return fmt.Errorf("failed to set one time boot device %s: %v", device, err)
+	}
+	return nil
+}
+
+// Inventory - return what redfish tells about the server.
+func (rs *RedfishSession) Inventory(ctx context.Context) (Inventory, error) {
+	sys := struct {
+		Manufacturer     string
+		Model            string
+		SerialNumber     string
+		UUID             string
+		BiosVersion      string
+		PowerState       string
+		ProcessorSummary struct {
+			Count int
+			Model string
+		}
+		MemorySummary struct{ TotalSystemMemoryGiB float64 }
+	}{}
+	if err := rs.get(ctx, rs.system, &sys); err != nil {
+		return Inventory{}, err
+	}
+	inv := Inventory{
+		Manufacturer:   sys.Manufacturer,
+		Model:          sys.Model,
+		SerialNumber:   sys.SerialNumber,
+		UUID:           sys.UUID,
+		BIOSVersion:    sys.BiosVersion,
+		Processors:     sys.ProcessorSummary.Count,
+		ProcessorModel: sys.ProcessorSummary.Model,
+		MemoryGiB:      sys.MemorySummary.TotalSystemMemoryGiB,
+	}
+	switch sys.PowerState {
+	case "On":
+		inv.PowerState = On
+	case "Off":
+		inv.PowerState = Off
+	}
+	return inv, nil
+}
+
+// FirmwareVersions - return the firmware inventory.  Redfish has no
+// backup versions, so Backup is empty.
+func (rs *RedfishSession) FirmwareVersions(ctx context.Context) ([]Firmware, error) {
+	fws := []Firmware{}
+	inv := struct{ Members []odataLink }{}
+	if err := rs.get(ctx, "/redfish/v1/UpdateService/FirmwareInventory", &inv); err != nil {
+		return fws, err
+	}
+	for _, m := range inv.Members {
+		item := struct {
+			ID            string `json:"Id"`
+			Name, Version string
+			RelatedItem   []odataLink
+		}{}
+		if err := rs.get(ctx, m.ID, &item); err != nil {
+			return fws, err
+		}
+		fw := Firmware{Name: item.Name, Running: item.Version}
+		switch {
+		case item.ID == "CIMC":
+			fw.Component = CIMCFirmware
+		case item.ID == "BIOS":
+			fw.Component = BIOSFirmware
+		case strings.HasPrefix(item.ID, "slot-"):
+			fw.Slot = strings.TrimPrefix(item.ID, "slot-")
+			fw.Component = AdapterFirmware
+			for _, r := range item.RelatedItem {
+				if strings.Contains(r.ID, "/Storage/") {
+					fw.Component = StorageFirmware
+				}
+			}
+		default:
+			continue
+		}
+		fws = append(fws, fw)
+	}
+	return fws, nil
+}
+
+// RedfishInfo - query state of redfish.  Redfish does not tell the maximum
+// number of sessions, so MaxSessions is 0.
+func (rs *RedfishSession) RedfishInfo(ctx context.Context) (RedfishStatus, error) {
+	status := RedfishStatus{Port: 443}
+	if u, err := url.Parse(rs.base); err == nil && u.Port() != "" {
+		status.Port, _ = strconv.Atoi(u.Port())
+	}
+	svc := struct {
+		ServiceEnabled bool
+		SessionTimeout int
+	}{}
+	if err := rs.get(ctx, "/redfish/v1/SessionService", &svc); err != nil {
+		return status, err
+	}
+	status.Enabled, status.SessionTimeout = svc.ServiceEnabled, svc.SessionTimeout
+	sessions := struct {
+		Count int `json:"Members@odata.count"`
+	}{}
+	if err := rs.get(ctx, "/redfish/v1/SessionService/Sessions", &sessions); err != nil {
+		return status, err
+	}
+	status.ActiveSessions = sessions.Count
+	return status, nil
+}
+
+// odataLink - a link to another redfish resource.
+type odataLink struct {
+	ID string `json:"@odata.id"`
+}
+
+// redfishError - the body of a redfish error reply.
+type redfishError struct {
+	Error struct {
+		Code         string `json:"code"`
+		Message      string `json:"message"`
+		ExtendedInfo []struct {
+			Message string
+		} `json:"@Message.ExtendedInfo"`
+	} `json:"error"`
+}
+
+func (rs *RedfishSession) get(ctx context.Context, path string, out interface{}) error {
+	return rs.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// do - send a request with body, if not nil, as json, and decode the json
+// reply into out, if not nil.
+func (rs *RedfishSession) do(ctx context.Context, method, path string, body, out interface{}) error {
+	resp, err := rs.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("bad reply to %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// request - send a request, return the reply if it succeeded.  Failures
+// return the message of the redfish error.
+func (rs *RedfishSession) request(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(buf)
+	}
+	target := path
+	if strings.HasPrefix(path, "/") {
+		target = rs.base + path
+	}
+	req, err := http.NewRequest(method, target, rd)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if rs.token != "" {
+		req.Header.Set("X-Auth-Token", rs.token)
+	}
+
+	resp, err := rs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	msg := resp.Status
+	rerr := redfishError{}
+	if json.NewDecoder(resp.Body).Decode(&rerr) == nil && rerr.Error.Message != "" {
+		msg = rerr.Error.Message
+		if len(rerr.Error.ExtendedInfo) > 0 {
+			msg = rerr.Error.ExtendedInfo[0].Message
+		}
+		msg += " (" + resp.Status + ")"
+	}
+	return nil, fmt.Errorf("%s %s: %s", method, path, msg)
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
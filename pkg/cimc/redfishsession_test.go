@@ -0,0 +1,170 @@
+package cimc_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedfishSession(t *testing.T) {
+	Convey("Given a CIMC with redfish enabled", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+		So(sess.RedfishEnable(ctx), ShouldBeNil)
+
+		srv := httptest.NewTLSServer(m.RedfishHandler())
+		defer srv.Close()
+		addr := srv.Listener.Addr().String()
+		roots := x509.NewCertPool()
+		roots.AddCert(srv.Certificate())
+		verify := &tls.Config{RootCAs: roots}
+
+		Convey("NewRedfishSession() verifies the certificate", func() {
+			_, err := cimc.NewRedfishSession(addr, "test", "test123", nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "certificate")
+		})
+
+		Convey("NewRedfishSession() needs a valid login", func() {
+			_, err := cimc.NewRedfishSession(addr, "test", "wrong", verify)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Invalid username or password")
+		})
+
+		Convey("NewRedfishSession() needs redfish enabled", func() {
+			So(sess.RedfishDisable(ctx), ShouldBeNil)
+			_, err := cimc.NewRedfishSession(addr, "test", "test123", verify)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "disabled")
+		})
+
+		Convey("The cimc web port serves redfish too", func() {
+			rs, err := cimc.NewRedfishSession(fmt.Sprintf("127.0.0.1:%d", m.HTTPSPort), "test", "test123",
+				&tls.Config{InsecureSkipVerify: true})
+			So(err, ShouldBeNil)
+			So(rs.Close(ctx), ShouldBeNil)
+		})
+
+		Convey("Given a redfish session", func() {
+			rs, err := cimc.NewRedfishSession(addr, "test", "test123", verify)
+			So(err, ShouldBeNil)
+
+			Convey("Power operations change the power state", func() {
+				So(rs.PowerOff(ctx), ShouldBeNil)
+				So(m.Get("chassis", "Power"), ShouldEqual, "off")
+				state, err := rs.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.Off)
+
+				So(rs.PowerCycle(ctx), ShouldBeNil)
+				state, err = rs.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.On)
+				So(rs.PowerCycle(ctx), ShouldBeNil)
+				So(rs.PowerOn(ctx), ShouldBeNil)
+			})
+
+			Convey("SetOneTimeBoot() sets the boot override", func() {
+				So(rs.SetOneTimeBoot(ctx, "PXE"), ShouldBeNil)
+				So(m.Get("bios", "one-time-boot-device"), ShouldEqual, "PXE")
+				So(rs.SetOneTimeBoot(ctx, "Cd"), ShouldBeNil)
+				So(m.Get("bios", "one-time-boot-device"), ShouldEqual, "CIMC-Mapped-vDVD")
+				So(rs.SetOneTimeBoot(ctx, "Floppy"), ShouldNotBeNil)
+			})
+
+			Convey("Inventory() describes the server", func() {
+				inv, err := rs.Inventory(ctx)
+				So(err, ShouldBeNil)
+				So(inv.SerialNumber, ShouldEqual, "WZP2326007Q")
+				So(inv.Model, ShouldEqual, "UCS C220 M5SX")
+				So(inv.Processors, ShouldEqual, 2)
+				So(inv.MemoryGiB, ShouldEqual, 384)
+				So(inv.PowerState, ShouldEqual, cimc.On)
+			})
+
+			Convey("FirmwareVersions() lists the firmware inventory", func() {
+				fws, err := rs.FirmwareVersions(ctx)
+				So(err, ShouldBeNil)
+				So(fws, ShouldContain, cimc.Firmware{Component: cimc.CIMCFirmware, Name: "Cisco IMC", Running: "4.1(2f)"})
+				So(fws, ShouldContain, cimc.Firmware{Component: cimc.StorageFirmware, Slot: "MRAID",
+					Name: "Cisco 12G Modular Raid Controller with 2GB cache", Running: "51.10.0-3151"})
+				So(fws, ShouldContain, cimc.Firmware{Component: cimc.AdapterFirmware, Slot: "MLOM",
+					Name: "UCS VIC 1457", Running: "5.1(2d)"})
+			})
+
+			Convey("OpenConsole() is unsupported", func() {
+				_, err := rs.OpenConsole(ctx)
+				So(errors.Is(err, cimc.ErrUnsupported), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "OpenConsole over redfish")
+			})
+
+			Convey("It has only the capabilities of redfish", func() {
+				var b cimc.BMC = rs
+				_, ok := b.(cimc.ConsoleProvider)
+				So(ok, ShouldBeFalse)
+				_, ok = b.(cimc.ConfigCommander)
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("Close() ends the redfish session", func() {
+				status, err := rs.RedfishInfo(ctx)
+				So(err, ShouldBeNil)
+				So(status.Enabled, ShouldBeTrue)
+				So(status.ActiveSessions, ShouldEqual, 1)
+				So(status.SessionTimeout, ShouldEqual, 1800)
+
+				So(rs.Close(ctx), ShouldBeNil)
+				status, err = sess.RedfishInfo(ctx)
+				So(err, ShouldBeNil)
+				So(status.ActiveSessions, ShouldEqual, 0)
+				So(rs.Close(ctx), ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestRedfishStatus(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("RedfishInfo() returns all redfish settings", func() {
+			So(sess.RedfishEnable(ctx), ShouldBeNil)
+			status, err := sess.RedfishInfo(ctx)
+			So(err, ShouldBeNil)
+			So(status, ShouldResemble, cimc.RedfishStatus{
+				Enabled: true, ActiveSessions: 0, MaxSessions: 4, Port: m.HTTPSPort, SessionTimeout: 1800,
+			})
+			So(status.Warning(), ShouldEqual, "")
+		})
+
+		Convey("Warning() warns close to the session limit", func() {
+			status := cimc.RedfishStatus{Enabled: true, ActiveSessions: 2, MaxSessions: 4}
+			So(status.Warning(), ShouldEqual, "")
+			status.ActiveSessions = 3
+			So(status.Warning(), ShouldContainSubstring, "close to its session limit, 3 of 4")
+			status.ActiveSessions = 4
+			So(status.Warning(), ShouldContainSubstring, "limit reached")
+			status.MaxSessions = 0
+			So(status.Warning(), ShouldEqual, "")
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ResetPhase - a phase of a cimc reboot or factory default.
+type ResetPhase string
+
+const (
+	// ResetDown - waiting for the cimc to drop the session.
+	ResetDown ResetPhase = "going down"
+	// ResetUp - waiting for the cimc to take a login again.
+	ResetUp ResetPhase = "coming back"
+	// ResetReady - waiting for the cimc command line to answer.
+	ResetReady ResetPhase = "getting ready"
+)
+
+// ResetTimeouts - how long RebootCIMC and FactoryDefault wait in each
+// phase at most.  The ctx they are given may end them sooner.
+var ResetTimeouts = map[ResetPhase]time.Duration{
+	ResetDown:  2 * time.Minute,
+	ResetUp:    10 * time.Minute,
+	ResetReady: 5 * time.Minute,
+}
+
+// RebootCIMC - reboot the cimc (not the host), wait for it to come back
+// and return a new session to it.  This session is closed.
+func (cs *Session) RebootCIMC(ctx context.Context) (CIMCSession, error) {
+	return cs.reset(ctx, "reboot", "/cimc/reboot", cs.user, cs.pass)
+}
+
+// FactoryDefault - reset the cimc configuration to factory default, wait
+// for it to come back and return a new session to it, logged in as user
+// with pass, the factory account (usually admin and password).  This
+// session is closed.
+//   The management network is reset too, so the cimc has to come back at
+//   the same address for this to work.
+func (cs *Session) FactoryDefault(ctx context.Context, user, pass string) (CIMCSession, error) {
+	return cs.reset(ctx, "factory default", "/cimc/factory-default", user, pass)
+}
+
+// reset - send cmd, which makes the cimc reboot, and wait through each
+// ResetPhase for it to come back.
+func (cs *Session) reset(ctx context.Context, what, cmd, user, pass string) (CIMCSession, error) {
+	if cs.sshClient == nil {
+		return nil, fmt.Errorf("%s is not connected", cs.desc)
+	}
+	dropped := make(chan struct{})
+	go func(clt *ssh.Client) {
+		clt.Wait()
+		close(dropped)
+	}(cs.sshClient)
+
+	// the cimc may drop the session before we see a prompt again, so an
+	// error here only counts if the cimc said so.
+	if _, err := cs.SendCmd(ctx, cmd); err != nil && isCIMCError(err) {
+		return nil, fmt.Errorf("failed to start cimc %s: %v", what, err)
+	}
+
+	err := resetPhase(ctx, what, ResetDown, func(ctx context.Context) error {
+		select {
+		case <-dropped:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	cs.disconnect()
+	if err != nil {
+		return nil, err
+	}
+
+	next := &Session{addr: cs.addr, user: user, pass: pass, opts: cs.opts}
+	err = resetPhase(ctx, what, ResetUp, func(ctx context.Context) error {
+		return next.reconnect(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = resetPhase(ctx, what, ResetReady, func(ctx context.Context) error {
+		for {
+			_, err := next.SendCmd(ctx, "/cimc/show detail")
+			if err == nil {
+				return nil
+			}
+			// the cimc may still drop us while it starts up.
+			if next.reconnect(ctx) != nil {
+				return fmt.Errorf("%v (last error: %v)", ctx.Err(), err)
+			}
+		}
+	})
+	if err != nil {
+		next.disconnect()
+		return nil, err
+	}
+	return next, nil
+}
+
+// resetPhase - run wait with the timeout of phase, and say which phase of
+// the cimc 'what' stalled if it fails.
+func resetPhase(ctx context.Context, what string, phase ResetPhase, wait func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, ResetTimeouts[phase])
+	defer cancel()
+	if err := wait(ctx); err != nil {
+		return fmt.Errorf("cimc %s stalled %s (%s at most): %v", what, phase, ResetTimeouts[phase], err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cimc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReset(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		Convey("RebootCIMC() returns a new session once the cimc is back", func() {
+			m.Set("cimc/network", "hostname", "kept")
+			next, err := sess.RebootCIMC(ctx)
+			So(err, ShouldBeNil)
+			defer next.Close(ctx)
+
+			_, err = sess.SendCmd(ctx, "/cimc/show detail")
+			So(err, ShouldNotBeNil)
+
+			cfg, err := next.GetNetwork(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Hostname, ShouldEqual, "kept")
+		})
+
+		Convey("FactoryDefault() resets the configuration and logs in as admin", func() {
+			m.Set("cimc/network", "hostname", "lost")
+			next, err := sess.FactoryDefault(ctx, test.Users[0][0], test.Users[0][1])
+			So(err, ShouldBeNil)
+			defer next.Close(ctx)
+
+			So(m.Get("cimc/network", "hostname"), ShouldEqual, "C220-WZP2326007Q")
+			users, err := next.Users(ctx)
+			So(err, ShouldBeNil)
+			So(len(users), ShouldEqual, 1)
+			So(users[0].Name, ShouldEqual, "admin")
+		})
+
+		Convey("RebootCIMC() says which phase stalled", func() {
+			defer func(up time.Duration) { cimc.ResetTimeouts[cimc.ResetUp] = up }(cimc.ResetTimeouts[cimc.ResetUp])
+			cimc.ResetTimeouts[cimc.ResetUp] = 200 * time.Millisecond
+			m.RebootTime = 5 * time.Second
+
+			_, err := sess.RebootCIMC(ctx)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "reboot stalled coming back")
+		})
+	})
+}
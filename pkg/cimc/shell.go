@@ -0,0 +1,180 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	goexpect "github.com/google/goexpect"
+	"github.com/google/goterm/term"
+	"golang.org/x/crypto/ssh"
+)
+
+// shell - an interactive command line over ssh on a pty.  A command is
+// sent a line at a time, its response ends at the next prompt, and confirm
+// prompts on the way are answered.  Session drives the cimc command line
+// with it, IDRACSession racadm.
+type shell struct {
+	sshClient         *ssh.Client
+	exp               *goexpect.GExpect
+	promptRe          *regexp.Regexp
+	promptOrConfirmRe *regexp.Regexp
+}
+
+// dial - log in to addr as user over ssh, start the command line on a pty
+// and wait for firstRe, the first prompt.  Return what came up to it and
+// its submatches.  If ctx is done first, the login is dropped.
+//   sshClient is set even if the command line does not start, so the
+//   caller can close it.
+func (sh *shell) dial(ctx context.Context, addr, user, pass string, opts []goexpect.Option, firstRe *regexp.Regexp) (string, []string, error) {
+	conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", nil, err
+	}
+	// closing conn stops the handshake or the wait for the prompt.
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	data, subs, err := sh.login(conn, addr, user, pass, opts, firstRe)
+	close(done)
+	<-exited
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return data, subs, err
+}
+
+func (sh *shell) login(conn net.Conn, addr, user, pass string, opts []goexpect.Option, firstRe *regexp.Regexp) (string, []string, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.Password(pass)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		conn.Close()
+		return "", nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	sshClt := ssh.NewClient(c, chans, reqs)
+	sh.sshClient = sshClt
+
+	// we can't simply use the SpawnSSH for connecting to a cimc because
+	// the cimc basically refuses to send content if ssh.ECHO is set to 0
+	// Presumably that is to attempt to force people to use it interactively.
+	tios := term.Termios{}
+	tios.Raw()
+	tios.Wz.WsCol, tios.Wz.WsRow = 0, 32768
+	tios.Lflag |= term.ECHO
+
+	e, _, err := goexpect.SpawnSSHPTY(sshClt, timeout, tios, opts...)
+	if err != nil {
+		return "", nil, err
+	}
+	sh.exp = e
+
+	data, subs, err := e.Expect(firstRe, timeout)
+	return data, subs, err
+}
+
+// setPrompt - expect promptReStr at the end of every response from now on.
+func (sh *shell) setPrompt(promptReStr string) {
+	sh.promptRe = regexp.MustCompile(promptReStr)
+	sh.promptOrConfirmRe = regexp.MustCompile(promptReStr + "|" + confirmReStr)
+}
+
+// converse - send line and return the lines of the response, without the
+// echo of line and without prompt lines.  A confirm prompt is answered
+// with answer.
+func (sh *shell) converse(line, answer string) ([]string, error) {
+	if err := sh.exp.Send(line + "\n"); err != nil {
+		return nil, err
+	}
+
+	// data has
+	//  * the command we sent (due to ECHO)
+	//  * multi line response
+	//  * prompt line
+	data, _, err := sh.exp.Expect(sh.promptOrConfirmRe, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if confirmRe.MatchString(data) {
+		// Confirm prompt
+		if err := sh.exp.Send(answer + "\n"); err != nil {
+			return nil, fmt.Errorf("failed to send '%s' to a confirm response: %s", answer, err)
+		}
+		afterConfirm, _, err := sh.exp.Expect(sh.promptRe, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("error after confirming operation: %s", err)
+		}
+		data += afterConfirm
+	}
+
+	fulldata := strings.Replace(data, ctrlM, "", -1)
+	lines := strings.Split(fulldata, "\n")
+
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("Failed to parse response from '%s': %s", line, data)
+	}
+
+	promptLine := strings.TrimSpace(lines[len(lines)-1])
+
+	// sometimes we get multiple prompt lines in response.
+	dataLines := []string{}
+	for _, line := range lines[1 : len(lines)-1] {
+		if strings.TrimSpace(line) == promptLine {
+			continue
+		}
+		dataLines = append(dataLines, line)
+	}
+	return dataLines, nil
+}
+
+// logout - close the command line and the connection, for Close of the
+// session desc.
+func (sh *shell) logout(desc string) error {
+	if sh.exp == nil {
+		return fmt.Errorf("%s is not connected", desc)
+	}
+	err := sh.exp.Close()
+	if cerr := sh.sshClient.Close(); err == nil {
+		err = cerr
+	}
+	sh.exp, sh.sshClient = nil, nil
+	return err
+}
+
+// detach - leave the host console of the session desc by sending escape,
+// and wait for the prompt of the command line.
+func (sh *shell) detach(desc, escape string) error {
+	if sh.exp == nil {
+		return fmt.Errorf("%s is not connected", desc)
+	}
+	if err := sh.exp.Send(escape); err != nil {
+		return err
+	}
+	_, _, err := sh.exp.Expect(sh.promptRe, timeout)
+	return err
+}
+
+// disconnect - close the connection, ignoring errors.  For when the other
+// end is known to have dropped it.
+func (sh *shell) disconnect() {
+	if sh.exp != nil {
+		sh.exp.Close()
+	}
+	if sh.sshClient != nil {
+		sh.sshClient.Close()
+	}
+	sh.exp, sh.sshClient = nil, nil
+}
@@ -0,0 +1,167 @@
+package cimc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	goexpect "github.com/google/goexpect"
+)
+
+// smashPromptReStr - the prompt of a DMTF SMASH-CLP command line, with the
+// current target in front on iLO, like '</system1>hpiLO-> ', or just '-> '.
+const smashPromptReStr = `(</[^>\s]*>)?[-0-9A-Za-z_]*-> `
+
+var smashPromptRe = regexp.MustCompile(smashPromptReStr)
+
+// smashEscape - ends 'vsp', ESC then '('.
+const smashEscape = "\x1b("
+
+// smashConsoleRe - what 'vsp' answers, the way out of the console or a
+// failed status up to the next prompt.
+var smashConsoleRe = regexp.MustCompile(`Press 'ESC \(' to return to the CLI Session|status=([1-9][0-9]*)(?s:.*)` + smashPromptReStr)
+
+// SMASHSession - a BMC on the DMTF SMASH-CLP command line of a bmc, like
+// HPE iLO, over ssh.
+//   It is a PowerController of /system1, a ConsoleProvider on the virtual
+//   serial port (vsp) and a ConfigCommander of clp commands.
+type SMASHSession struct {
+	shell
+	cliPower
+	desc string
+}
+
+// NewSMASHSession - return a SMASHSession, logging in with password and
+// user@addr
+//   For example NewSMASHSession("10.0.0.3:22", "Administrator", "password")
+func NewSMASHSession(addr, user, pass string) (*SMASHSession, error) {
+	return NewSMASHSessionOpts(addr, user, pass, []goexpect.Option{})
+}
+
+// NewSMASHSessionOpts - return a SMASHSession, with provided
+// goexpect.Option list.
+func NewSMASHSessionOpts(addr, user, pass string, opts []goexpect.Option) (*SMASHSession, error) {
+	sh := shell{}
+	if _, _, err := sh.dial(context.Background(), addr, user, pass, opts, smashPromptRe); err != nil {
+		sh.disconnect()
+		return nil, fmt.Errorf("%s@%s: %v", user, addr, err)
+	}
+	return newSMASHSession(sh, addr, user), nil
+}
+
+// newSMASHSession - return a SMASHSession on sh, logged in and at the
+// first clp prompt.
+func newSMASHSession(sh shell, addr, user string) *SMASHSession {
+	ss := &SMASHSession{shell: sh, desc: user + "@" + addr + " [smash-clp]"}
+	ss.setPrompt(smashPromptReStr)
+	// stop does not wait for the host to shut down.
+	ss.cliPower = cliPower{
+		on:    "start /system1",
+		off:   "stop -f /system1",
+		cycle: "reset /system1",
+		state: ss.GetPowerState,
+		send:  ss.SendCmd,
+	}
+	return ss
+}
+
+func (ss SMASHSession) String() string {
+	return ss.desc
+}
+
+// Close - log out of the bmc.
+func (ss *SMASHSession) Close(ctx context.Context) error {
+	return ss.logout(ss.desc)
+}
+
+// SendCmd - send a clp command, like "show /system1".  Return its response.
+//   A non zero 'status=' in the response is returned as an error with
+//   the error_tag and the last line of the response, which says why.
+func (ss *SMASHSession) SendCmd(ctx context.Context, msg string) (string, error) {
+	if ss.exp == nil {
+		return "", fmt.Errorf("%s is not connected", ss.desc)
+	}
+	lines, err := ss.converse(msg, "y")
+	if err != nil {
+		return "", err
+	}
+	response := strings.Join(lines, "\n") + "\n"
+
+	status, tag, last := "", "", ""
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "status="):
+			status = strings.TrimPrefix(line, "status=")
+		case strings.HasPrefix(line, "error_tag="):
+			tag = strings.TrimPrefix(line, "error_tag=")
+		case line != "":
+			last = line
+		}
+	}
+	if status != "" && status != "0" {
+		if tag == "" {
+			tag = "status " + status
+		}
+		return response, errors.New(tag + ": " + last)
+	}
+	return response, nil
+}
+
+// GetPowerState - return the power state of /system1, from its
+// enabledstate.
+func (ss *SMASHSession) GetPowerState(ctx context.Context) (PowerState, error) {
+	resp, err := ss.SendCmd(ctx, "show /system1 enabledstate")
+	if err != nil {
+		return Unknown, err
+	}
+	for _, line := range strings.Split(resp, "\n") {
+		toks := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(toks) != 2 || !strings.EqualFold(toks[0], "enabledstate") {
+			continue
+		}
+		switch strings.ToLower(toks[1]) {
+		case "enabled":
+			return On, nil
+		case "disabled":
+			return Off, nil
+		}
+		return Unknown, fmt.Errorf("bad power state '%s'", toks[1])
+	}
+	return Unknown, fmt.Errorf("did not find power state in %s", resp)
+}
+
+// OpenConsole - attach to the virtual serial port of the host with 'vsp'.
+//   Return the expecter of the session; CloseConsole detaches.
+func (ss *SMASHSession) OpenConsole(ctx context.Context) (*goexpect.GExpect, error) {
+	if ss.exp == nil {
+		return nil, fmt.Errorf("%s is not connected", ss.desc)
+	}
+	if err := ss.exp.Send("vsp\n"); err != nil {
+		return nil, err
+	}
+	data, subs, err := ss.exp.Expect(smashConsoleRe, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if subs[1] != "" {
+		// still at the clp prompt, after the reason.
+		lines := strings.Split(strings.Replace(data, ctrlM, "", -1), "\n")
+		why := ""
+		for _, line := range lines[:len(lines)-1] {
+			if line = strings.TrimSpace(line); line != "" {
+				why = line
+			}
+		}
+		return nil, fmt.Errorf("%s: vsp: status %s: %s", ss.desc, subs[1], why)
+	}
+	return ss.exp, nil
+}
+
+// CloseConsole - detach from the virtual serial port with 'ESC (', back to
+// the clp.
+func (ss *SMASHSession) CloseConsole(ctx context.Context) error {
+	return ss.detach(ss.desc, smashEscape)
+}
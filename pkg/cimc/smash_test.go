@@ -0,0 +1,137 @@
+package cimc_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	"github.com/anuvu/axepect/pkg/loginshell"
+	"github.com/anuvu/axepect/pkg/test"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSMASHSession(t *testing.T) {
+	Convey("Given a mock SMASH-CLP bmc", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m := test.NewMockSMASH()
+		So(m.Start(), ShouldBeNil)
+		defer m.Close()
+		addr := fmt.Sprintf("127.0.0.1:%d", m.Port)
+
+		Convey("NewSMASHSession() needs the right password", func() {
+			_, err := cimc.NewSMASHSession(addr, test.SMASHUser, "wrong")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Given a SMASH-CLP session", func() {
+			ss, err := cimc.NewSMASHSession(addr, test.SMASHUser, test.SMASHPassword)
+			So(err, ShouldBeNil)
+			defer ss.Close(ctx)
+
+			Convey("Power operations change the power state", func() {
+				state, err := ss.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.On)
+				So(ss.PowerOn(ctx), ShouldBeNil)
+
+				So(ss.PowerOff(ctx), ShouldBeNil)
+				So(m.Get("/system1", "enabledstate"), ShouldEqual, "disabled")
+				state, err = ss.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.Off)
+				So(ss.PowerOff(ctx), ShouldBeNil)
+
+				So(ss.PowerCycle(ctx), ShouldBeNil)
+				So(m.Get("/system1", "enabledstate"), ShouldEqual, "enabled")
+				So(ss.PowerCycle(ctx), ShouldBeNil)
+				So(m.Get("/system1", "enabledstate"), ShouldEqual, "enabled")
+			})
+
+			Convey("SendCmd() runs clp commands", func() {
+				resp, err := ss.SendCmd(ctx, "show /map1/firmware1 version")
+				So(err, ShouldBeNil)
+				So(resp, ShouldContainSubstring, "status=0")
+				So(resp, ShouldContainSubstring, "version=2.44")
+
+				_, err = ss.SendCmd(ctx, "cd /system1")
+				So(err, ShouldBeNil)
+				resp, err = ss.SendCmd(ctx, "show")
+				So(err, ShouldBeNil)
+				So(resp, ShouldContainSubstring, "  Targets\n    cpu1\n")
+
+				_, err = ss.SendCmd(ctx, "start")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "COMMAND ERROR-UNSPECIFIED: Server power already on.")
+
+				_, err = ss.SendCmd(ctx, "frobnicate")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "COMMAND NOT RECOGNIZED: Invalid command.")
+			})
+
+			Convey("It has only the capabilities of the clp", func() {
+				var b cimc.BMC = ss
+				_, ok := b.(cimc.InventoryProvider)
+				So(ok, ShouldBeFalse)
+				_, ok = b.(cimc.CIMCSession)
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("loginshell logs in on the virtual serial port", func() {
+				exp, err := ss.OpenConsole(ctx)
+				So(err, ShouldBeNil)
+
+				// one session at a time has the vsp.
+				other, err := cimc.NewSMASHSession(addr, test.SMASHUser, test.SMASHPassword)
+				So(err, ShouldBeNil)
+				defer other.Close(ctx)
+				_, err = other.OpenConsole(ctx)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "in use by another session")
+
+				shell, err := loginshell.Login(exp, test.HostUser, test.HostPassword)
+				So(err, ShouldBeNil)
+				ret := shell.Run("echo hello from the host")
+				So(ret.RC, ShouldEqual, 0)
+				So(ret.Output, ShouldEqual, "hello from the host")
+				So(shell.Logout(), ShouldBeNil)
+				So(ss.CloseConsole(ctx), ShouldBeNil)
+
+				// back at the clp, and the vsp is free.
+				state, err := ss.GetPowerState(ctx)
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, cimc.On)
+
+				exp, err = other.OpenConsole(ctx)
+				So(err, ShouldBeNil)
+				So(exp.Send("\n"), ShouldBeNil)
+				_, _, err = exp.Expect(regexp.MustCompile("login:"), 5*time.Second)
+				So(err, ShouldBeNil)
+				So(other.CloseConsole(ctx), ShouldBeNil)
+			})
+
+			Convey("A closed session is not connected", func() {
+				So(ss.Close(ctx), ShouldBeNil)
+				err := ss.CloseConsole(ctx)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "is not connected")
+				So(ss.Close(ctx), ShouldNotBeNil)
+			})
+		})
+
+		Convey("A generic bmc prompts with just '-> '", func() {
+			m.Name = ""
+			ss, err := cimc.NewSMASHSession(addr, test.SMASHUser, test.SMASHPassword)
+			So(err, ShouldBeNil)
+			defer ss.Close(ctx)
+			So(ss.PowerCycle(ctx), ShouldBeNil)
+			state, err := ss.GetPowerState(ctx)
+			So(err, ShouldBeNil)
+			So(state, ShouldEqual, cimc.On)
+		})
+	})
+}
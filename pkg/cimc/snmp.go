@@ -0,0 +1,296 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// SNMPConfig - snmp settings of the cimc (/snmp).
+//   CommunityAccess is Full, Limited or Disabled.  EngineID is read-only.
+//   Users are the snmp v3 users, Traps the trap destinations.
+type SNMPConfig struct {
+	Enabled         bool   `cimc:"enabled,SNMP Enabled"`
+	Port            int    `cimc:"snmp-port,SNMP Port"`
+	Community       string `cimc:"community-str,Access Community String"`
+	CommunityAccess string `cimc:"community-access,SNMP Community access"`
+	TrapCommunity   string `cimc:"trap-community-str,Trap Community String"`
+	SysContact      string `cimc:"sys-contact,System Contact"`
+	SysLocation     string `cimc:"sys-location,System Location"`
+	EngineID        string `cimc:",Engine ID"`
+	Users           []SNMPUser
+	Traps           []SNMPTrap
+}
+
+// SNMPUser - an snmp v3 user.
+//   Level is noauthnopriv, authnopriv or authpriv (the default).
+//   AuthProto is MD5 or SHA (the default), PrivProto DES or AES (the
+//   default).  AuthKey and PrivKey are never read back, and only set if
+//   not empty.
+type SNMPUser struct {
+	Name      string `cimc:"v3security-name,Security Name"`
+	Level     string `cimc:"v3security-level,Security Level"`
+	AuthProto string `cimc:"v3proto,Auth Type"`
+	AuthKey   string
+	PrivProto string `cimc:"v3priv-proto,Privacy Type"`
+	PrivKey   string
+}
+
+// SNMPTrap - an snmp trap destination.
+//   Version is 1, 2 (the default) or 3, which sends as User.
+//   Type is trap (the default) or inform, Port defaults to 162.
+type SNMPTrap struct {
+	Enabled bool   `cimc:"enabled,Enabled"`
+	Version string `cimc:"version,SNMP version"`
+	Type    string `cimc:"type,Trap type"`
+	User    string `cimc:"user,User"`
+	Address string `cimc:"trap-addr,Trap Destination Address"`
+	Port    int    `cimc:"trap-port,Trap Destination Port"`
+}
+
+// unusedSNMPUser, unusedSNMPTrap - what the cimc shows for free slots.
+var unusedSNMPUser = SNMPUser{Level: "authpriv", AuthProto: "SHA", PrivProto: "AES"}
+var unusedSNMPTrap = SNMPTrap{Version: "2", Type: "trap", Address: "0.0.0.0", Port: 162}
+
+// GetSNMP - return the snmp settings.
+func (cs *Session) GetSNMP(ctx context.Context) (SNMPConfig, error) {
+	cfg := SNMPConfig{Users: []SNMPUser{}, Traps: []SNMPTrap{}}
+	resp, err := cs.SendCmd(ctx, "/snmp/show detail")
+	if err != nil {
+		return cfg, err
+	}
+	if err := decodeDetail(parseDetail(resp), &cfg); err != nil {
+		return cfg, err
+	}
+
+	users, err := snmpUsers(ctx, cs)
+	if err != nil {
+		return cfg, err
+	}
+	for _, u := range users {
+		if u.Name != "" {
+			cfg.Users = append(cfg.Users, u)
+		}
+	}
+
+	traps, err := snmpTraps(ctx, cs)
+	if err != nil {
+		return cfg, err
+	}
+	for _, t := range traps {
+		if t.Address != "" && t.Address != unusedSNMPTrap.Address {
+			cfg.Traps = append(cfg.Traps, t)
+		}
+	}
+	return cfg, nil
+}
+
+// SetSNMP - change the snmp settings to cfg.
+//   Users and Traps fill the cimc slots in order, the rest are cleared.
+func (cs *Session) SetSNMP(ctx context.Context, cfg SNMPConfig) error {
+	cur, err := cs.GetSNMP(ctx)
+	if err != nil {
+		return err
+	}
+	users, err := snmpUsers(ctx, cs)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Users) > len(users) {
+		return fmt.Errorf("cimc takes at most %d snmp users, got %d", len(users), len(cfg.Users))
+	}
+	traps, err := snmpTraps(ctx, cs)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Traps) > len(traps) {
+		return fmt.Errorf("cimc takes at most %d snmp trap destinations, got %d", len(traps), len(cfg.Traps))
+	}
+
+	if err := commitCmds(ctx, cs, "/snmp", setChanges(cur, cfg)); err != nil {
+		return err
+	}
+	for i := range users {
+		want := SNMPUser{}
+		if i < len(cfg.Users) {
+			want = cfg.Users[i]
+		}
+		if err := setSNMPUser(ctx, cs, i+1, users[i], want); err != nil {
+			return err
+		}
+	}
+	for i := range traps {
+		want := unusedSNMPTrap
+		if i < len(cfg.Traps) {
+			want = cfg.Traps[i]
+		}
+		if err := setSNMPTrap(ctx, cs, i+1, traps[i], want); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifySNMPTraps - check that snmp traps from the cimc reach this host.
+//   A free trap destination is pointed at a local udp listener, the cimc
+//   sends a test trap, and we wait for it until ctx is done.  The trap
+//   destination is cleared afterwards.  snmp must be enabled.
+func (cs *Session) VerifySNMPTraps(ctx context.Context) error {
+	cfg, err := cs.GetSNMP(ctx)
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled {
+		return fmt.Errorf("snmp is disabled on %s", cs.desc)
+	}
+	traps, err := snmpTraps(ctx, cs)
+	if err != nil {
+		return err
+	}
+	slot := 0
+	for i, t := range traps {
+		if t.Address == "" || t.Address == unusedSNMPTrap.Address {
+			slot = i + 1
+			break
+		}
+	}
+	if slot == 0 {
+		return fmt.Errorf("no free snmp trap destination to verify with")
+	}
+
+	conn, err := cs.listenUDP()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	laddr := conn.LocalAddr().(*net.UDPAddr)
+
+	test := SNMPTrap{Enabled: true, Version: "2", Type: "trap", Address: laddr.IP.String(), Port: laddr.Port}
+	if err := setSNMPTrap(ctx, cs, slot, traps[slot-1], test); err != nil {
+		return err
+	}
+	defer setSNMPTrap(context.Background(), cs, slot, test, unusedSNMPTrap)
+
+	if err := cs.enterScope(ctx, "snmp", "trap-destinations "+strconv.Itoa(slot)); err != nil {
+		return err
+	}
+	if _, err := cs.SendCmd(ctx, "sendSNMPtrap"); err != nil {
+		return fmt.Errorf("failed to send test trap: %v", err)
+	}
+	if _, err := waitUDP(ctx, conn); err != nil {
+		return fmt.Errorf("test trap did not arrive at %s: %v", laddr, err)
+	}
+	return nil
+}
+
+// snmpUsers - return all snmp v3 user slots, with an empty name if unused.
+// Expected input looks like this:
+// User 1:
+//    Security Name: monitor
+//    Security Level: authpriv
+//    Auth Type: SHA
+//    Privacy Type: AES
+func snmpUsers(ctx context.Context, cs *Session) ([]SNMPUser, error) {
+	users := []SNMPUser{}
+	resp, err := cs.SendCmd(ctx, "/snmp/show v3users detail")
+	if err != nil {
+		return users, err
+	}
+	for _, b := range parseDetailList(resp) {
+		u := SNMPUser{}
+		if err := decodeDetail(b.Props, &u); err != nil {
+			return users, fmt.Errorf("snmp %s: %v", b.Title, err)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// snmpTraps - return all trap destination slots.
+// Expected input looks like this:
+// Trap Destination 1:
+//    Enabled: yes
+//    SNMP version: 2
+//    Trap type: trap
+//    User:
+//    Trap Destination Address: 10.0.0.9
+//    Trap Destination Port: 162
+func snmpTraps(ctx context.Context, cs *Session) ([]SNMPTrap, error) {
+	traps := []SNMPTrap{}
+	resp, err := cs.SendCmd(ctx, "/snmp/show trap-destinations detail")
+	if err != nil {
+		return traps, err
+	}
+	for _, b := range parseDetailList(resp) {
+		t := SNMPTrap{}
+		if err := decodeDetail(b.Props, &t); err != nil {
+			return traps, fmt.Errorf("snmp %s: %v", b.Title, err)
+		}
+		traps = append(traps, t)
+	}
+	return traps, nil
+}
+
+// setSNMPUser - change v3 user slot to want.  An empty name frees the
+// slot, which also clears its other settings.
+func setSNMPUser(ctx context.Context, cs *Session, slot int, cur, want SNMPUser) error {
+	cmds := []string{}
+	if want.Name == "" {
+		if cur.Name != "" {
+			cmds = append(cmds, `set v3security-name ""`)
+		}
+		return commitIn(ctx, cs, []string{"snmp", "v3users " + strconv.Itoa(slot)}, cmds)
+	}
+
+	if want.Level == "" {
+		want.Level = unusedSNMPUser.Level
+	}
+	if want.AuthProto == "" {
+		want.AuthProto = unusedSNMPUser.AuthProto
+	}
+	if want.PrivProto == "" {
+		want.PrivProto = unusedSNMPUser.PrivProto
+	}
+	cmds = setChanges(cur, want)
+	if len(cmds) == 0 && want.AuthKey == "" && want.PrivKey == "" {
+		return nil
+	}
+
+	if err := cs.enterScope(ctx, "snmp", "v3users "+strconv.Itoa(slot)); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if _, err := cs.SendCmd(ctx, cmd); err != nil {
+			cs.SendCmd(ctx, "discard")
+			return fmt.Errorf("failed to %s for snmp user %d: %v", cmd, slot, err)
+		}
+	}
+	for _, key := range [][2]string{{"v3auth-key", want.AuthKey}, {"v3priv-auth-key", want.PrivKey}} {
+		name := key[0]
+		if key[1] == "" {
+			continue
+		}
+		if err := cs.setSecret(ctx, name, key[1]); err != nil {
+			cs.SendCmd(ctx, "discard")
+			return fmt.Errorf("failed to set %s for snmp user %d: %v", name, slot, err)
+		}
+	}
+	if _, err := cs.SendCmd(ctx, "commit"); err != nil {
+		return fmt.Errorf("failed to commit snmp user %d: %v", slot, err)
+	}
+	return nil
+}
+
+func setSNMPTrap(ctx context.Context, cs *Session, slot int, cur, want SNMPTrap) error {
+	if want.Version == "" {
+		want.Version = unusedSNMPTrap.Version
+	}
+	if want.Type == "" {
+		want.Type = unusedSNMPTrap.Type
+	}
+	if want.Port == 0 {
+		want.Port = unusedSNMPTrap.Port
+	}
+	return commitIn(ctx, cs, []string{"snmp", "trap-destinations " + strconv.Itoa(slot)}, setChanges(cur, want))
+}
@@ -0,0 +1,90 @@
+package cimc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSNMP(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("GetSNMP() returns the defaults", func() {
+			cfg, err := sess.GetSNMP(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Enabled, ShouldBeFalse)
+			So(cfg.Port, ShouldEqual, 161)
+			So(cfg.CommunityAccess, ShouldEqual, "Disabled")
+			So(cfg.EngineID, ShouldNotBeEmpty)
+			So(cfg.Users, ShouldBeEmpty)
+			So(cfg.Traps, ShouldBeEmpty)
+		})
+
+		Convey("SetSNMP() configures communities, users and traps", func() {
+			cfg, err := sess.GetSNMP(ctx)
+			So(err, ShouldBeNil)
+			cfg.Enabled = true
+			cfg.Community = "lab ro"
+			cfg.CommunityAccess = "Limited"
+			cfg.SysLocation = "rack 12"
+			cfg.Users = []cimc.SNMPUser{
+				{Name: "monitor", AuthKey: "Auth-Key-1", PrivKey: "Priv-Key-1"},
+				{Name: "legacy", Level: "noauthnopriv"},
+			}
+			cfg.Traps = []cimc.SNMPTrap{
+				{Enabled: true, Version: "3", User: "monitor", Address: "10.0.0.9"},
+			}
+			So(sess.SetSNMP(ctx, cfg), ShouldBeNil)
+			So(m.Get("snmp/v3users 1", "v3auth-key"), ShouldEqual, "Auth-Key-1")
+			So(m.Get("snmp/v3users 1", "v3priv-auth-key"), ShouldEqual, "Priv-Key-1")
+
+			got, err := sess.GetSNMP(ctx)
+			So(err, ShouldBeNil)
+			So(got.Community, ShouldEqual, "lab ro")
+			So(got.SysLocation, ShouldEqual, "rack 12")
+			So(got.Users, ShouldResemble, []cimc.SNMPUser{
+				{Name: "monitor", Level: "authpriv", AuthProto: "SHA", PrivProto: "AES"},
+				{Name: "legacy", Level: "noauthnopriv", AuthProto: "SHA", PrivProto: "AES"},
+			})
+			So(got.Traps, ShouldResemble, []cimc.SNMPTrap{
+				{Enabled: true, Version: "3", Type: "trap", User: "monitor", Address: "10.0.0.9", Port: 162},
+			})
+
+			Convey("removing a user deletes it", func() {
+				got.Users = got.Users[1:]
+				So(sess.SetSNMP(ctx, got), ShouldBeNil)
+				So(m.Get("snmp/v3users 2", "v3security-name"), ShouldEqual, "")
+				So(m.Get("snmp/v3users 1", "v3security-name"), ShouldEqual, "legacy")
+			})
+
+			Convey("VerifySNMPTraps() receives the test trap", func() {
+				So(sess.VerifySNMPTraps(ctx), ShouldBeNil)
+				after, err := sess.GetSNMP(ctx)
+				So(err, ShouldBeNil)
+				So(after.Traps, ShouldResemble, got.Traps)
+			})
+		})
+
+		Convey("SetSNMP() refuses a short v3 key", func() {
+			cfg, err := sess.GetSNMP(ctx)
+			So(err, ShouldBeNil)
+			cfg.Users = []cimc.SNMPUser{{Name: "monitor", AuthKey: "short"}}
+			So(sess.SetSNMP(ctx, cfg), ShouldNotBeNil)
+			So(m.Get("snmp/v3users 1", "v3security-name"), ShouldEqual, "")
+		})
+
+		Convey("VerifySNMPTraps() needs snmp enabled", func() {
+			So(sess.VerifySNMPTraps(ctx), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,50 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+)
+
+// SOLConfig - serial over lan settings of the cimc (/sol).
+//   BaudRate is one of 9600, 19200, 38400, 57600 or 115200, and must match
+//   the host's serial console.  ComPort is com0 or com1.  SSHPort is where
+//   the cimc serves the console over ssh directly.
+type SOLConfig struct {
+	Enabled  bool   `cimc:"enabled,Enabled"`
+	BaudRate int    `cimc:"baud-rate,Baud Rate(bps)"`
+	ComPort  string `cimc:"comport,Com Port"`
+	SSHPort  int    `cimc:"ssh-port,SSH Port"`
+}
+
+// GetSOL - return the serial over lan settings.
+func (cs *Session) GetSOL(ctx context.Context) (SOLConfig, error) {
+	cfg := SOLConfig{}
+	resp, err := cs.SendCmd(ctx, "/sol/show detail")
+	if err != nil {
+		return cfg, err
+	}
+	err = decodeDetail(parseDetail(resp), &cfg)
+	return cfg, err
+}
+
+// SetSOL - change the serial over lan settings to cfg.
+func (cs *Session) SetSOL(ctx context.Context, cfg SOLConfig) error {
+	cur, err := cs.GetSOL(ctx)
+	if err != nil {
+		return err
+	}
+	return commitCmds(ctx, cs, "/sol", setChanges(cur, cfg))
+}
+
+// checkSOL - return an error saying what to do if the console can not work.
+func (cs *Session) checkSOL(ctx context.Context) error {
+	cfg, err := cs.GetSOL(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read serial over lan settings: %v", err)
+	}
+	if !cfg.Enabled {
+		return fmt.Errorf("serial over lan is disabled on %s, enable it with SetSOL "+
+			"(or 'scope sol', 'set enabled yes', 'commit' on the cimc)", cs.desc)
+	}
+	return nil
+}
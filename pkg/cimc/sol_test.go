@@ -0,0 +1,60 @@
+package cimc_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSOL(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("GetSOL() returns the defaults", func() {
+			cfg, err := sess.GetSOL(ctx)
+			So(err, ShouldBeNil)
+			So(cfg, ShouldResemble, cimc.SOLConfig{Enabled: false, BaudRate: 115200, ComPort: "com0", SSHPort: 2400})
+		})
+
+		Convey("OpenConsole() fails right away while SOL is disabled", func() {
+			start := time.Now()
+			_, err := sess.OpenConsole(ctx)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "SetSOL")
+			So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+		})
+
+		Convey("SetSOL() changes the settings", func() {
+			want := cimc.SOLConfig{Enabled: true, BaudRate: 9600, ComPort: "com1", SSHPort: 2400}
+			So(sess.SetSOL(ctx, want), ShouldBeNil)
+			cfg, err := sess.GetSOL(ctx)
+			So(err, ShouldBeNil)
+			So(cfg, ShouldResemble, want)
+
+			want.BaudRate = 1200
+			So(sess.SetSOL(ctx, want), ShouldNotBeNil)
+
+			Convey("and OpenConsole() then works", func() {
+				exp, err := sess.OpenConsole(ctx)
+				So(err, ShouldBeNil)
+				So(exp.Send("hello host\n"), ShouldBeNil)
+				_, _, err = exp.Expect(regexp.MustCompile("hello host"), 5*time.Second)
+				So(err, ShouldBeNil)
+				So(sess.CloseConsole(ctx), ShouldBeNil)
+
+				_, err = sess.GetSOL(ctx)
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,381 @@
+package cimc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Reasons a storage operation fails, the Err of a StorageError.  Other
+// failures carry what the cimc said.
+var (
+	ErrNoController     = errors.New("no such storage controller")
+	ErrNoDrive          = errors.New("no such drive")
+	ErrDriveUnavailable = errors.New("physical drive is not unconfigured good")
+	ErrForeignConfig    = errors.New("physical drive has a foreign configuration")
+	ErrRAIDLevel        = errors.New("unsupported raid level or number of drives")
+)
+
+// StorageError - a failed storage operation.
+//   Drive, if set, is the drive it failed on, like "physical-drive 3".
+//   Use errors.Is with the Err values above to tell why.
+type StorageError struct {
+	Op         string
+	Controller string
+	Drive      string
+	Err        error
+}
+
+func (e *StorageError) Error() string {
+	s := e.Op + " on " + e.Controller
+	if e.Drive != "" {
+		s += " " + e.Drive
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// StorageController - a storage controller, named by its pci slot, like
+// "MRAID".
+type StorageController struct {
+	Slot         string
+	Name         string `cimc:",Product Name"`
+	Health       string `cimc:",Health"`
+	Status       string `cimc:",Controller Status"`
+	SerialNumber string `cimc:",Serial Number"`
+	Firmware     string `cimc:",Firmware Package Build"`
+}
+
+// PhysicalDrive - a physical drive of a storage controller.
+//   Status is like "Unconfigured Good", "Online" or "JBOD".  Only unconfigured
+//   good drives without ForeignConfig make new virtual drives.
+type PhysicalDrive struct {
+	ID            int
+	Health        string `cimc:",Health"`
+	Status        string `cimc:",Status"`
+	Manufacturer  string `cimc:",Manufacturer"`
+	Model         string `cimc:",Model"`
+	Type          string `cimc:",Type"`
+	Interface     string `cimc:",Interface Type"`
+	ForeignConfig bool   `cimc:",Foreign Config"`
+	SizeMB        int
+}
+
+// VirtualDrive - a virtual drive (raid volume) of a storage controller.
+type VirtualDrive struct {
+	ID        int
+	Name      string `cimc:",Name"`
+	Status    string `cimc:",Status"`
+	Health    string `cimc:",Health"`
+	BootDrive bool   `cimc:",Boot Drive"`
+	RAIDLevel int
+	SizeMB    int
+	Drives    []int
+}
+
+// VirtualDriveSpec - a virtual drive for CreateVirtualDrive.
+//   RAIDLevel is one of 0, 1, 5, 6 or 10.  RAID 1 mirrors pairs of drives,
+//   so it takes an even number of them, as the MegaRAID controllers do.
+//   RAID 10 spans the first and the second half of Drives, so it takes a
+//   multiple of four.  Name is at most 15 characters, no spaces.
+//   SizeMB 0 takes all the space of the drives.
+type VirtualDriveSpec struct {
+	Name      string
+	RAIDLevel int
+	Drives    []int
+	SizeMB    int
+}
+
+// raidMinDrives - the raid levels the cimc creates, and how many drives each
+// needs at least.
+var raidMinDrives = map[int]int{0: 1, 1: 2, 5: 3, 6: 4, 10: 4}
+
+// wizardRe - match a question of the cimc 'create-virtual-drive' wizard,
+// like "Please enter Virtual Drive name (15 characters maximum)--> "
+var wizardRe = regexp.MustCompile(`(?m)^([^\n]*)-->[ ]*$`)
+
+var spanRe = regexp.MustCompile(`span (\d+)`)
+
+// StorageControllers - return the storage controllers.
+func (cs *Session) StorageControllers(ctx context.Context) ([]StorageController, error) {
+	ctrls := []StorageController{}
+	resp, err := cs.SendCmd(ctx, "/chassis/show storageadapter detail")
+	if err != nil {
+		return ctrls, err
+	}
+	for _, b := range parseDetailList(resp) {
+		c := StorageController{Slot: strings.TrimPrefix(b.Title, "PCI Slot ")}
+		if err := decodeDetail(b.Props, &c); err != nil {
+			return ctrls, fmt.Errorf("%s: %v", b.Title, err)
+		}
+		ctrls = append(ctrls, c)
+	}
+	return ctrls, nil
+}
+
+// PhysicalDrives - return the physical drives of controller.
+// Expected input looks like this:
+// Physical Drive 1:
+//    Controller: MRAID
+//    Health: Good
+//    Status: Unconfigured Good
+//    Manufacturer: SEAGATE
+//    Model: ST1200MM0088
+//    Type: HDD
+//    Interface Type: SAS
+//    Coerced Size: 1143455 MB
+//    Foreign Config: no
+func (cs *Session) PhysicalDrives(ctx context.Context, controller string) ([]PhysicalDrive, error) {
+	drives := []PhysicalDrive{}
+	resp, err := showStorage(ctx, cs, "list physical drives", controller, "physical-drive")
+	if err != nil {
+		return drives, err
+	}
+	for _, b := range parseDetailList(resp) {
+		d := PhysicalDrive{}
+		if err := decodeDetail(b.Props, &d); err != nil {
+			return drives, fmt.Errorf("%s: %v", b.Title, err)
+		}
+		d.ID, _ = strconv.Atoi(strings.TrimPrefix(b.Title, "Physical Drive "))
+		d.SizeMB = parseSizeMB(b.Props["Coerced Size"])
+		drives = append(drives, d)
+	}
+	return drives, nil
+}
+
+// VirtualDrives - return the virtual drives of controller.
+// Expected input looks like this:
+// Virtual Drive 0:
+//    Name: boot
+//    RAID Level: RAID 1
+//    Size: 1143455 MB
+//    Status: Optimal
+//    Health: Good
+//    Boot Drive: true
+//    Physical Drives: 1,2
+func (cs *Session) VirtualDrives(ctx context.Context, controller string) ([]VirtualDrive, error) {
+	vds := []VirtualDrive{}
+	resp, err := showStorage(ctx, cs, "list virtual drives", controller, "virtual-drive")
+	if err != nil {
+		return vds, err
+	}
+	for _, b := range parseDetailList(resp) {
+		vd := VirtualDrive{Drives: []int{}}
+		if err := decodeDetail(b.Props, &vd); err != nil {
+			return vds, fmt.Errorf("%s: %v", b.Title, err)
+		}
+		vd.ID, _ = strconv.Atoi(strings.TrimPrefix(b.Title, "Virtual Drive "))
+		vd.RAIDLevel, _ = strconv.Atoi(strings.TrimPrefix(b.Props["RAID Level"], "RAID "))
+		vd.SizeMB = parseSizeMB(b.Props["Size"])
+		for _, pd := range strings.Split(b.Props["Physical Drives"], ",") {
+			if id, err := strconv.Atoi(strings.TrimSpace(pd)); err == nil {
+				vd.Drives = append(vd.Drives, id)
+			}
+		}
+		vds = append(vds, vd)
+	}
+	return vds, nil
+}
+
+// CreateVirtualDrive - create the virtual drive spec on controller, going
+// through the cimc wizard.  Return the new virtual drive.
+func (cs *Session) CreateVirtualDrive(ctx context.Context, controller string, spec VirtualDriveSpec) (VirtualDrive, error) {
+	const op = "create virtual drive"
+	fail := func(drive string, err error) (VirtualDrive, error) {
+		return VirtualDrive{}, &StorageError{Op: op, Controller: controller, Drive: drive, Err: err}
+	}
+
+	if spec.Name == "" || len(spec.Name) > 15 || strings.ContainsAny(spec.Name, " \t") {
+		return fail("", fmt.Errorf("bad virtual drive name '%s'", spec.Name))
+	}
+	min, ok := raidMinDrives[spec.RAIDLevel]
+	n := len(spec.Drives)
+	if !ok || n < min || (spec.RAIDLevel == 1 && n%2 != 0) || (spec.RAIDLevel == 10 && n%4 != 0) {
+		return fail("", fmt.Errorf("%w: RAID %d with %d drives", ErrRAIDLevel, spec.RAIDLevel, n))
+	}
+
+	drives, err := cs.PhysicalDrives(ctx, controller)
+	if err != nil {
+		return VirtualDrive{}, err
+	}
+	byID := map[int]PhysicalDrive{}
+	for _, d := range drives {
+		byID[d.ID] = d
+	}
+	for _, id := range spec.Drives {
+		d, ok := byID[id]
+		name := "physical-drive " + strconv.Itoa(id)
+		switch {
+		case !ok:
+			return fail(name, ErrNoDrive)
+		case d.ForeignConfig:
+			return fail(name, ErrForeignConfig)
+		case d.Status != "Unconfigured Good":
+			return fail(name, fmt.Errorf("%w: %s", ErrDriveUnavailable, d.Status))
+		}
+	}
+
+	spans := [][]int{spec.Drives}
+	if spec.RAIDLevel == 10 {
+		spans = [][]int{spec.Drives[:n/2], spec.Drives[n/2:]}
+	}
+	answer := func(q string) string {
+		switch {
+		case strings.Contains(q, "RAID level"):
+			return strconv.Itoa(spec.RAIDLevel)
+		case spanRe.MatchString(q):
+			i, _ := strconv.Atoi(spanRe.FindStringSubmatch(q)[1])
+			if i >= len(spans) {
+				return ""
+			}
+			ids := []string{}
+			for _, id := range spans[i] {
+				ids = append(ids, strconv.Itoa(id))
+			}
+			return strings.Join(ids, ",")
+		case strings.Contains(q, "name"):
+			return spec.Name
+		case strings.Contains(q, "size") && spec.SizeMB > 0:
+			return fmt.Sprintf("%d MB", spec.SizeMB)
+		case strings.Contains(q, "OK?"):
+			return "y"
+		}
+		return ""
+	}
+
+	if err := storageScope(ctx, cs, op, controller, ""); err != nil {
+		return VirtualDrive{}, err
+	}
+	if err := cs.exp.Send("create-virtual-drive\n"); err != nil {
+		return VirtualDrive{}, err
+	}
+	questionRe := regexp.MustCompile(cs.promptRe.String() + "|" + wizardRe.String())
+	out := ""
+	for {
+		data, _, err := cs.exp.Expect(questionRe, timeout)
+		if err != nil {
+			return fail("", fmt.Errorf("wizard did not finish: %v", err))
+		}
+		data = strings.Replace(data, ctrlM, "", -1)
+		out += data
+		if cs.promptRe.MatchString(data) {
+			break
+		}
+		if err := cs.exp.Send(answer(wizardRe.FindStringSubmatch(data)[1]) + "\n"); err != nil {
+			return VirtualDrive{}, err
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "Error:") {
+			return fail("", errors.New(strings.TrimSpace(line)))
+		}
+	}
+
+	vds, err := cs.VirtualDrives(ctx, controller)
+	if err != nil {
+		return VirtualDrive{}, err
+	}
+	for _, vd := range vds {
+		if vd.Name == spec.Name {
+			return vd, nil
+		}
+	}
+	return fail("", fmt.Errorf("virtual drive '%s' not found after creating it", spec.Name))
+}
+
+// DeleteVirtualDrive - delete the virtual drive id of controller, freeing
+// its physical drives.  All data on it is lost.
+func (cs *Session) DeleteVirtualDrive(ctx context.Context, controller string, id int) error {
+	return virtualDriveCmd(ctx, cs, "delete virtual drive", controller, id, "delete-virtual-drive")
+}
+
+// SetBootDrive - make the virtual drive id of controller the one the host
+// boots from.
+func (cs *Session) SetBootDrive(ctx context.Context, controller string, id int) error {
+	return virtualDriveCmd(ctx, cs, "set boot drive", controller, id, "set-boot-drive")
+}
+
+// ClearForeignConfig - clear the foreign configuration of all physical
+// drives of controller, for drives moved from another server.
+func (cs *Session) ClearForeignConfig(ctx context.Context, controller string) error {
+	const op = "clear foreign config"
+	if err := storageScope(ctx, cs, op, controller, ""); err != nil {
+		return err
+	}
+	if _, err := cs.SendCmd(ctx, "clear-foreign-config"); err != nil {
+		return &StorageError{Op: op, Controller: controller, Err: err}
+	}
+	return nil
+}
+
+// showStorage - 'show <kind> detail' in the scope of controller.
+func showStorage(ctx context.Context, cs *Session, op, controller, kind string) (string, error) {
+	if err := storageScope(ctx, cs, op, controller, ""); err != nil {
+		return "", err
+	}
+	return cs.SendCmd(ctx, "show "+kind+" detail")
+}
+
+// virtualDriveCmd - send cmd, which the cimc asks to confirm, in the scope
+// of the virtual drive id.
+func virtualDriveCmd(ctx context.Context, cs *Session, op, controller string, id int, cmd string) error {
+	drive := "virtual-drive " + strconv.Itoa(id)
+	if err := storageScope(ctx, cs, op, controller, drive); err != nil {
+		return err
+	}
+	if _, err := cs.SendCmd(ctx, cmd); err != nil {
+		return &StorageError{Op: op, Controller: controller, Drive: drive, Err: err}
+	}
+	return nil
+}
+
+// storageScope - go to the scope of controller, and of drive in it if drive
+// is not empty.  A scope the cimc does not know is a StorageError.
+func storageScope(ctx context.Context, cs *Session, op, controller, drive string) error {
+	if err := cs.enterScope(ctx, "chassis"); err != nil {
+		return err
+	}
+	if _, err := cs.SendCmd(ctx, "scope storageadapter "+controller); err != nil {
+		if isCIMCError(err) {
+			err = ErrNoController
+		}
+		return &StorageError{Op: op, Controller: controller, Err: err}
+	}
+	if drive == "" {
+		return nil
+	}
+	if _, err := cs.SendCmd(ctx, "scope "+drive); err != nil {
+		if isCIMCError(err) {
+			err = ErrNoDrive
+		}
+		return &StorageError{Op: op, Controller: controller, Drive: drive, Err: err}
+	}
+	return nil
+}
+
+// parseSizeMB - parse a size like "1143455 MB" or "1.5 TB" to MB.
+func parseSizeMB(s string) int {
+	f := strings.Fields(s)
+	if len(f) == 0 {
+		return 0
+	}
+	n, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return 0
+	}
+	if len(f) > 1 {
+		switch strings.ToUpper(f[1]) {
+		case "GB":
+			n *= 1024
+		case "TB":
+			n *= 1024 * 1024
+		}
+	}
+	return int(n)
+}
@@ -0,0 +1,127 @@
+package cimc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStorage(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("StorageControllers() lists the raid controller", func() {
+			ctrls, err := sess.StorageControllers(ctx)
+			So(err, ShouldBeNil)
+			So(len(ctrls), ShouldEqual, 1)
+			So(ctrls[0].Slot, ShouldEqual, "MRAID")
+			So(ctrls[0].Status, ShouldEqual, "Optimal")
+			So(ctrls[0].Firmware, ShouldEqual, "51.10.0-3151")
+		})
+
+		Convey("PhysicalDrives() lists the drives", func() {
+			pds, err := sess.PhysicalDrives(ctx, "MRAID")
+			So(err, ShouldBeNil)
+			So(len(pds), ShouldEqual, 8)
+			So(pds[0].ID, ShouldEqual, 1)
+			So(pds[0].Status, ShouldEqual, "Unconfigured Good")
+			So(pds[0].SizeMB, ShouldEqual, 1143455)
+			So(pds[0].ForeignConfig, ShouldBeFalse)
+			So(pds[7].ForeignConfig, ShouldBeTrue)
+		})
+
+		Convey("CreateVirtualDrive() builds a raid volume", func() {
+			vd, err := sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "boot", RAIDLevel: 1, Drives: []int{1, 2}})
+			So(err, ShouldBeNil)
+			So(vd.ID, ShouldEqual, 0)
+			So(vd.RAIDLevel, ShouldEqual, 1)
+			So(vd.SizeMB, ShouldEqual, 1143455)
+			So(vd.Drives, ShouldResemble, []int{1, 2})
+
+			data, err := sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "data", RAIDLevel: 10, Drives: []int{3, 4, 5, 6}, SizeMB: 100000})
+			So(err, ShouldBeNil)
+			So(data.ID, ShouldEqual, 1)
+			So(data.SizeMB, ShouldEqual, 100000)
+
+			pds, err := sess.PhysicalDrives(ctx, "MRAID")
+			So(err, ShouldBeNil)
+			So(pds[0].Status, ShouldEqual, "Online")
+
+			Convey("SetBootDrive() marks it the boot drive", func() {
+				So(sess.SetBootDrive(ctx, "MRAID", 0), ShouldBeNil)
+				vds, err := sess.VirtualDrives(ctx, "MRAID")
+				So(err, ShouldBeNil)
+				So(vds[0].BootDrive, ShouldBeTrue)
+				So(vds[1].BootDrive, ShouldBeFalse)
+			})
+
+			Convey("DeleteVirtualDrive() frees its drives", func() {
+				So(sess.DeleteVirtualDrive(ctx, "MRAID", 0), ShouldBeNil)
+				vds, err := sess.VirtualDrives(ctx, "MRAID")
+				So(err, ShouldBeNil)
+				So(len(vds), ShouldEqual, 1)
+				So(vds[0].Name, ShouldEqual, "data")
+
+				pds, err := sess.PhysicalDrives(ctx, "MRAID")
+				So(err, ShouldBeNil)
+				So(pds[0].Status, ShouldEqual, "Unconfigured Good")
+			})
+
+			Convey("Used drives are refused", func() {
+				_, err := sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "again", RAIDLevel: 0, Drives: []int{2}})
+				So(errors.Is(err, cimc.ErrDriveUnavailable), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "physical-drive 2")
+			})
+		})
+
+		Convey("CreateVirtualDrive() mirrors four drives in RAID 1", func() {
+			vd, err := sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "mirror", RAIDLevel: 1, Drives: []int{1, 2, 3, 4}})
+			So(err, ShouldBeNil)
+			So(vd.RAIDLevel, ShouldEqual, 1)
+			So(vd.Drives, ShouldResemble, []int{1, 2, 3, 4})
+		})
+
+		Convey("Foreign drives are refused until the foreign config is cleared", func() {
+			_, err := sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "old", RAIDLevel: 1, Drives: []int{7, 8}})
+			So(errors.Is(err, cimc.ErrForeignConfig), ShouldBeTrue)
+
+			So(sess.ClearForeignConfig(ctx, "MRAID"), ShouldBeNil)
+			_, err = sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "old", RAIDLevel: 1, Drives: []int{7, 8}})
+			So(err, ShouldBeNil)
+
+			err = sess.ClearForeignConfig(ctx, "MRAID")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "No foreign configuration")
+		})
+
+		Convey("Errors are typed", func() {
+			_, err := sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "r5", RAIDLevel: 5, Drives: []int{1, 2}})
+			So(errors.Is(err, cimc.ErrRAIDLevel), ShouldBeTrue)
+
+			_, err = sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "r1", RAIDLevel: 1, Drives: []int{1, 2, 3}})
+			So(errors.Is(err, cimc.ErrRAIDLevel), ShouldBeTrue)
+
+			_, err = sess.CreateVirtualDrive(ctx, "MRAID", cimc.VirtualDriveSpec{Name: "r0", RAIDLevel: 0, Drives: []int{9}})
+			So(errors.Is(err, cimc.ErrNoDrive), ShouldBeTrue)
+
+			_, err = sess.PhysicalDrives(ctx, "SBMezz1")
+			So(errors.Is(err, cimc.ErrNoController), ShouldBeTrue)
+
+			err = sess.DeleteVirtualDrive(ctx, "MRAID", 5)
+			So(errors.Is(err, cimc.ErrNoDrive), ShouldBeTrue)
+			serr := &cimc.StorageError{}
+			So(errors.As(err, &serr), ShouldBeTrue)
+			So(serr.Drive, ShouldEqual, "virtual-drive 5")
+		})
+	})
+}
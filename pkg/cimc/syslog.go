@@ -0,0 +1,182 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SyslogServer - a remote syslog server of the cimc.
+//   Protocol is udp or tcp, Port defaults to 514.
+type SyslogServer struct {
+	Enabled  bool   `cimc:"enabled,Enabled"`
+	Address  string `cimc:"server-ip,Server IP Address"`
+	Port     int    `cimc:"server-port,Server Port"`
+	Protocol string `cimc:"protocol,Protocol"`
+}
+
+// SyslogConfig - remote syslog settings of the cimc (/cimc/log).
+//   Severity is the lowest severity sent, for example "informational".
+type SyslogConfig struct {
+	Severity string `cimc:"remote-syslog-severity,Remote Syslog Severity"`
+	Servers  []SyslogServer
+}
+
+// unusedSyslogServer - what the cimc shows for a free syslog server slot.
+var unusedSyslogServer = SyslogServer{Address: "0.0.0.0", Port: 514, Protocol: "udp"}
+
+// GetSyslog - return the remote syslog settings.
+func (cs *Session) GetSyslog(ctx context.Context) (SyslogConfig, error) {
+	cfg := SyslogConfig{Servers: []SyslogServer{}}
+	resp, err := cs.SendCmd(ctx, "/cimc/log/show detail")
+	if err != nil {
+		return cfg, err
+	}
+	if err := decodeDetail(parseDetail(resp), &cfg); err != nil {
+		return cfg, err
+	}
+
+	servers, err := syslogServers(ctx, cs)
+	if err != nil {
+		return cfg, err
+	}
+	for _, srv := range servers {
+		if srv.Address != "" && srv.Address != unusedSyslogServer.Address {
+			cfg.Servers = append(cfg.Servers, srv)
+		}
+	}
+	return cfg, nil
+}
+
+// SetSyslog - change the remote syslog settings to cfg.
+//   Servers fill the cimc slots in order, the rest are cleared.
+func (cs *Session) SetSyslog(ctx context.Context, cfg SyslogConfig) error {
+	cur, err := cs.GetSyslog(ctx)
+	if err != nil {
+		return err
+	}
+	servers, err := syslogServers(ctx, cs)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Servers) > len(servers) {
+		return fmt.Errorf("cimc takes at most %d syslog servers, got %d", len(servers), len(cfg.Servers))
+	}
+
+	if err := commitCmds(ctx, cs, "/cimc/log", setChanges(cur, cfg)); err != nil {
+		return err
+	}
+	for i := range servers {
+		want := unusedSyslogServer
+		if i < len(cfg.Servers) {
+			want = cfg.Servers[i]
+		}
+		if err := setSyslogServer(ctx, cs, i+1, servers[i], want); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifySyslog - check that syslog from the cimc reaches this host.
+//   A free syslog server slot is pointed at a local udp listener, the cimc
+//   sends a test message, and we wait for it until ctx is done.  The slot
+//   is cleared afterwards.
+func (cs *Session) VerifySyslog(ctx context.Context) error {
+	servers, err := syslogServers(ctx, cs)
+	if err != nil {
+		return err
+	}
+	slot := 0
+	for i, srv := range servers {
+		if srv.Address == "" || srv.Address == unusedSyslogServer.Address {
+			slot = i + 1
+			break
+		}
+	}
+	if slot == 0 {
+		return fmt.Errorf("no free syslog server slot to verify with")
+	}
+
+	conn, err := cs.listenUDP()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	laddr := conn.LocalAddr().(*net.UDPAddr)
+
+	test := SyslogServer{Enabled: true, Address: laddr.IP.String(), Port: laddr.Port, Protocol: "udp"}
+	if err := setSyslogServer(ctx, cs, slot, servers[slot-1], test); err != nil {
+		return err
+	}
+	defer setSyslogServer(context.Background(), cs, slot, test, unusedSyslogServer)
+
+	if _, err := cs.SendCmd(ctx, "/cimc/log/send-test-syslog"); err != nil {
+		return fmt.Errorf("failed to send test syslog: %v", err)
+	}
+	if _, err := waitUDP(ctx, conn); err != nil {
+		return fmt.Errorf("test syslog did not arrive at %s: %v", laddr, err)
+	}
+	return nil
+}
+
+// syslogServers - return all syslog server slots.
+// Expected input looks like this:
+// Syslog Server 1:
+//    Server IP Address: 10.0.0.9
+//    Server Port: 514
+//    Protocol: udp
+//    Enabled: yes
+func syslogServers(ctx context.Context, cs *Session) ([]SyslogServer, error) {
+	servers := []SyslogServer{}
+	resp, err := cs.SendCmd(ctx, "/cimc/log/show server detail")
+	if err != nil {
+		return servers, err
+	}
+	for _, b := range parseDetailList(resp) {
+		srv := SyslogServer{}
+		if err := decodeDetail(b.Props, &srv); err != nil {
+			return servers, fmt.Errorf("%s: %v", b.Title, err)
+		}
+		servers = append(servers, srv)
+	}
+	return servers, nil
+}
+
+func setSyslogServer(ctx context.Context, cs *Session, slot int, cur, want SyslogServer) error {
+	if want.Port == 0 {
+		want.Port = unusedSyslogServer.Port
+	}
+	if want.Protocol == "" {
+		want.Protocol = unusedSyslogServer.Protocol
+	}
+	return commitIn(ctx, cs, []string{"cimc", "log", "server " + strconv.Itoa(slot)}, setChanges(cur, want))
+}
+
+// listenUDP - listen on a free udp port of the address the cimc reaches
+// us on, for test events sent by the cimc.
+func (cs *Session) listenUDP() (*net.UDPConn, error) {
+	ip, err := cs.localIP()
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+}
+
+// waitUDP - return the first datagram that arrives on conn, waiting until
+// ctx is done, or for at most 'timeout' if ctx has no deadline.
+func waitUDP(ctx context.Context, conn *net.UDPConn) ([]byte, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,76 @@
+package cimc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSyslog(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("GetSyslog() returns the defaults", func() {
+			cfg, err := sess.GetSyslog(ctx)
+			So(err, ShouldBeNil)
+			So(cfg, ShouldResemble, cimc.SyslogConfig{Severity: "informational", Servers: []cimc.SyslogServer{}})
+		})
+
+		Convey("SetSyslog() configures servers in order", func() {
+			want := cimc.SyslogConfig{
+				Severity: "warning",
+				Servers: []cimc.SyslogServer{
+					{Enabled: true, Address: "10.0.0.9", Port: 514, Protocol: "udp"},
+					{Enabled: true, Address: "10.0.0.10", Port: 6514, Protocol: "tcp"},
+				},
+			}
+			So(sess.SetSyslog(ctx, want), ShouldBeNil)
+			cfg, err := sess.GetSyslog(ctx)
+			So(err, ShouldBeNil)
+			So(cfg, ShouldResemble, want)
+			So(m.Get("cimc/log/server 2", "server-port"), ShouldEqual, "6514")
+
+			Convey("and clears the rest", func() {
+				want.Servers = want.Servers[1:]
+				So(sess.SetSyslog(ctx, want), ShouldBeNil)
+				cfg, err := sess.GetSyslog(ctx)
+				So(err, ShouldBeNil)
+				So(cfg, ShouldResemble, want)
+				So(m.Get("cimc/log/server 2", "server-ip"), ShouldEqual, "0.0.0.0")
+			})
+		})
+
+		Convey("SetSyslog() refuses more servers than slots", func() {
+			cfg := cimc.SyslogConfig{Severity: "debug"}
+			for i := 0; i < 4; i++ {
+				cfg.Servers = append(cfg.Servers, cimc.SyslogServer{Address: "10.0.0.1"})
+			}
+			So(sess.SetSyslog(ctx, cfg), ShouldNotBeNil)
+		})
+
+		Convey("VerifySyslog() receives the test message and frees its slot", func() {
+			So(sess.VerifySyslog(ctx), ShouldBeNil)
+			cfg, err := sess.GetSyslog(ctx)
+			So(err, ShouldBeNil)
+			So(cfg.Servers, ShouldBeEmpty)
+		})
+
+		Convey("VerifySyslog() needs a free slot", func() {
+			cfg := cimc.SyslogConfig{Severity: "debug"}
+			for i := 0; i < 3; i++ {
+				cfg.Servers = append(cfg.Servers, cimc.SyslogServer{Address: "10.0.0.1"})
+			}
+			So(sess.SetSyslog(ctx, cfg), ShouldBeNil)
+			So(sess.VerifySyslog(ctx), ShouldNotBeNil)
+		})
+	})
+}
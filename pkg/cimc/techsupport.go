@@ -0,0 +1,88 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CollectTechSupport - have the cimc collect a tech-support bundle and
+// upload it to the directory dest on this host, over http from a server
+// started for it.  Polls the cimc until the bundle has arrived, or ctx is
+// done.  Return the path of the archive and its size.
+func (cs *Session) CollectTechSupport(ctx context.Context, dest string) (string, int64, error) {
+	if st, err := os.Stat(dest); err != nil || !st.IsDir() {
+		return "", 0, fmt.Errorf("'%s' is not a directory", dest)
+	}
+	host, _, err := net.SplitHostPort(cs.addr)
+	if err != nil {
+		host = cs.addr
+	}
+	name := fmt.Sprintf("techsupport-%s-%s.tar.gz", host, time.Now().UTC().Format("20060102T150405Z"))
+	ts, err := cs.startTransfer(filepath.Join(dest, name))
+	if err != nil {
+		return "", 0, err
+	}
+	defer ts.Close()
+
+	if err := commitCmds(ctx, cs, "/cimc/tech-support", []string{
+		"set remote-protocol http",
+		"set remote-ip " + ts.Host(),
+		"set remote-port " + strconv.Itoa(ts.Port()),
+		"set remote-path " + ts.Path(),
+	}); err != nil {
+		return "", 0, err
+	}
+	if _, err := cs.SendCmd(ctx, "/cimc/tech-support/start"); err != nil {
+		return "", 0, fmt.Errorf("failed to start tech-support collection: %v", err)
+	}
+
+	for {
+		pct, status, err := techSupportStatus(ctx, cs)
+		if err != nil {
+			return "", 0, err
+		}
+		switch {
+		case strings.HasPrefix(status, "COMPLETED"):
+			u, err := ts.wait(ctx)
+			if err != nil {
+				return "", 0, fmt.Errorf("tech-support bundle did not arrive: %v", err)
+			}
+			return u.path, u.size, nil
+		case strings.Contains(status, "FAIL") || strings.Contains(status, "ERROR"):
+			return "", 0, fmt.Errorf("tech-support collection failed at %d%%: %s", pct, status)
+		}
+		select {
+		case <-ctx.Done():
+			return "", 0, fmt.Errorf("gave up on tech-support collection at %d%% (%s): %v", pct, status, ctx.Err())
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// techSupportStatus - return the progress and status of the collection.
+// Expected input looks like this:
+// Tech Support:
+//    Server Address: 10.0.0.2
+//    Path: /techsupport.tar.gz
+//    Protocol: http
+//    Progress (%): 45
+//    Status: COLLECTING
+func techSupportStatus(ctx context.Context, cs *Session) (int, string, error) {
+	resp, err := cs.SendCmd(ctx, "/cimc/tech-support/show detail")
+	if err != nil {
+		return 0, "", err
+	}
+	dets := parseDetail(resp)
+	status, ok := dets["Status"]
+	if !ok {
+		return 0, "", fmt.Errorf("did not find tech-support 'Status' in %s", resp)
+	}
+	pct, _ := strconv.Atoi(dets["Progress (%)"])
+	return pct, strings.ToUpper(status), nil
+}
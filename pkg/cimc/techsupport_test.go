@@ -0,0 +1,56 @@
+package cimc_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCollectTechSupport(t *testing.T) {
+	Convey("Given a CIMC session and a directory", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		dir, err := ioutil.TempDir("", "techsupport")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		Convey("CollectTechSupport() fetches the bundle into it", func() {
+			path, size, err := sess.CollectTechSupport(ctx, dir)
+			So(err, ShouldBeNil)
+			So(filepath.Dir(path), ShouldEqual, dir)
+			So(filepath.Base(path), ShouldStartWith, "techsupport-127.0.0.1-")
+
+			st, err := os.Stat(path)
+			So(err, ShouldBeNil)
+			So(st.Size(), ShouldEqual, size)
+			So(m.Get("cimc/tech-support", "Status"), ShouldEqual, "COMPLETED")
+
+			f, err := os.Open(path)
+			So(err, ShouldBeNil)
+			defer f.Close()
+			gz, err := gzip.NewReader(f)
+			So(err, ShouldBeNil)
+			hdr, err := tar.NewReader(gz).Next()
+			So(err, ShouldBeNil)
+			So(hdr.Name, ShouldEqual, "CIMC/messages")
+		})
+
+		Convey("CollectTechSupport() needs an existing directory", func() {
+			_, _, err := sess.CollectTechSupport(ctx, filepath.Join(dir, "missing"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,159 @@
+package cimc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// transferServer - an http server on the address the cimc reaches us on,
+// for the one file the cimc uploads (PUT or POST) to, or downloads from,
+// this host.  So no external file server is needed.
+//   The file is only served at Path, which has a random token in it, so
+//   others on the network can not guess it.
+type transferServer struct {
+	ln       net.Listener
+	srv      *http.Server
+	file     string
+	path     string
+	received chan upload
+}
+
+// upload - a file the cimc uploaded, or why that failed.
+type upload struct {
+	path string
+	size int64
+	err  error
+}
+
+// startTransfer - serve file, a path on this host, over http on the local
+// address of the session.
+func (cs *Session) startTransfer(file string) (*transferServer, error) {
+	ip, err := cs.localIP()
+	if err != nil {
+		return nil, err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, err
+	}
+	ts := &transferServer{
+		ln:       ln,
+		file:     file,
+		path:     "/" + token + "/" + filepath.Base(file),
+		received: make(chan upload, 1),
+	}
+	ts.srv = &http.Server{Handler: http.HandlerFunc(ts.handle)}
+	go ts.srv.Serve(ln)
+	return ts, nil
+}
+
+// Host - the ip address the cimc should connect to.
+func (ts *transferServer) Host() string {
+	return ts.ln.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port - the port the cimc should connect to.
+func (ts *transferServer) Port() int {
+	return ts.ln.Addr().(*net.TCPAddr).Port
+}
+
+// Path - the path of the file on the server, for the cimc.
+func (ts *transferServer) Path() string {
+	return ts.path
+}
+
+func (ts *transferServer) Close() error {
+	return ts.srv.Close()
+}
+
+func (ts *transferServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ts.path {
+		http.NotFound(w, r)
+		return
+	}
+	name := ts.file
+	switch r.Method {
+	case http.MethodGet:
+		http.ServeFile(w, r, name)
+	case http.MethodPut, http.MethodPost:
+		size, err := ts.store(name, r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		select {
+		case ts.received <- upload{path: name, size: size, err: err}:
+		default:
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// store - write body to name, through a temporary file so that a partial
+// upload never looks like a good one.
+func (ts *transferServer) store(name string, body io.Reader) (int64, error) {
+	tmp := name + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("failed to store %s: %v", name, err)
+	}
+	return size, nil
+}
+
+// randomToken - a random hex string for a url path, that others can not
+// guess.
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// wait - return the upload, waiting for it until ctx is done, or for at
+// most 'timeout' if ctx has no deadline.
+func (ts *transferServer) wait(ctx context.Context) (upload, error) {
+	var expire <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		expire = time.After(timeout)
+	}
+	select {
+	case u := <-ts.received:
+		return u, u.err
+	case <-ctx.Done():
+		return upload{}, ctx.Err()
+	case <-expire:
+		return upload{}, fmt.Errorf("no upload after %s", timeout)
+	}
+}
+
+// localIP - the ip address of this host that the cimc connection uses.
+func (cs *Session) localIP() (net.IP, error) {
+	if cs.sshClient == nil {
+		return nil, fmt.Errorf("%s is not connected", cs.desc)
+	}
+	return cs.sshClient.LocalAddr().(*net.TCPAddr).IP, nil
+}
@@ -0,0 +1,279 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// UpdatePhase - the step a firmware update is in.
+type UpdatePhase string
+
+const (
+	UpdateDownload UpdatePhase = "download"
+	UpdateActivate UpdatePhase = "activate"
+	UpdateReboot   UpdatePhase = "reboot"
+	UpdateDone     UpdatePhase = "done"
+)
+
+// UpdateProgress - progress of a firmware update, as passed to an UpdateProgressFunc.
+type UpdateProgress struct {
+	Component FirmwareComponent
+	Phase     UpdatePhase
+	Percent   int
+	Status    string
+}
+
+func (p UpdateProgress) String() string {
+	return fmt.Sprintf("%s %s %d%% %s", p.Component, p.Phase, p.Percent, p.Status)
+}
+
+// UpdateProgressFunc - called by UpdateFirmware as the update goes on.
+type UpdateProgressFunc func(UpdateProgress)
+
+var updateProtocols = []string{"http", "https", "tftp", "ftp", "sftp", "scp"}
+
+var updateScopes = map[FirmwareComponent]string{
+	CIMCFirmware: "/cimc/firmware",
+	BIOSFirmware: "/bios",
+}
+
+// UpdateFirmware - update the cimc or bios firmware from source and activate it.
+//   source is a url of the image, for example http://10.0.0.5:8080/cimc-4.1.3b.bin
+//   Supported schemes are http, https, tftp, ftp, sftp and scp.
+//   progress, if not nil, is called as the update goes on.
+//
+//   Activating cimc firmware reboots the cimc, UpdateFirmware waits for it to
+//   come back and reconnects the session.  Activated bios firmware is used on
+//   the next boot of the host.
+func (cs *Session) UpdateFirmware(ctx context.Context, component FirmwareComponent, source string, progress UpdateProgressFunc) error {
+	scope, ok := updateScopes[component]
+	if !ok {
+		return fmt.Errorf("firmware update of '%s' is not supported", component)
+	}
+
+	u, err := parseUpdateSource(source)
+	if err != nil {
+		return err
+	}
+	cmd, pass := updateCmd(u)
+
+	report := func(phase UpdatePhase, percent int, status string) {
+		if progress != nil {
+			progress(UpdateProgress{Component: component, Phase: phase, Percent: percent, Status: status})
+		}
+	}
+
+	report(UpdateDownload, 0, "starting update from "+u.Redacted())
+	if pass == "" {
+		_, err = cs.SendCmd(ctx, scope+"/"+cmd)
+	} else if err = cs.enterScope(ctx, strings.Trim(scope, "/")); err == nil {
+		// the password goes at the prompt, never on the command line.
+		err = cs.sendSecret(ctx, cmd, pass)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start %s firmware update: %v", component, err)
+	}
+
+	// 'show' says NONE both before an update starts and after it is done,
+	// so NONE only counts once the update was seen running.
+	started := false
+	for {
+		resp, err := cs.SendCmd(ctx, scope+"/show detail")
+		if err != nil {
+			return err
+		}
+		st, err := parseUpdateStatus(component, resp)
+		if err != nil {
+			return err
+		}
+		report(UpdateDownload, st.percent, st.status)
+		if st.failed {
+			return fmt.Errorf("%s firmware update failed: %s", component, st.status)
+		}
+		started = started || st.running
+		if st.done || (started && st.idle) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s firmware update did not finish: %v (last status: %s)", component, ctx.Err(), st.status)
+		case <-time.After(PollInterval):
+		}
+	}
+
+	report(UpdateActivate, 0, "activating")
+	if component == BIOSFirmware {
+		if _, err := cs.SendCmd(ctx, scope+"/activate"); err != nil {
+			return fmt.Errorf("failed to activate bios firmware: %v", err)
+		}
+		report(UpdateDone, 100, "activated, used on next host boot")
+		return nil
+	}
+
+	// the update replaced the backup image, which activate makes the
+	// running one.
+	resp, err := cs.SendCmd(ctx, scope+"/show detail")
+	if err != nil {
+		return err
+	}
+	want := imageVersions(parseDetail(resp)).Backup
+
+	if cs.sshClient == nil {
+		return fmt.Errorf("%s is not connected", cs.desc)
+	}
+	dropped := make(chan struct{})
+	go func(clt *ssh.Client) {
+		clt.Wait()
+		close(dropped)
+	}(cs.sshClient)
+
+	// the cimc reboots on activate, so the session may well drop before we
+	// get a prompt back.  An error only counts if the cimc said so.
+	if _, err := cs.SendCmd(ctx, scope+"/activate"); err != nil && isCIMCError(err) {
+		return fmt.Errorf("failed to activate cimc firmware: %v", err)
+	}
+
+	report(UpdateReboot, 0, "waiting for cimc to reboot")
+	err = resetPhase(ctx, "firmware activation", ResetDown, func(ctx context.Context) error {
+		select {
+		case <-dropped:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := cs.reconnect(ctx); err != nil {
+		return fmt.Errorf("cimc did not come back after firmware activation: %v", err)
+	}
+
+	resp, err = cs.SendCmd(ctx, scope+"/show detail")
+	if err != nil {
+		return err
+	}
+	running := imageVersions(parseDetail(resp)).Running
+	if running != want {
+		return fmt.Errorf("cimc came back running firmware %s, not %s", running, want)
+	}
+	report(UpdateDone, 100, "running "+running)
+	return nil
+}
+
+// parseUpdateSource - parse and check the url of a firmware image.  Errors
+// show it without its password, or not at all if it does not parse.
+func parseUpdateSource(source string) (*url.URL, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		// a url.Error has all of source in it.
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("bad firmware source: %v", err)
+	}
+
+	ok := false
+	for _, p := range updateProtocols {
+		if u.Scheme == p {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("bad firmware source '%s': protocol must be one of %s",
+			u.Redacted(), strings.Join(updateProtocols, ", "))
+	}
+	if u.Host == "" || u.Path == "" {
+		return nil, fmt.Errorf("bad firmware source '%s': need a server and a path", u.Redacted())
+	}
+	return u, nil
+}
+
+// updateCmd - turn a source url into 'update <protocol> <server> <path> [user]'
+// and the password of user, which the cimc prompts for.
+func updateCmd(u *url.URL) (string, string) {
+	args := []string{"update", u.Scheme, u.Host, u.Path}
+	pass := ""
+	if u.User != nil {
+		args = append(args, u.User.Username())
+		pass, _ = u.User.Password()
+	}
+	return strings.Join(args, " "), pass
+}
+
+type updateStatus struct {
+	percent int
+	status  string
+	done    bool
+	failed  bool
+	// running is an update in progress, idle is NONE at 100%, which is
+	// either done or not started yet.
+	running bool
+	idle    bool
+}
+
+// parseUpdateStatus - parse update progress out of 'show detail'
+//   cimc:
+//     Update Stage: DOWNLOAD
+//     Update Progress: 50
+//     Update Status: Download in progress
+//   bios:
+//     FW Update/Recovery Status: Downloading, OK
+//     FW Update/Recovery Progress: 50
+func parseUpdateStatus(component FirmwareComponent, resp string) (updateStatus, error) {
+	st := updateStatus{}
+	dets := parseDetail(resp)
+
+	stageKey, progressKey := "Update Stage", "Update Progress"
+	if component == BIOSFirmware {
+		stageKey, progressKey = "FW Update/Recovery Status", "FW Update/Recovery Progress"
+	}
+
+	stage, ok := dets[stageKey]
+	if !ok {
+		return st, fmt.Errorf("did not find '%s' in %s", stageKey, resp)
+	}
+	if val, ok := dets[progressKey]; ok {
+		pct, err := strconv.Atoi(strings.TrimSuffix(val, "%"))
+		if err != nil {
+			return st, fmt.Errorf("Failed to parse '%s' setting: '%s'", progressKey, val)
+		}
+		st.percent = pct
+	}
+
+	if component == BIOSFirmware {
+		// "<stage>, <status>"
+		toks := strings.SplitN(stage, ",", 2)
+		stage = strings.TrimSpace(toks[0])
+		st.status = stage
+		if len(toks) == 2 {
+			st.status = stage + ", " + strings.TrimSpace(toks[1])
+			st.failed = strings.TrimSpace(toks[1]) != "OK"
+		}
+	} else {
+		st.status = stage
+		if status := dets["Update Status"]; status != "" {
+			st.status = stage + ", " + status
+		}
+	}
+
+	switch strings.ToUpper(stage) {
+	case "ERROR":
+		st.failed = true
+	case "DONE":
+		st.done = true
+	case "NONE":
+		st.idle = st.percent == 100
+	default:
+		st.running = true
+	}
+	return st, nil
+}
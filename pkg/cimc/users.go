@@ -0,0 +1,188 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UserRole - the privilege of a cimc user account.
+type UserRole string
+
+const (
+	RoleAdmin    UserRole = "admin"
+	RoleUser     UserRole = "user"
+	RoleReadOnly UserRole = "read-only"
+)
+
+// User - a local cimc user account.  ID is the account slot (1 to 15).
+type User struct {
+	ID      int
+	Name    string
+	Role    UserRole
+	Enabled bool
+}
+
+// Users - return the configured local user accounts.
+func (cs *Session) Users(ctx context.Context) ([]User, error) {
+	users, err := userSlots(ctx, cs)
+	if err != nil {
+		return users, err
+	}
+	ret := []User{}
+	for _, u := range users {
+		if u.Name != "" {
+			ret = append(ret, u)
+		}
+	}
+	return ret, nil
+}
+
+// CreateUser - create an enabled user account in the first free slot.
+func (cs *Session) CreateUser(ctx context.Context, name string, role UserRole, password string) (User, error) {
+	users, err := userSlots(ctx, cs)
+	if err != nil {
+		return User{}, err
+	}
+	free := User{}
+	for _, u := range users {
+		if u.Name == name {
+			return u, fmt.Errorf("user '%s' already exists", name)
+		}
+		if u.Name == "" && free.ID == 0 {
+			free = u
+		}
+	}
+	if free.ID == 0 {
+		return free, fmt.Errorf("no free user slot for '%s'", name)
+	}
+
+	u := User{ID: free.ID, Name: name, Role: role, Enabled: true}
+	err = changeUser(ctx, cs, u.ID, password, []string{
+		"set name " + quoteValue(name),
+		"set role " + string(role),
+		"set enabled yes",
+	})
+	return u, err
+}
+
+// EnableUser - enable the user account 'name'.
+func (cs *Session) EnableUser(ctx context.Context, name string) error {
+	return updateUser(ctx, cs, name, "", "set enabled yes")
+}
+
+// DisableUser - disable the user account 'name'.
+func (cs *Session) DisableUser(ctx context.Context, name string) error {
+	return updateUser(ctx, cs, name, "", "set enabled no")
+}
+
+// SetUserRole - change the role of the user account 'name'.
+func (cs *Session) SetUserRole(ctx context.Context, name string, role UserRole) error {
+	return updateUser(ctx, cs, name, "", "set role "+string(role))
+}
+
+// SetUserPassword - change the password of the user account 'name'.
+//   If that is the account of this session, the session reconnects with
+//   the new password from now on.
+func (cs *Session) SetUserPassword(ctx context.Context, name, password string) error {
+	if password == "" {
+		return fmt.Errorf("empty password for user '%s'", name)
+	}
+	if err := updateUser(ctx, cs, name, password); err != nil {
+		return err
+	}
+	if name == cs.user {
+		cs.pass = password
+	}
+	return nil
+}
+
+// DeleteUser - delete the user account 'name', freeing its slot.
+func (cs *Session) DeleteUser(ctx context.Context, name string) error {
+	if name == cs.user {
+		return fmt.Errorf("refusing to delete '%s', the account of this session", name)
+	}
+	u, err := findUser(ctx, cs, name)
+	if err != nil {
+		return err
+	}
+	if err := cs.enterScope(ctx, "user "+strconv.Itoa(u.ID)); err != nil {
+		return err
+	}
+	if _, err := cs.SendCmd(ctx, "clear"); err != nil {
+		return fmt.Errorf("failed to delete user '%s': %v", name, err)
+	}
+	return nil
+}
+
+// userSlots - return all user slots, including unused ones with an empty name.
+// Expected input looks like this:
+// User 1:
+//    Name: admin
+//    Role: admin
+//    Enabled: yes
+func userSlots(ctx context.Context, cs *Session) ([]User, error) {
+	users := []User{}
+	resp, err := cs.SendCmd(ctx, "/show user detail")
+	if err != nil {
+		return users, err
+	}
+	for _, b := range parseDetailList(resp) {
+		id, err := strconv.Atoi(strings.TrimPrefix(b.Title, "User "))
+		if err != nil {
+			return users, fmt.Errorf("bad user slot '%s'", b.Title)
+		}
+		enabled, err := parseBool(b.Props["Enabled"])
+		if err != nil {
+			return users, fmt.Errorf("Failed to parse 'Enabled' setting of user %d: %v", id, err)
+		}
+		users = append(users, User{ID: id, Name: b.Props["Name"], Role: UserRole(b.Props["Role"]), Enabled: enabled})
+	}
+	return users, nil
+}
+
+func findUser(ctx context.Context, cs *Session, name string) (User, error) {
+	users, err := userSlots(ctx, cs)
+	if err != nil {
+		return User{}, err
+	}
+	for _, u := range users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+	return User{}, fmt.Errorf("no such user '%s'", name)
+}
+
+func updateUser(ctx context.Context, cs *Session, name, password string, cmds ...string) error {
+	u, err := findUser(ctx, cs, name)
+	if err != nil {
+		return err
+	}
+	return changeUser(ctx, cs, u.ID, password, cmds)
+}
+
+// changeUser - send cmds, and the password if not empty, to user slot id
+// and commit them.
+func changeUser(ctx context.Context, cs *Session, id int, password string, cmds []string) error {
+	if err := cs.enterScope(ctx, "user "+strconv.Itoa(id)); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if _, err := cs.SendCmd(ctx, cmd); err != nil {
+			cs.SendCmd(ctx, "discard")
+			return fmt.Errorf("failed to %s for user %d: %v", cmd, id, err)
+		}
+	}
+	if password != "" {
+		if err := cs.setSecret(ctx, "password", password); err != nil {
+			cs.SendCmd(ctx, "discard")
+			return fmt.Errorf("failed to set password for user %d: %v", id, err)
+		}
+	}
+	if _, err := cs.SendCmd(ctx, "commit"); err != nil {
+		return fmt.Errorf("failed to commit user %d: %v", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package cimc_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	goexpect "github.com/google/goexpect"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUsers(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+		addr := fmt.Sprintf("127.0.0.1:%d", m.Port)
+
+		login := func(user, pass string) error {
+			s, err := cimc.NewSession(addr, user, pass)
+			if err == nil {
+				s.Close(ctx)
+			}
+			return err
+		}
+
+		Convey("Users() lists the configured accounts", func() {
+			users, err := sess.Users(ctx)
+			So(err, ShouldBeNil)
+			So(users, ShouldResemble, []cimc.User{
+				{ID: 1, Name: "admin", Role: cimc.RoleAdmin, Enabled: true},
+				{ID: 2, Name: "test", Role: cimc.RoleAdmin, Enabled: true},
+			})
+		})
+
+		Convey("CreateUser() adds an account that can log in", func() {
+			u, err := sess.CreateUser(ctx, "alice", cimc.RoleUser, "Wonder1and")
+			So(err, ShouldBeNil)
+			So(u, ShouldResemble, cimc.User{ID: 3, Name: "alice", Role: cimc.RoleUser, Enabled: true})
+			So(login("alice", "Wonder1and"), ShouldBeNil)
+
+			_, err = sess.CreateUser(ctx, "alice", cimc.RoleUser, "Wonder1and")
+			So(err, ShouldNotBeNil)
+
+			Convey("DisableUser() and EnableUser() toggle logins", func() {
+				So(sess.DisableUser(ctx, "alice"), ShouldBeNil)
+				So(login("alice", "Wonder1and"), ShouldNotBeNil)
+				So(sess.EnableUser(ctx, "alice"), ShouldBeNil)
+				So(login("alice", "Wonder1and"), ShouldBeNil)
+			})
+
+			Convey("SetUserRole() changes the role", func() {
+				So(sess.SetUserRole(ctx, "alice", cimc.RoleReadOnly), ShouldBeNil)
+				So(m.Get("user 3", "role"), ShouldEqual, "read-only")
+			})
+
+			Convey("SetUserPassword() changes the password", func() {
+				So(sess.SetUserPassword(ctx, "alice", "Looking-Glass"), ShouldBeNil)
+				So(login("alice", "Wonder1and"), ShouldNotBeNil)
+				So(login("alice", "Looking-Glass"), ShouldBeNil)
+			})
+
+			Convey("DeleteUser() frees the slot", func() {
+				So(sess.DeleteUser(ctx, "alice"), ShouldBeNil)
+				users, err := sess.Users(ctx)
+				So(err, ShouldBeNil)
+				So(len(users), ShouldEqual, 2)
+				So(login("alice", "Wonder1and"), ShouldNotBeNil)
+			})
+		})
+
+		Convey("CreateUser() with a weak password creates nothing", func() {
+			_, err := sess.CreateUser(ctx, "bob", cimc.RoleUser, "short")
+			So(err, ShouldNotBeNil)
+			users, err := sess.Users(ctx)
+			So(err, ShouldBeNil)
+			So(len(users), ShouldEqual, 2)
+		})
+
+		Convey("DeleteUser() refuses the session's own account", func() {
+			So(sess.DeleteUser(ctx, "test"), ShouldNotBeNil)
+		})
+
+		Convey("SetUserPassword() of the session's account is used to reconnect", func() {
+			So(sess.SetUserPassword(ctx, "test", "New-Test-Pass"), ShouldBeNil)
+
+			// a vlan change drops the session, and we reconnect.
+			cfg, err := sess.GetNetwork(ctx)
+			So(err, ShouldBeNil)
+			cfg.VLANEnabled = true
+			So(sess.SetNetwork(ctx, cfg, true), ShouldBeNil)
+			m.Set("user 2", "password", "test123")
+		})
+	})
+}
+
+func TestPasswordsAreNotLogged(t *testing.T) {
+	Convey("Given a verbose CIMC session", t, func() {
+		ctx := context.TODO()
+		m, _, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+
+		log := &bytes.Buffer{}
+		sess, err := cimc.NewSessionOpts(fmt.Sprintf("127.0.0.1:%d", m.Port), "test", "test123",
+			[]goexpect.Option{goexpect.Verbose(true), goexpect.VerboseWriter(log)})
+		So(err, ShouldBeNil)
+		defer sess.Close(ctx)
+
+		_, err = sess.CreateUser(ctx, "carol", cimc.RoleAdmin, "Very-Secret-1")
+		So(err, ShouldBeNil)
+		So(sess.SetUserPassword(ctx, "carol", "Very-Secret-2"), ShouldBeNil)
+		So(log.String(), ShouldContainSubstring, "set password")
+		So(log.String(), ShouldNotContainSubstring, "Very-Secret")
+	})
+}
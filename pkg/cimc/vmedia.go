@@ -0,0 +1,212 @@
+package cimc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// VMediaType - how the cimc mounts a virtual media image.
+type VMediaType string
+
+const (
+	VMediaWWW  VMediaType = "www"
+	VMediaNFS  VMediaType = "nfs"
+	VMediaCIFS VMediaType = "cifs"
+)
+
+// VMediaMount - an image for MapVMedia.
+//   Share is where the image is, in the form the cimc takes for Type:
+//     www:  http://10.0.0.1/images/
+//     nfs:  10.0.0.1:/export/images
+//     cifs: //10.0.0.1/share/images
+//   Options are the cimc mount options, like "ro" or "vers=3".
+//   Username and Password are optional, the password is not logged.
+type VMediaMount struct {
+	Volume   string
+	Type     VMediaType
+	Share    string
+	File     string
+	Options  string
+	Username string
+	Password string
+}
+
+// VMediaMapping - a virtual media mapping of the cimc.
+//   Status is OK, In Progress, Stale or Error.  Error says why, if the
+//   cimc knows.
+type VMediaMapping struct {
+	Volume  string
+	Type    VMediaType
+	Share   string
+	File    string
+	Options string
+	Drive   string
+	Status  string
+	Error   string
+}
+
+// ParseVMediaURL - return the VMediaMount for the image at rawurl.
+//   http and https urls are mounted with www, nfs://host/export/file.iso
+//   with nfs and cifs://host/share/file.iso (or smb://) with cifs.
+//   Credentials in the url are used for the mount.
+func ParseVMediaURL(volume, rawurl string) (VMediaMount, error) {
+	m := VMediaMount{Volume: volume}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return m, err
+	}
+	dir, file := path.Split(u.Path)
+	if u.Host == "" || file == "" {
+		return m, fmt.Errorf("'%s' does not name an image on a host", rawurl)
+	}
+	if u.User != nil {
+		m.Username = u.User.Username()
+		m.Password, _ = u.User.Password()
+	}
+	m.File = file
+
+	switch u.Scheme {
+	case "http", "https":
+		m.Type = VMediaWWW
+		m.Share = u.Scheme + "://" + u.Host + dir
+	case "nfs":
+		m.Type = VMediaNFS
+		m.Share = u.Host + ":" + strings.TrimSuffix(dir, "/")
+	case "cifs", "smb":
+		m.Type = VMediaCIFS
+		m.Share = "//" + u.Host + strings.TrimSuffix(dir, "/")
+	default:
+		return m, fmt.Errorf("unsupported virtual media url scheme '%s'", u.Scheme)
+	}
+	return m, nil
+}
+
+// VMediaMappings - return the virtual media mappings.
+func (cs *Session) VMediaMappings(ctx context.Context) ([]VMediaMapping, error) {
+	return vmediaMappings(ctx, cs)
+}
+
+// VMediaMapping - return the mapping of volume, to check its status.
+func (cs *Session) VMediaMapping(ctx context.Context, volume string) (VMediaMapping, error) {
+	mappings, err := vmediaMappings(ctx, cs)
+	if err != nil {
+		return VMediaMapping{}, err
+	}
+	for _, m := range mappings {
+		if m.Volume == volume {
+			return m, nil
+		}
+	}
+	return VMediaMapping{}, fmt.Errorf("no virtual media mapping '%s'", volume)
+}
+
+// MapVMedia - map the image of m, and wait until the cimc has mounted it,
+// or ctx is done.  Return the mapping.  If the cimc could not mount the
+// image, the mapping is left for inspection, and an error returned.
+func (cs *Session) MapVMedia(ctx context.Context, m VMediaMount) (VMediaMapping, error) {
+	if m.Volume == "" || strings.ContainsAny(m.Volume, " \t") {
+		return VMediaMapping{}, fmt.Errorf("bad virtual media volume name '%s'", m.Volume)
+	}
+	switch m.Type {
+	case VMediaWWW, VMediaNFS, VMediaCIFS:
+	default:
+		return VMediaMapping{}, fmt.Errorf("unsupported virtual media type '%s'", m.Type)
+	}
+
+	cmd := fmt.Sprintf("map-%s %s %s %s", m.Type, m.Volume, quoteValue(m.Share), quoteValue(m.File))
+	if m.Options != "" {
+		cmd += " --mountOptions " + quoteValue(m.Options)
+	}
+	if m.Username != "" {
+		cmd += " --username " + quoteValue(m.Username)
+	}
+	if m.Password == "" {
+		if _, err := cs.SendCmd(ctx, "/vmedia/"+cmd); err != nil {
+			return VMediaMapping{}, fmt.Errorf("failed to map %s: %v", m.Volume, err)
+		}
+	} else {
+		if err := cs.enterScope(ctx, "vmedia"); err != nil {
+			return VMediaMapping{}, err
+		}
+		if err := cs.sendSecret(ctx, cmd+" --password", m.Password); err != nil {
+			return VMediaMapping{}, fmt.Errorf("failed to map %s: %v", m.Volume, err)
+		}
+	}
+
+	for {
+		mapping, err := cs.VMediaMapping(ctx, m.Volume)
+		if err != nil {
+			return mapping, err
+		}
+		switch mapping.Status {
+		case "OK":
+			return mapping, nil
+		case "In Progress":
+		default:
+			return mapping, fmt.Errorf("failed to mount %s: %s %s", m.Volume, mapping.Status, mapping.Error)
+		}
+		select {
+		case <-ctx.Done():
+			return mapping, fmt.Errorf("gave up waiting for %s to mount: %v", m.Volume, ctx.Err())
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// UnmapVMedia - remove the mapping of volume.
+func (cs *Session) UnmapVMedia(ctx context.Context, volume string) error {
+	if _, err := cs.SendCmd(ctx, "/vmedia/unmap "+volume); err != nil {
+		return fmt.Errorf("failed to unmap %s: %v", volume, err)
+	}
+	return nil
+}
+
+// SetOneTimeBoot - boot the host from device on its next boot only, for
+// example "CIMC-Mapped-vDVD" for the image mapped with MapVMedia.  The host
+// is not rebooted.
+func (cs *Session) SetOneTimeBoot(ctx context.Context, device string) error {
+	if _, err := cs.SendCmd(ctx, "/bios/set one-time-boot-device "+quoteValue(device)); err != nil {
+		cs.SendCmd(ctx, "discard")
+		return fmt.Errorf("failed to set one time boot device %s: %v", device, err)
+	}
+	// like SetBIOSTokens, decline the cimc's offer to reboot now.
+	if _, err := cs.sendCmd(ctx, "commit", "n"); err != nil {
+		return fmt.Errorf("failed to commit one time boot device: %v", err)
+	}
+	return nil
+}
+
+// vmediaMappings - parse the virtual media mappings.
+// Expected input looks like this:
+// Volume ubuntu:
+//    Map-status: OK
+//    Drive-type: CD
+//    Remote-share: http://10.0.0.1/images/
+//    Remote-file: ubuntu.iso
+//    Mount-type: www
+//    Mount-options: noauto
+//    Map-error:
+func vmediaMappings(ctx context.Context, cs *Session) ([]VMediaMapping, error) {
+	mappings := []VMediaMapping{}
+	resp, err := cs.SendCmd(ctx, "/vmedia/show mappings detail")
+	if err != nil {
+		return mappings, err
+	}
+	for _, b := range parseDetailList(resp) {
+		mappings = append(mappings, VMediaMapping{
+			Volume:  strings.TrimPrefix(b.Title, "Volume "),
+			Type:    VMediaType(b.Props["Mount-type"]),
+			Share:   b.Props["Remote-share"],
+			File:    b.Props["Remote-file"],
+			Options: b.Props["Mount-options"],
+			Drive:   b.Props["Drive-type"],
+			Status:  b.Props["Map-status"],
+			Error:   b.Props["Map-error"],
+		})
+	}
+	return mappings, nil
+}
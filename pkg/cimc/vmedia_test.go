@@ -0,0 +1,116 @@
+package cimc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseVMediaURL(t *testing.T) {
+	Convey("ParseVMediaURL() splits urls the way the cimc takes them", t, func() {
+		m, err := cimc.ParseVMediaURL("os", "http://u:p@10.0.0.1:8080/images/ubuntu.iso")
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, cimc.VMediaMount{Volume: "os", Type: cimc.VMediaWWW,
+			Share: "http://10.0.0.1:8080/images/", File: "ubuntu.iso", Username: "u", Password: "p"})
+
+		m, err = cimc.ParseVMediaURL("os", "nfs://10.0.0.1/export/images/ubuntu.iso")
+		So(err, ShouldBeNil)
+		So(m.Type, ShouldEqual, cimc.VMediaNFS)
+		So(m.Share, ShouldEqual, "10.0.0.1:/export/images")
+
+		m, err = cimc.ParseVMediaURL("os", "smb://10.0.0.1/share/ubuntu.iso")
+		So(err, ShouldBeNil)
+		So(m.Type, ShouldEqual, cimc.VMediaCIFS)
+		So(m.Share, ShouldEqual, "//10.0.0.1/share")
+
+		_, err = cimc.ParseVMediaURL("os", "ftp://10.0.0.1/ubuntu.iso")
+		So(err, ShouldNotBeNil)
+		_, err = cimc.ParseVMediaURL("os", "http://10.0.0.1/images/")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestVMedia(t *testing.T) {
+	Convey("Given a CIMC session and an image server", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/images/ubuntu.iso":
+			case "/private/rhel.iso":
+				if u, p, ok := r.BasicAuth(); !ok || u != "builder" || p != "Image-Secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer srv.Close()
+
+		Convey("there are no mappings to start with", func() {
+			mappings, err := sess.VMediaMappings(ctx)
+			So(err, ShouldBeNil)
+			So(mappings, ShouldBeEmpty)
+		})
+
+		Convey("MapVMedia() mounts an image over http", func() {
+			mount, err := cimc.ParseVMediaURL("ubuntu", srv.URL+"/images/ubuntu.iso")
+			So(err, ShouldBeNil)
+			mapping, err := sess.MapVMedia(ctx, mount)
+			So(err, ShouldBeNil)
+			So(mapping, ShouldResemble, cimc.VMediaMapping{
+				Volume: "ubuntu", Type: cimc.VMediaWWW, Share: srv.URL + "/images/", File: "ubuntu.iso",
+				Drive: "CD", Status: "OK",
+			})
+
+			_, err = sess.MapVMedia(ctx, mount)
+			So(err, ShouldNotBeNil)
+
+			Convey("UnmapVMedia() removes it", func() {
+				So(sess.UnmapVMedia(ctx, "ubuntu"), ShouldBeNil)
+				_, err := sess.VMediaMapping(ctx, "ubuntu")
+				So(err, ShouldNotBeNil)
+				So(sess.UnmapVMedia(ctx, "ubuntu"), ShouldNotBeNil)
+			})
+
+			Convey("SetOneTimeBoot() boots from it next", func() {
+				So(sess.SetOneTimeBoot(ctx, "CIMC-Mapped-vDVD"), ShouldBeNil)
+				So(m.Get("bios", "one-time-boot-device"), ShouldEqual, "CIMC-Mapped-vDVD")
+				So(sess.SetOneTimeBoot(ctx, "floppy"), ShouldNotBeNil)
+			})
+		})
+
+		Convey("MapVMedia() uses credentials", func() {
+			mount, err := cimc.ParseVMediaURL("rhel", srv.URL+"/private/rhel.iso")
+			So(err, ShouldBeNil)
+			_, err = sess.MapVMedia(ctx, mount)
+			So(err, ShouldNotBeNil)
+			So(sess.UnmapVMedia(ctx, "rhel"), ShouldBeNil)
+
+			mount.Username, mount.Password = "builder", "Image-Secret"
+			mapping, err := sess.MapVMedia(ctx, mount)
+			So(err, ShouldBeNil)
+			So(mapping.Status, ShouldEqual, "OK")
+		})
+
+		Convey("MapVMedia() reports mount errors", func() {
+			mount, err := cimc.ParseVMediaURL("missing", srv.URL+"/images/missing.iso")
+			So(err, ShouldBeNil)
+			mapping, err := sess.MapVMedia(ctx, mount)
+			So(err, ShouldNotBeNil)
+			So(mapping.Status, ShouldEqual, "Error")
+			So(mapping.Error, ShouldContainSubstring, "404")
+		})
+	})
+}
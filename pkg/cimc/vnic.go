@@ -0,0 +1,155 @@
+package cimc
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// VNIC - a host ethernet interface (vNIC) of a Cisco VIC adapter.
+//   MAC empty on create lets the adapter assign one.  VLAN 0 is no vlan,
+//   VLANMode is TRUNK or ACCESS.  Changes are used from the next boot of
+//   the host.
+type VNIC struct {
+	Name       string
+	MAC        string `cimc:"mac-addr,MAC Address"`
+	MTU        int    `cimc:"mtu,MTU"`
+	UplinkPort int    `cimc:"uplink,Uplink Port"`
+	VLANMode   string `cimc:"vlan-mode,VLAN Mode"`
+	VLAN       int
+	PXEBoot    bool
+}
+
+// VNICs - return the vNICs of the adapter in slot, like "MLOM" or "1".
+// Expected input looks like this:
+// Name eth0:
+//    MTU: 1500
+//    Uplink Port: 0
+//    MAC Address: 70:0F:6A:D4:3B:20
+//    VLAN: NONE
+//    VLAN Mode: TRUNK
+//    PXE Boot: disabled
+func (cs *Session) VNICs(ctx context.Context, slot string) ([]VNIC, error) {
+	vnics := []VNIC{}
+	if err := cs.enterScope(ctx, "chassis", "adapter "+slot); err != nil {
+		return vnics, fmt.Errorf("no adapter in slot '%s': %v", slot, err)
+	}
+	resp, err := cs.SendCmd(ctx, "show host-eth-if detail")
+	if err != nil {
+		return vnics, err
+	}
+	for _, b := range parseDetailList(resp) {
+		v := VNIC{Name: strings.TrimPrefix(b.Title, "Name ")}
+		if err := decodeDetail(b.Props, &v); err != nil {
+			return vnics, fmt.Errorf("%s: %v", b.Title, err)
+		}
+		if vlan := b.Props["VLAN"]; vlan != "NONE" {
+			if v.VLAN, err = strconv.Atoi(vlan); err != nil {
+				return vnics, fmt.Errorf("%s: bad VLAN '%s'", b.Title, vlan)
+			}
+		}
+		v.PXEBoot = b.Props["PXE Boot"] == "enabled"
+		vnics = append(vnics, v)
+	}
+	return vnics, nil
+}
+
+// SetVNIC - change the vNIC v.Name of the adapter in slot to v, or create
+// it if there is none.  Return the vNIC as the adapter has it.
+//   To change a vNIC, change one returned by VNICs, all fields are set.
+func (cs *Session) SetVNIC(ctx context.Context, slot string, v VNIC) (VNIC, error) {
+	if v.Name == "" || strings.ContainsAny(v.Name, " \t") {
+		return VNIC{}, fmt.Errorf("bad vNIC name '%s'", v.Name)
+	}
+	if v.VLAN < 0 || v.VLAN > 4094 {
+		return VNIC{}, fmt.Errorf("bad VLAN %d for vNIC %s", v.VLAN, v.Name)
+	}
+	vnics, err := cs.VNICs(ctx, slot)
+	if err != nil {
+		return VNIC{}, err
+	}
+	cur, found := VNIC{}, false
+	for _, c := range vnics {
+		if c.Name == v.Name {
+			cur, found = c, true
+			break
+		}
+	}
+
+	if v.MAC == "" {
+		v.MAC = cur.MAC
+	}
+	cmds := setChanges(cur, v)
+	if !found || cur.VLAN != v.VLAN {
+		vlan := "NONE"
+		if v.VLAN != 0 {
+			vlan = strconv.Itoa(v.VLAN)
+		}
+		cmds = append(cmds, "set vlan "+vlan)
+	}
+	if !found || cur.PXEBoot != v.PXEBoot {
+		boot := "disabled"
+		if v.PXEBoot {
+			boot = "enabled"
+		}
+		cmds = append(cmds, "set boot "+boot)
+	}
+
+	scopes := []string{"chassis", "adapter " + slot, "host-eth-if " + v.Name}
+	if found {
+		if err := commitIn(ctx, cs, scopes, cmds); err != nil {
+			return VNIC{}, err
+		}
+	} else {
+		// create enters the scope of the new vNIC, which exists once
+		// committed.
+		if err := cs.enterScope(ctx, scopes[:2]...); err != nil {
+			return VNIC{}, err
+		}
+		if _, err := cs.SendCmd(ctx, "create host-eth-if "+v.Name); err != nil {
+			return VNIC{}, fmt.Errorf("failed to create vNIC %s: %v", v.Name, err)
+		}
+		for _, cmd := range append(cmds, "commit") {
+			if _, err := cs.SendCmd(ctx, cmd); err != nil {
+				cs.SendCmd(ctx, "discard")
+				return VNIC{}, fmt.Errorf("failed to %s in %s: %v", cmd, strings.Join(scopes, "/"), err)
+			}
+		}
+	}
+
+	if vnics, err = cs.VNICs(ctx, slot); err != nil {
+		return VNIC{}, err
+	}
+	for _, c := range vnics {
+		if c.Name == v.Name {
+			return c, nil
+		}
+	}
+	return VNIC{}, fmt.Errorf("vNIC %s not found after commit", v.Name)
+}
+
+// ExportVNICMACs - write the vNICs of all adapters of cs to w as csv, with
+// the columns adapter, vnic, mac and pxe, for example to feed a dhcp server.
+func ExportVNICMACs(ctx context.Context, cs CIMCSession, w io.Writer) error {
+	resp, err := cs.SendCmd(ctx, "/chassis/show adapter detail")
+	if err != nil {
+		return err
+	}
+	out := csv.NewWriter(w)
+	out.Write([]string{"adapter", "vnic", "mac", "pxe"})
+	for _, b := range parseDetailList(resp) {
+		slot := strings.TrimPrefix(b.Title, "PCI Slot ")
+		vnics, err := cs.VNICs(ctx, slot)
+		if err != nil {
+			return err
+		}
+		for _, v := range vnics {
+			out.Write([]string{slot, v.Name, v.MAC, formatBool(v.PXEBoot)})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,73 @@
+package cimc_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anuvu/axepect/pkg/cimc"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVNICs(t *testing.T) {
+	Convey("Given a CIMC session", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		m, sess, err := newMock()
+		So(err, ShouldBeNil)
+		defer m.Close()
+		defer sess.Close(ctx)
+
+		Convey("VNICs() returns typed vNICs", func() {
+			vnics, err := sess.VNICs(ctx, "MLOM")
+			So(err, ShouldBeNil)
+			So(len(vnics), ShouldEqual, 2)
+			So(vnics[0], ShouldResemble, cimc.VNIC{
+				Name: "eth0", MAC: "70:0F:6A:D4:3B:20", MTU: 1500, VLANMode: "TRUNK",
+			})
+		})
+
+		Convey("VNICs() needs an adapter", func() {
+			_, err := sess.VNICs(ctx, "9")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("SetVNIC() changes a vNIC", func() {
+			vnics, err := sess.VNICs(ctx, "MLOM")
+			So(err, ShouldBeNil)
+			v := vnics[1]
+			v.MTU, v.VLAN, v.VLANMode, v.PXEBoot = 9000, 100, "ACCESS", true
+			got, err := sess.SetVNIC(ctx, "MLOM", v)
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, v)
+			So(m.Get("chassis/adapter MLOM/host-eth-if eth1", "vlan"), ShouldEqual, "100")
+
+			v.VLAN = 0
+			got, err = sess.SetVNIC(ctx, "MLOM", v)
+			So(err, ShouldBeNil)
+			So(got.VLAN, ShouldEqual, 0)
+
+			v.MTU = 100
+			_, err = sess.SetVNIC(ctx, "MLOM", v)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("SetVNIC() creates a vNIC with a new MAC", func() {
+			got, err := sess.SetVNIC(ctx, "MLOM", cimc.VNIC{Name: "pxe0", MTU: 1500, VLANMode: "TRUNK", PXEBoot: true})
+			So(err, ShouldBeNil)
+			So(got.MAC, ShouldEqual, "70:0F:6A:D4:3B:22")
+			So(got.PXEBoot, ShouldBeTrue)
+
+			Convey("ExportVNICMACs() lists it", func() {
+				var buf bytes.Buffer
+				So(cimc.ExportVNICMACs(ctx, sess, &buf), ShouldBeNil)
+				So(buf.String(), ShouldEqual, "adapter,vnic,mac,pxe\n"+
+					"MLOM,eth0,70:0F:6A:D4:3B:20,no\n"+
+					"MLOM,eth1,70:0F:6A:D4:3B:21,no\n"+
+					"MLOM,pxe0,70:0F:6A:D4:3B:22,yes\n")
+			})
+		})
+	})
+}
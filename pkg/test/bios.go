@@ -0,0 +1,48 @@
+package test
+
+var enabledDisabled = []string{"Enabled", "Disabled"}
+
+// token - a bios token, shown and set by the same name.
+func token(name, value string, values ...string) *Prop {
+	if len(values) == 0 {
+		values = enabledDisabled
+	}
+	return &Prop{Name: name, Label: name, Value: value, Values: values}
+}
+
+// addBiosTokens - the bios token scopes, and the reboot question bios
+// changes ask on commit.
+func addBiosTokens(s *Scope) {
+	s.Add(
+		NewScope("advanced", "BIOS Settings",
+			token("IntelHyperThread", "Enabled"),
+			token("IntelVT", "Enabled"),
+			token("IntelVTD", "Enabled"),
+			token("SrIov", "Enabled"),
+			token("CPUPerformance", "Enterprise", "Enterprise", "HPC", "High Throughput", "Custom"),
+			token("PackageCstateLimit", "C0 C1 State", "C0 C1 State", "C2", "C6 Non Retention", "C6 Retention", "Auto"),
+			token("ProcessorC1E", "Disabled"),
+			token("ProcessorC6Report", "Disabled"),
+			token("UsbLegacySupport", "Enabled"),
+		),
+		NewScope("main", "BIOS Main",
+			token("POSTErrorPause", "Disabled"),
+			token("TPMAdminCtrl", "Enabled"),
+		),
+		NewScope("server-management", "Server Management",
+			token("BaudRate", "115200", "9600", "19200", "38400", "57600", "115200"),
+			token("ConsoleRedir", "COM0", "Disabled", "COM0", "COM1"),
+			token("FRB2Enable", "Enabled"),
+			token("OSBootWatchdogTimer", "Disabled"),
+		),
+	)
+
+	s.OnCommit = func(t *Term, changed map[string]string) {
+		t.Printf("Changes to BIOS set-up parameters will require a reboot.\n")
+		if t.Ask("Do you want to reboot the system?") {
+			t.Mock.Root.Child("chassis").Set("Power", "on")
+			return
+		}
+		t.Printf("Changes will be applied on next reboot.\n")
+	}
+}
@@ -0,0 +1,195 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// csrFields - the questions of 'generate-csr'.
+var csrFields = []string{
+	"Common Name (CN)",
+	"Organization Name (O)",
+	"Organization Unit (OU)",
+	"Locality (L)",
+	"StateName (S)",
+	"Country Code (CC)",
+	"Email",
+}
+
+// startHTTPS - serve the cimc web interface, that is only its certificate,
+// and the redfish api on a free port.
+func (m *MockCIMC) startHTTPS() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "C220-WZP2326007Q", Organization: []string{"Cisco Self Signed"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	m.cert = tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return err
+	}
+	m.HTTPSPort = ln.Addr().(*net.TCPAddr).Port
+	m.https = ln
+
+	cfg := &tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		cert := m.cert
+		return &cert, nil
+	}}
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/redfish/", m.RedfishHandler())
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "Cisco Integrated Management Controller\n")
+		})
+		err := http.Serve(tls.NewListener(ln, cfg), mux)
+		if err != nil && !strings.Contains(err.Error(), "use of closed") {
+			log.Printf("https server on port %d failed: %v\n", m.HTTPSPort, err)
+		}
+	}()
+	return nil
+}
+
+func httpsScope(m *MockCIMC) *Scope {
+	s := NewScope("https", "HTTPS Settings",
+		prop("enabled", "HTTPS Enabled", "yes", "yes", "no"),
+		ro("HTTPS Port", ""),
+		prop("redirect", "HTTP to HTTPS Redirection", "enabled", "enabled", "disabled"),
+	)
+	s.OnShow = func(s *Scope) {
+		s.Set("HTTPS Port", fmt.Sprint(m.HTTPSPort))
+	}
+	return s
+}
+
+func certificateScope(m *MockCIMC) *Scope {
+	s := NewScope("certificate", "Certificate Information",
+		ro("Serial Number", ""),
+		ro("Subject Information", ""),
+		ro("Issuer Information", ""),
+		ro("Valid From", ""),
+		ro("Valid To", ""),
+	)
+	s.OnShow = func(s *Scope) {
+		cert, err := x509.ParseCertificate(m.cert.Certificate[0])
+		if err != nil {
+			return
+		}
+		s.Set("Serial Number", cert.SerialNumber.Text(16))
+		s.Set("Subject Information", cert.Subject.String())
+		s.Set("Issuer Information", cert.Issuer.String())
+		s.Set("Valid From", cert.NotBefore.UTC().Format(time.ANSIC))
+		s.Set("Valid To", cert.NotAfter.UTC().Format(time.ANSIC))
+	}
+	s.Commands["generate-csr"] = generateCSR
+	s.Commands["paste-certificate"] = pasteCertificate
+	return s
+}
+
+// generateCSR - ask for the subject, make a new key and print a csr for it.
+//   The key is used by the next pasted certificate.
+func generateCSR(t *Term, args []string) {
+	answers := map[string]string{}
+	for _, q := range csrFields {
+		t.Printf("%s: ", q)
+		a, err := t.ReadLine()
+		if err != nil {
+			return
+		}
+		t.Printf("%s\n", a)
+		answers[q] = strings.TrimSpace(a)
+	}
+	if answers["Common Name (CN)"] == "" {
+		t.Errorf("Common Name is required")
+		return
+	}
+	if cc := answers["Country Code (CC)"]; cc != "" && len(cc) != 2 {
+		t.Errorf("Invalid Country Code '%s'", cc)
+		return
+	}
+	if !t.Ask("Continue to generate CSR?") {
+		return
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	subject := pkix.Name{CommonName: answers["Common Name (CN)"]}
+	for q, field := range map[string]*[]string{
+		"Organization Name (O)":  &subject.Organization,
+		"Organization Unit (OU)": &subject.OrganizationalUnit,
+		"Locality (L)":           &subject.Locality,
+		"StateName (S)":          &subject.Province,
+		"Country Code (CC)":      &subject.Country,
+	} {
+		if a := answers[q]; a != "" {
+			*field = []string{a}
+		}
+	}
+	tmpl := &x509.CertificateRequest{Subject: subject}
+	if email := answers["Email"]; email != "" {
+		tmpl.EmailAddresses = []string{email}
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	t.Mock.csrKey = key
+	t.Printf("%s", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+// pasteCertificate - read a PEM certificate up to ctrl-D and serve it, if
+// it is for the key of the last csr.
+func pasteCertificate(t *Term, args []string) {
+	t.Printf("Please paste your certificate here, when finished, press CTRL+D.\n")
+	data, err := t.ReadUntil(0x04)
+	if err != nil {
+		return
+	}
+	block, _ := pem.Decode([]byte(strings.Replace(data, "\r", "", -1)))
+	if block == nil || block.Type != "CERTIFICATE" {
+		t.Errorf("Invalid certificate")
+		return
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Errorf("Invalid certificate: %v", err)
+		return
+	}
+	key := t.Mock.csrKey
+	if key == nil || !key.PublicKey.Equal(cert.PublicKey) {
+		t.Errorf("Certificate does not match the generated CSR")
+		return
+	}
+	t.Mock.cert = tls.Certificate{Certificate: [][]byte{block.Bytes}, PrivateKey: key}
+	t.Printf("Certificate uploaded successfully.\n")
+}
@@ -1,57 +1,706 @@
 package test
 
 import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gliderlabs/ssh"
 	"github.com/phayes/freeport"
+	gossh "golang.org/x/crypto/ssh"
 )
 
+// Prompt - the serial number shown in the mock cimc prompt.
 const Prompt = "CONSOLE"
 
+// NewMockServer - start a mock cimc with the default scopes, return its port.
 func NewMockServer() (int, error) {
+	m := NewMockCIMC()
+	if err := m.Start(); err != nil {
+		return -1, err
+	}
+	return m.Port, nil
+}
+
+// MockCIMC - a simulated cimc command line, served over ssh.
+//   The command line is a tree of Scope.  'top', 'scope', 'exit', 'show',
+//   'set', 'commit' and 'discard' are handled for every scope, anything
+//   else is looked up in Scope.Commands.
+type MockCIMC struct {
+	Serial string
+	Port   int
+	Root   *Scope
+	// RebootTime is how long the cimc refuses connections when it reboots.
+	RebootTime time.Duration
+	// ClockOffset is how far the cimc clock is ahead of the real one.
+	ClockOffset time.Duration
+	// HTTPSPort is where the web interface serves the cimc certificate.
+	HTTPSPort int
+	// IPMIPort is the udp port of ipmi over lan.
+	IPMIPort int
+	// ConfigStartShows is how many 'show' of import-export still show the
+	// last operation after an export or import started, like a slow cimc.
+	ConfigStartShows int
+	// IPMIChassisControls counts the ipmi chassis controls done.
+	IPMIChassisControls int
+	// IPMILostReplies is how many of the next chassis control responses
+	// get lost, like on a bad network.
+	IPMILostReplies int
+
+	mu        sync.Mutex
+	server    *ssh.Server
+	conns     map[net.Conn]bool
+	downUntil time.Time
+	https     net.Listener
+	ipmi      *net.UDPConn
+	cert      tls.Certificate
+	csrKey    *ecdsa.PrivateKey
+	// redfish sessions, token to id.
+	rfSessions map[string]string
+	rfNextID   int
+	// redfish event subscriptions, by id.
+	rfSubs    map[string]rfSubscription
+	rfEventID int
+	// ipmi sessions, by bmc session id.
+	ipmiSessions map[uint32]*ipmiSession
+}
+
+// NewMockCIMC - return a MockCIMC populated with the default scopes.
+func NewMockCIMC() *MockCIMC {
+	m := &MockCIMC{
+		Serial:       Prompt,
+		RebootTime:   500 * time.Millisecond,
+		conns:        map[net.Conn]bool{},
+		rfSessions:   map[string]string{},
+		rfSubs:       map[string]rfSubscription{},
+		ipmiSessions: map[uint32]*ipmiSession{},
+	}
+	m.Root = defaultRoot(m)
+	return m
+}
+
+// defaultRoot - return the top scope with the default scopes, as the mock
+// starts, or is reset to by 'factory-default'.
+func defaultRoot(m *MockCIMC) *Scope {
+	root := &Scope{Commands: map[string]Command{"connect": connect}}
+	root.Add(
+		chassisScope(),
+		cimcScope(m),
+		biosScope(),
+		timeScope(),
+		ldapScope(),
+		snmpScope(),
+		httpsScope(m),
+		certificateScope(m),
+		vmediaScope(),
+		solScope(),
+		ipmiScope(),
+		redfishScope(m),
+	)
+	root.Add(userScopes()...)
+	return root
+}
+
+// Start - listen on a free port and serve ssh in the background.
+func (m *MockCIMC) Start() error {
 	port, err := freeport.GetFreePort()
 	if err != nil {
-		return -1, err
+		return err
+	}
+
+	// listen before returning so callers can connect right away.
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	if err := m.startHTTPS(); err != nil {
+		ln.Close()
+		return err
+	}
+	if err := m.startIPMI(); err != nil {
+		ln.Close()
+		m.https.Close()
+		return err
+	}
+
+	m.Port = port
+	m.server = &ssh.Server{
+		Handler:         m.handle,
+		ConnCallback:    m.accept,
+		PasswordHandler: m.password,
 	}
 
-	ssh.Handle(func(s ssh.Session) {
-		io.WriteString(s, fmt.Sprintf("%s# \n", Prompt))
-		cmd := make([]byte, 1024)
-		for {
-			n, err := s.Read(cmd)
-			if err != nil {
-				s.Exit(1)
-				return
-			}
-			str := strings.TrimSpace(string(cmd[0:n]))
-			log.Printf("str=%#v n=%#v err=%#v\n", str, n, err)
-			switch str {
-			case "top":
-				io.WriteString(s, fmt.Sprintf("%s# \n", Prompt))
-			case "scope chassis":
-				io.WriteString(s, fmt.Sprintf("%s /chassis # \n", Prompt))
-			case "show detail | no-more":
-				io.WriteString(s, "\nChassis:\n Power: on\n")
-				io.WriteString(s, fmt.Sprintf("%s# \n", Prompt))
-			case "power on | no-more":
-				io.WriteString(s, fmt.Sprintf("%s# \n", Prompt))
-			case "power off | no-more":
-				io.WriteString(s, fmt.Sprintf("%s# \n", Prompt))
-			default:
-				log.Printf("str=%#v\n", str)
-			}
-		}
-	})
-
-	// start the server in a different goroutine
 	go func() {
 		log.Printf("ssh server listening on port %d\n", port)
-		log.Fatal(ssh.ListenAndServe(fmt.Sprintf(":%d", port), nil))
+		if err := m.server.Serve(ln); err != nil && err != ssh.ErrServerClosed {
+			log.Printf("ssh server on port %d failed: %v\n", port, err)
+		}
 	}()
 
-	return port, err
+	return nil
+}
+
+// Close - stop the ssh server and drop all connections.
+func (m *MockCIMC) Close() error {
+	m.https.Close()
+	m.ipmi.Close()
+	return m.server.Close()
+}
+
+// Restart - drop all connections and refuse new ones for 'down',
+//   like the cimc does while it reboots.
+func (m *MockCIMC) Restart(down time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restartLocked(down)
+}
+
+// Reboot - drop all connections and refuse new ones for RebootTime.
+//   Call it from a Command, where the mock is already locked.
+func (t *Term) Reboot() {
+	t.Mock.restartLocked(t.Mock.RebootTime)
+	t.hungup = true
+}
+
+func (m *MockCIMC) restartLocked(down time.Duration) {
+	m.downUntil = time.Now().Add(down)
+	for c := range m.conns {
+		c.Close()
+		delete(m.conns, c)
+	}
+}
+
+// Lookup - return the scope at path (for example "chassis/adapter MLOM"),
+//   or nil if there is none.
+func (m *MockCIMC) Lookup(path string) *Scope {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Root.lookup(path)
+}
+
+// Get - return the value of property 'name' in the scope at path.
+func (m *MockCIMC) Get(path, name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.Root.lookup(path); s != nil {
+		return s.Get(name)
+	}
+	return ""
+}
+
+// Set - set property 'name' in the scope at path, bypassing set/commit.
+func (m *MockCIMC) Set(path, name, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.Root.lookup(path); s != nil {
+		s.Set(name, value)
+	}
+}
+
+// Do - run f with the mock locked, for changes more involved than Set.
+func (m *MockCIMC) Do(f func(m *MockCIMC)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f(m)
+}
+
+func (m *MockCIMC) accept(ctx ssh.Context, conn net.Conn) net.Conn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Now().Before(m.downUntil) {
+		return nil
+	}
+	m.conns[conn] = true
+	return conn
+}
+
+func (m *MockCIMC) password(ctx ssh.Context, password string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.login(ctx.User(), password)
+}
+
+// login - check the password against the enabled user accounts, then
+// against the ldap directories if ldap is enabled.
+func (m *MockCIMC) login(name, password string) bool {
+	for _, u := range m.Root.Children {
+		if strings.HasPrefix(u.Name, "user ") && u.Get("name") == name {
+			return u.Get("enabled") == "yes" && u.Get("password") == password
+		}
+	}
+	return m.ldapLogin(name, password)
+}
+
+func (m *MockCIMC) handle(s ssh.Session) {
+	t := &Term{Mock: m, Scope: m.Root, sess: s, in: bufio.NewReader(s)}
+	t.prompt()
+	for {
+		line, err := t.ReadLine()
+		if err != nil {
+			s.Exit(1)
+			return
+		}
+		t.Printf("%s\n", line)
+
+		m.mu.Lock()
+		t.exec(line)
+		m.mu.Unlock()
+
+		if t.hungup {
+			return
+		}
+		t.prompt()
+	}
+}
+
+// Scope - a scope of the mock cimc command line.
+type Scope struct {
+	// Name is what follows 'scope', e.g. "chassis" or "adapter MLOM".
+	Name string
+	// Title heads the 'show detail' output, e.g. "Chassis".
+	Title string
+	Props []*Prop
+	// Commands handles commands other than the builtins.
+	Commands map[string]Command
+	// OnShow, if set, is called before the scope is shown.
+	OnShow func(s *Scope)
+	// OnCommit, if set, is called after 'commit' applied changes to this
+	// scope or any scope below it that has no OnCommit of its own.
+	OnCommit func(t *Term, changed map[string]string)
+	Children []*Scope
+	// Kinds are kinds of children 'show' takes even if there are none yet,
+	// like "mappings" for 'show mappings'.
+	Kinds []string
+
+	parent *Scope
+}
+
+// NewScope - return a Scope with the given name, title and properties.
+func NewScope(name, title string, props ...*Prop) *Scope {
+	return &Scope{Name: name, Title: title, Props: props, Commands: map[string]Command{}}
+}
+
+// Add - add children to the scope, return the scope.
+func (s *Scope) Add(children ...*Scope) *Scope {
+	for _, c := range children {
+		c.parent = s
+		s.Children = append(s.Children, c)
+	}
+	return s
+}
+
+// Remove - remove the child scope with name.
+func (s *Scope) Remove(name string) {
+	for i, c := range s.Children {
+		if c.Name == name {
+			s.Children = append(s.Children[:i], s.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// Child - return the child scope with name, or nil.
+func (s *Scope) Child(name string) *Scope {
+	for _, c := range s.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Parent - return the parent scope, nil for the top.
+func (s *Scope) Parent() *Scope {
+	return s.parent
+}
+
+// Path - return the path of the scope, for example "/chassis/adapter MLOM".
+func (s *Scope) Path() string {
+	if s.parent == nil {
+		return ""
+	}
+	return s.parent.Path() + "/" + s.Name
+}
+
+// Prop - return the property with the given 'set' name or label, or nil.
+func (s *Scope) Prop(name string) *Prop {
+	for _, p := range s.Props {
+		if p.Name == name || p.Label == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Get - return the value of a property, "" if there is none.
+func (s *Scope) Get(name string) string {
+	if p := s.Prop(name); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+// Set - set the value of a property, adding a read-only one if needed.
+func (s *Scope) Set(name, value string) {
+	if p := s.Prop(name); p != nil {
+		p.Value = value
+		return
+	}
+	s.Props = append(s.Props, &Prop{Label: name, Value: value})
+}
+
+func (s *Scope) lookup(path string) *Scope {
+	cur := s
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		if cur = cur.Child(name); cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+func (s *Scope) detail() string {
+	if s.OnShow != nil {
+		s.OnShow(s)
+	}
+	title := s.Title
+	if title == "" {
+		title = s.Name
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", title)
+	for _, p := range s.Props {
+		if p.Secret {
+			continue
+		}
+		fmt.Fprintf(&b, "    %s: %s\n", p.label(), p.Value)
+	}
+	return b.String()
+}
+
+// Prop - a property of a scope.  It is shown by 'show detail' as
+//   'Label: Value' and, if Name is not empty, changed with 'set Name value'.
+type Prop struct {
+	Name  string
+	Label string
+	Value string
+	// Values, if not empty, is the list of values 'set' accepts.
+	Values []string
+	// Secret properties are never shown, and 'set Name' prompts for them.
+	Secret bool
+	// Validate, if set, checks a value before 'set' accepts it.
+	Validate func(value string) error
+}
+
+func (p *Prop) label() string {
+	if p.Label == "" {
+		return p.Name
+	}
+	return p.Label
+}
+
+func (p *Prop) allows(value string) bool {
+	if len(p.Values) == 0 {
+		return true
+	}
+	for _, v := range p.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Command - handler for a command in a scope.  args[0] is the command.
+type Command func(t *Term, args []string)
+
+// Term - one ssh session on the mock cimc.
+type Term struct {
+	Mock  *MockCIMC
+	Scope *Scope
+
+	sess   ssh.Session
+	in     *bufio.Reader
+	hungup bool
+	// uncommitted 'set's, like the cimc they span scopes until 'commit'.
+	pending map[*Scope]map[string]string
+}
+
+// Printf - write to the session.
+func (t *Term) Printf(format string, a ...interface{}) {
+	fmt.Fprintf(t.sess, format, a...)
+}
+
+// Errorf - write a cimc style error line to the session.
+func (t *Term) Errorf(format string, a ...interface{}) {
+	t.Printf("Error: "+format+"\n", a...)
+}
+
+// ReadLine - read a line of input from the session.
+func (t *Term) ReadLine() (string, error) {
+	line, err := t.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ReadUntil - read input from the session up to and excluding delim.
+func (t *Term) ReadUntil(delim byte) (string, error) {
+	data, err := t.in.ReadString(delim)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(data, string(delim)), nil
+}
+
+// Confirm - show msg and the cimc confirm prompt, return true on 'y'.
+func (t *Term) Confirm(msg string) bool {
+	if msg != "" {
+		t.Printf("%s\n", msg)
+	}
+	return t.Ask("Do you want to continue?")
+}
+
+// Ask - show a yes/no question, return true on 'y'.
+func (t *Term) Ask(question string) bool {
+	t.Printf("%s[y|N]", question)
+	answer, err := t.ReadLine()
+	if err != nil {
+		return false
+	}
+	t.Printf("%s\n", answer)
+	return strings.TrimSpace(answer) == "y"
+}
+
+// readSecret - prompt for a password twice, without echo.
+func (t *Term) readSecret() (string, error) {
+	t.Printf("Please enter password:")
+	secret, err := t.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	t.Printf("\nPlease confirm password:")
+	confirm, err := t.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	t.Printf("\n")
+	if secret != confirm {
+		return "", fmt.Errorf("Passwords do not match")
+	}
+	return secret, nil
+}
+
+// Hangup - drop the ssh connection of this session.
+func (t *Term) Hangup() {
+	t.hungup = true
+	if conn, ok := t.sess.Context().Value(ssh.ContextKeyConn).(*gossh.ServerConn); ok {
+		conn.Close()
+	}
+}
+
+func (t *Term) prompt() {
+	star := ""
+	if len(t.pending) != 0 {
+		star = "*"
+	}
+	// like the cimc, show 'scope user 3' as /user
+	path := ""
+	for s := t.Scope; s.parent != nil; s = s.parent {
+		path = "/" + strings.SplitN(s.Name, " ", 2)[0] + path
+	}
+	if path != "" {
+		t.Printf("%s %s %s# ", t.Mock.Serial, path, star)
+	} else {
+		t.Printf("%s%s# ", t.Mock.Serial, star)
+	}
+}
+
+func (t *Term) exec(line string) {
+	// the cimc pipes output through filters like 'no-more'; we just drop them.
+	if i := strings.Index(line, "|"); i >= 0 {
+		line = line[:i]
+	}
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return
+	}
+
+	s := t.Scope
+	switch args[0] {
+	case "top":
+		t.Scope = t.Mock.Root
+	case "exit":
+		if s.parent != nil {
+			t.Scope = s.parent
+		}
+	case "scope":
+		next := s.lookup(strings.Join(args[1:], " "))
+		if len(args) < 2 || next == nil {
+			t.Errorf("Invalid scope '%s'", strings.Join(args[1:], " "))
+			return
+		}
+		t.Scope = next
+	case "show":
+		t.show(args[1:])
+	case "set":
+		if len(args) < 2 {
+			t.Errorf("Missing property name")
+			return
+		}
+		value := unquote(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line[len("set"):]), args[1])))
+		t.set(args[1], value)
+	case "commit":
+		t.commit()
+	case "discard":
+		t.pending = nil
+	default:
+		cmd, ok := s.Commands[args[0]]
+		if !ok {
+			t.Errorf("Invalid command '%s'", args[0])
+			return
+		}
+		cmd(t, args)
+	}
+}
+
+func (t *Term) show(args []string) {
+	if len(args) > 0 && args[len(args)-1] == "detail" {
+		args = args[:len(args)-1]
+	}
+	if len(args) == 0 {
+		t.Printf("%s", t.Scope.detail())
+		return
+	}
+
+	// 'show <kind> [id]' shows all children 'kind' or 'kind id'.
+	name := strings.Join(args, " ")
+	found := false
+	for _, c := range t.Scope.Children {
+		if c.Name == name || (len(args) == 1 && strings.HasPrefix(c.Name, name+" ")) {
+			t.Printf("%s", c.detail())
+			found = true
+		}
+	}
+	if !found {
+		if _, ok := t.Scope.Commands[args[0]]; !ok && t.Scope.Child(args[0]) == nil && !t.isKind(args[0]) {
+			t.Errorf("Invalid object '%s'", name)
+		}
+	}
+}
+
+// isKind - collections like 'user' may be empty, which is not an error.
+func (t *Term) isKind(kind string) bool {
+	for _, k := range t.Scope.kinds() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) kinds() []string {
+	kinds := append([]string{}, s.Kinds...)
+	for _, c := range s.Children {
+		kinds = append(kinds, strings.SplitN(c.Name, " ", 2)[0])
+	}
+	return kinds
+}
+
+func (t *Term) set(name, value string) {
+	s := t.Scope
+	p := s.Prop(name)
+	if p == nil || p.Name != name {
+		t.Errorf("Invalid property '%s'", name)
+		return
+	}
+	if p.Secret {
+		if value != "" {
+			t.Errorf("'%s' can not be given on the command line", name)
+			return
+		}
+		var err error
+		if value, err = t.readSecret(); err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+	} else if value == "?" {
+		// help: list the valid values, one per line.
+		for _, v := range p.Values {
+			t.Printf("  %s\n", v)
+		}
+		return
+	}
+	if !p.allows(value) {
+		t.Errorf("Invalid value '%s' for '%s'. Valid values: %s", value, name, strings.Join(p.Values, ", "))
+		return
+	}
+	if p.Validate != nil {
+		if err := p.Validate(value); err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+	}
+	if t.pending == nil {
+		t.pending = map[*Scope]map[string]string{}
+	}
+	if t.pending[s] == nil {
+		t.pending[s] = map[string]string{}
+	}
+	t.pending[s][name] = value
+}
+
+// commit - apply all pending changes, then call the OnCommit of each
+// scope that owns a change once, with all changes it owns.
+func (t *Term) commit() {
+	owners := []*Scope{}
+	changes := map[*Scope]map[string]string{}
+	for s, changed := range t.pending {
+		owner := s
+		for owner.OnCommit == nil && owner.parent != nil {
+			owner = owner.parent
+		}
+		if changes[owner] == nil {
+			owners = append(owners, owner)
+			changes[owner] = map[string]string{}
+		}
+		for name, value := range changed {
+			s.Prop(name).Value = value
+			changes[owner][name] = value
+		}
+	}
+	t.pending = nil
+
+	for _, owner := range owners {
+		if owner.OnCommit != nil {
+			owner.OnCommit(t, changes[owner])
+		}
+	}
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func prop(name, label, value string, values ...string) *Prop {
+	return &Prop{Name: name, Label: label, Value: value, Values: values}
+}
+
+func ro(label, value string) *Prop {
+	return &Prop{Label: label, Value: value}
 }
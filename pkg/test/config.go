@@ -0,0 +1,207 @@
+package test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configStep - how far an export or import gets between two 'show'.
+const configStep = 50
+
+// noExport - scopes whose settings are not part of an exported
+// configuration.
+var noExport = []string{"/cimc/import-export", "/cimc/tech-support"}
+
+// cimcConfig - the mock's exported configuration: the settable, not
+// secret, properties of each scope.
+type cimcConfig struct {
+	XMLName xml.Name      `xml:"CIMC-Configuration"`
+	Serial  string        `xml:"serial,attr"`
+	Scopes  []configScope `xml:"scope"`
+}
+
+type configScope struct {
+	Path  string       `xml:"path,attr"`
+	Props []configProp `xml:"prop"`
+}
+
+type configProp struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+func importExportScope(m *MockCIMC) *Scope {
+	s := NewScope("import-export", "Import Export",
+		ro("Operation", "NONE"),
+		ro("Status", "NONE"),
+		ro("Progress (%)", "0"),
+		ro("Error Code", "100 (No Error)"),
+		ro("Diagnostic Message", "NONE"),
+		ro("Remote URL", ""),
+	)
+
+	// pending starts an operation, once the last one is no longer shown.
+	var pending func()
+	start := func(op string) Command {
+		return func(t *Term, args []string) {
+			if s.Get("Status") == "IN-PROGRESS" || pending != nil {
+				t.Errorf("An import or export operation is already in progress")
+				return
+			}
+			if len(args) != 4 {
+				t.Errorf("Usage: %s <protocol> <server> <path>", args[0])
+				return
+			}
+			url := args[1] + "://" + args[2] + "/" + strings.TrimPrefix(args[3], "/")
+			pending = func() {
+				s.Set("Operation", op)
+				s.Set("Status", "IN-PROGRESS")
+				s.Set("Progress (%)", "0")
+				s.Set("Error Code", "100 (No Error)")
+				s.Set("Diagnostic Message", "NONE")
+				s.Set("Remote URL", url)
+			}
+			if m.ConfigStartShows == 0 {
+				pending()
+				pending = nil
+			}
+			t.Printf("%s config process started. Please check the status using \"show detail\".\n",
+				op[:1]+strings.ToLower(op[1:]))
+		}
+	}
+	s.Commands["export-config"] = start("EXPORT")
+	s.Commands["import-config"] = start("IMPORT")
+
+	s.OnShow = func(s *Scope) {
+		if pending != nil {
+			if m.ConfigStartShows > 0 {
+				m.ConfigStartShows--
+				return
+			}
+			pending()
+			pending = nil
+		}
+		if s.Get("Status") != "IN-PROGRESS" {
+			return
+		}
+		pct, _ := strconv.Atoi(s.Get("Progress (%)"))
+		if pct += configStep; pct < 100 {
+			s.Set("Progress (%)", strconv.Itoa(pct))
+			return
+		}
+		s.Set("Progress (%)", "100")
+
+		var err error
+		if s.Get("Operation") == "EXPORT" {
+			err = exportConfig(m, s.Get("Remote URL"))
+		} else {
+			err = importConfig(m, s.Get("Remote URL"))
+		}
+		if err != nil {
+			s.Set("Status", "FAILED")
+			s.Set("Error Code", "2 (Operation Failed)")
+			s.Set("Diagnostic Message", err.Error())
+			return
+		}
+		s.Set("Status", "COMPLETED")
+	}
+	return s
+}
+
+func exportConfig(m *MockCIMC, url string) error {
+	if !strings.HasPrefix(url, "http://") {
+		return fmt.Errorf("protocol of '%s' is not supported by the mock", url)
+	}
+	cfg := cimcConfig{Serial: m.Serial}
+	var walk func(s *Scope)
+	walk = func(s *Scope) {
+		for _, skip := range noExport {
+			if s.Path() == skip {
+				return
+			}
+		}
+		cs := configScope{Path: s.Path()}
+		for _, p := range s.Props {
+			if p.Name != "" && !p.Secret {
+				cs.Props = append(cs.Props, configProp{Name: p.Name, Value: p.Value})
+			}
+		}
+		if len(cs.Props) != 0 {
+			cfg.Scopes = append(cfg.Scopes, cs)
+		}
+		for _, c := range s.Children {
+			walk(c)
+		}
+	}
+	walk(m.Root)
+
+	data, err := xml.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(xml.Header+string(data)+"\n"))
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("upload failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// importConfig - fetch a configuration and apply it.  Nothing is changed
+// if any setting is invalid.
+func importConfig(m *MockCIMC, url string) error {
+	if !strings.HasPrefix(url, "http://") {
+		return fmt.Errorf("protocol of '%s' is not supported by the mock", url)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	cfg := cimcConfig{}
+	if err := xml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("Invalid configuration file: %v", err)
+	}
+
+	type change struct {
+		p     *Prop
+		value string
+	}
+	changes := []change{}
+	for _, cs := range cfg.Scopes {
+		s := m.Root.lookup(cs.Path)
+		if s == nil {
+			return fmt.Errorf("Invalid scope '%s'", cs.Path)
+		}
+		for _, cp := range cs.Props {
+			p := s.Prop(cp.Name)
+			if p == nil || p.Name != cp.Name || p.Secret || !p.allows(cp.Value) {
+				return fmt.Errorf("Invalid setting %s/%s=%s", cs.Path, cp.Name, cp.Value)
+			}
+			changes = append(changes, change{p, cp.Value})
+		}
+	}
+	for _, c := range changes {
+		c.p.Value = c.value
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// firmwareStep - how far a firmware update gets between two 'show'.
+const firmwareStep = 50
+
+// fetchImage - download the image that 'update <protocol> <server> <path>
+// [user [password]]' points at and return its version.  Without the
+// password on the command line it is prompted for.  The mock only speaks
+// http(s), with basic auth for the user, and takes the first line of the
+// image as its version.
+func fetchImage(t *Term, args []string) (string, error) {
+	if len(args) < 4 || len(args) > 6 {
+		return "", fmt.Errorf("Usage: update <protocol> <server> <path> [user [password]]")
+	}
+	proto, server, path := args[1], args[2], args[3]
+	if proto != "http" && proto != "https" {
+		return "", fmt.Errorf("protocol '%s' is not supported by the mock", proto)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, proto+"://"+server+"/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	if len(args) >= 5 {
+		password := ""
+		if len(args) == 6 {
+			password = args[5]
+		} else {
+			t.Printf("Please enter password:")
+			if password, err = t.ReadLine(); err != nil {
+				return "", err
+			}
+			t.Printf("\n")
+		}
+		req.SetBasicAuth(args[4], password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0]), nil
+}
+
+func cimcFirmwareScope() *Scope {
+	s := NewScope("firmware", "Firmware Image Information",
+		ro("Update Stage", "NONE"),
+		ro("Update Progress", "100"),
+		ro("Update Status", "NONE"),
+		ro("Current FW Version", "4.1(2f)"),
+		ro("FW Image 1 Version", "4.1(2f)"),
+		ro("FW Image 1 State", "RUNNING ACTIVATED"),
+		ro("FW Image 2 Version", "4.1(1d)"),
+		ro("FW Image 2 State", "BACKUP INACTIVATED"),
+		ro("Boot-loader Version", "4.1(2f).36"),
+	)
+
+	// image - the index of the running or backup image.
+	image := func(state string) string {
+		if strings.HasPrefix(s.Get("FW Image 1 State"), state) {
+			return "1"
+		}
+		return "2"
+	}
+
+	s.Commands["update"] = func(t *Term, args []string) {
+		version, err := fetchImage(t, args)
+		if err != nil {
+			s.Set("Update Stage", "ERROR")
+			s.Set("Update Progress", "0")
+			s.Set("Update Status", err.Error())
+			return
+		}
+		// the new image replaces the backup one.
+		s.Set("FW Image "+image("BACKUP")+" Version", version)
+		s.Set("Update Stage", "DOWNLOAD")
+		s.Set("Update Progress", "0")
+		s.Set("Update Status", "Download in progress")
+	}
+
+	s.OnShow = func(s *Scope) {
+		if s.Get("Update Stage") != "DOWNLOAD" {
+			return
+		}
+		pct, _ := strconv.Atoi(s.Get("Update Progress"))
+		if pct += firmwareStep; pct >= 100 {
+			s.Set("Update Stage", "NONE")
+			s.Set("Update Status", "SUCCESS")
+			pct = 100
+		}
+		s.Set("Update Progress", strconv.Itoa(pct))
+	}
+
+	s.Commands["activate"] = func(t *Term, args []string) {
+		running, backup := image("RUNNING"), image("BACKUP")
+		version := s.Get("FW Image " + backup + " Version")
+		if !t.Confirm(fmt.Sprintf("This operation will activate firmware %s and reboot the BMC.", version)) {
+			return
+		}
+		s.Set("FW Image "+backup+" State", "RUNNING ACTIVATED")
+		s.Set("FW Image "+running+" State", "BACKUP INACTIVATED")
+		s.Set("Current FW Version", version)
+		s.Set("Update Status", "NONE")
+		s.Parent().Set("Firmware Version", version)
+		t.Printf("Firmware activation started, Cisco IMC will reboot.\n")
+		t.Reboot()
+	}
+
+	return s
+}
+
+func addBiosUpdate(s *Scope) {
+	s.Commands["update"] = func(t *Term, args []string) {
+		version, err := fetchImage(t, args)
+		if err != nil {
+			s.Set("FW Update/Recovery Status", "Error, "+err.Error())
+			s.Set("FW Update/Recovery Progress", "0")
+			return
+		}
+		s.Set("Backup BIOS Version", version)
+		s.Set("FW Update/Recovery Status", "Downloading, OK")
+		s.Set("FW Update/Recovery Progress", "0")
+	}
+
+	s.OnShow = func(s *Scope) {
+		if s.Get("FW Update/Recovery Status") != "Downloading, OK" {
+			return
+		}
+		pct, _ := strconv.Atoi(s.Get("FW Update/Recovery Progress"))
+		if pct += firmwareStep; pct >= 100 {
+			s.Set("FW Update/Recovery Status", "Done, OK")
+			pct = 100
+		}
+		s.Set("FW Update/Recovery Progress", strconv.Itoa(pct))
+	}
+
+	s.Commands["activate"] = func(t *Term, args []string) {
+		msg := fmt.Sprintf("This operation will activate \"%s\" after the host power off.", s.Get("Backup BIOS Version"))
+		if !t.Confirm(msg) {
+			return
+		}
+		running := s.Get("BIOS Version")
+		s.Set("BIOS Version", s.Get("Backup BIOS Version"))
+		s.Set("Backup BIOS Version", running)
+		s.Set("FW Update/Recovery Status", "None, OK")
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostUser and HostPassword - the login of the host behind the mocks, on
+// its serial console over ipmi sol or racadm 'console com2'.
+const (
+	HostUser     = "root"
+	HostPassword = "cisco123"
+)
+
+const hostLogin = "localhost login: "
+
+// hostShell - a login prompt, then a shell, on the host serial console.
+//   It knows just enough for loginshell: PS1, stty, export, echo, true,
+//   false, logout and poweroff.  Everything else is not found.
+type hostShell struct {
+	out func(string)
+	// poweroff turns the host off.
+	poweroff func()
+	line     []byte
+	cr       bool
+	echo     bool
+	// state is "login", "password" or "shell".
+	state string
+	user  string
+	// pending is a command line with an open quote.
+	pending string
+	ps1     string
+	rc      int
+}
+
+func newHostShell(out func(string), poweroff func()) *hostShell {
+	return &hostShell{out: out, poweroff: poweroff, echo: true, state: "login"}
+}
+
+// input - take what is typed on the console, with the mock locked.
+func (h *hostShell) input(data []byte) {
+	for _, b := range data {
+		switch {
+		case b == '\n' && h.cr:
+			h.cr = false
+		case b == '\r' || b == '\n':
+			h.cr = b == '\r'
+			if h.echo {
+				h.out("\r\n")
+			}
+			line := string(h.line)
+			h.line = nil
+			h.enter(line)
+		case b == 0x7f || b == 0x08:
+			h.cr = false
+			if len(h.line) > 0 {
+				h.line = h.line[:len(h.line)-1]
+				if h.echo && h.state != "password" {
+					h.out("\b \b")
+				}
+			}
+		default:
+			h.cr = false
+			h.line = append(h.line, b)
+			if h.echo && h.state != "password" {
+				h.out(string(b))
+			}
+		}
+	}
+}
+
+func (h *hostShell) enter(line string) {
+	switch h.state {
+	case "login":
+		if line = strings.TrimSpace(line); line != "" {
+			h.user = line
+			h.state = "password"
+			h.out("Password: ")
+			return
+		}
+		h.out(hostLogin)
+	case "password":
+		if h.user != HostUser || line != HostPassword {
+			h.state = "login"
+			h.out("\r\nLogin incorrect\r\n" + hostLogin)
+			return
+		}
+		h.state, h.echo, h.rc = "shell", true, 0
+		h.ps1 = "[root@localhost ~]# "
+		h.out("Last login: on ttyS0\r\n")
+		h.prompt()
+	case "shell":
+		// a quote left open goes on on the next line, like bash.
+		line = h.pending + line
+		if strings.Count(line, "'")%2 != 0 {
+			h.pending = line + "\n"
+			h.out("> ")
+			return
+		}
+		h.pending = ""
+		if h.run(strings.TrimSpace(line)) {
+			h.prompt()
+		}
+	}
+}
+
+// run - run a command line, return false if there is no prompt after.
+func (h *hostShell) run(line string) bool {
+	f := strings.Fields(line)
+	if len(f) == 0 {
+		return true
+	}
+	h.rc = 0
+	switch {
+	case strings.HasPrefix(line, "PS1="):
+		h.ps1 = strings.Trim(strings.TrimPrefix(line, "PS1="), `'"`)
+	case f[0] == "stty":
+		for _, a := range f[1:] {
+			if a == "-echo" || a == "echo" {
+				h.echo = a == "echo"
+			}
+		}
+	case f[0] == "export" || f[0] == "true":
+	case f[0] == "false":
+		h.rc = 1
+	case f[0] == "echo":
+		h.out(strings.Join(f[1:], " ") + "\r\n")
+	case f[0] == "logout" || f[0] == "exit":
+		h.state, h.echo = "login", true
+		h.out("\r\n" + hostLogin)
+		return false
+	case f[0] == "poweroff":
+		h.out("[  OK  ] Reached target Power-Off.\r\nreboot: Power down\r\n")
+		h.state, h.echo = "login", true
+		h.poweroff()
+		return false
+	default:
+		h.out("-bash: " + f[0] + ": command not found\r\n")
+		h.rc = 127
+	}
+	return true
+}
+
+// prompt - print PS1, with the escapes loginshell uses.
+func (h *hostShell) prompt() {
+	p := strings.Replace(h.ps1, `\n`, "\n", -1)
+	p = strings.Replace(p, "\n", "\r\n", -1)
+	h.out(strings.Replace(p, "$?", fmt.Sprint(h.rc), -1))
+}
@@ -0,0 +1,306 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/phayes/freeport"
+)
+
+// IDRACUser and IDRACPassword - the login of the mock iDRAC, the Dell
+// default.
+const (
+	IDRACUser     = "root"
+	IDRACPassword = "calvin"
+)
+
+const (
+	racadmPrompt  = "racadm>>"
+	ctrlBackslash = 0x1c
+)
+
+// MockIDRAC - a simulated Dell iDRAC racadm command line, served over ssh.
+//   It knows serveraction, getversion, getsysinfo, get and set of
+//   Attributes, and 'console com2', which attaches to the host serial
+//   console until ctrl-\.  Commands may be given with or without 'racadm'
+//   in front, like on the iDRAC.
+type MockIDRAC struct {
+	Port int
+	// Power is "ON" or "OFF".
+	Power string
+	// Attributes are what 'get' and 'set' see, like
+	// "iDRAC.SerialRedirection.Enable".
+	Attributes map[string]string
+
+	mu     sync.Mutex
+	server *ssh.Server
+	// host is the shell on the serial console, it starts again at the
+	// login prompt when the power goes off.
+	host *hostShell
+	// console is where the host writes, the session on 'console com2'.
+	console io.Writer
+}
+
+// NewMockIDRAC - return a MockIDRAC, powered on, with serial redirection
+// enabled.
+func NewMockIDRAC() *MockIDRAC {
+	return &MockIDRAC{
+		Power: "ON",
+		Attributes: map[string]string{
+			"iDRAC.Info.Name":                     "iDRAC",
+			"iDRAC.Info.Version":                  "5.10.00.00",
+			"iDRAC.SerialRedirection.Enable":      "Enabled",
+			"iDRAC.Serial.BaudRate":               "115200",
+			"BIOS.SerialCommSettings.SerialComm":  "OnConRedirCom2",
+			"BIOS.SysInformation.SystemModelName": "PowerEdge R640",
+		},
+	}
+}
+
+// Start - listen on a free port and serve ssh in the background.
+func (m *MockIDRAC) Start() error {
+	port, err := freeport.GetFreePort()
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	m.Port = port
+	m.server = &ssh.Server{
+		Handler: m.handle,
+		PasswordHandler: func(ctx ssh.Context, password string) bool {
+			return ctx.User() == IDRACUser && password == IDRACPassword
+		},
+	}
+	go func() {
+		log.Printf("racadm ssh server listening on port %d\n", port)
+		if err := m.server.Serve(ln); err != nil && err != ssh.ErrServerClosed {
+			log.Printf("racadm ssh server on port %d failed: %v\n", port, err)
+		}
+	}()
+	return nil
+}
+
+// Close - stop the ssh server and drop all connections.
+func (m *MockIDRAC) Close() error {
+	return m.server.Close()
+}
+
+// Get - return the power state or an attribute, with the mock locked.
+func (m *MockIDRAC) Get(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "Power" {
+		return m.Power
+	}
+	return m.Attributes[name]
+}
+
+// Set - set the power state or an attribute, with the mock locked.
+func (m *MockIDRAC) Set(name, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "Power" {
+		m.setPower(value)
+		return
+	}
+	m.Attributes[name] = value
+}
+
+func (m *MockIDRAC) handle(s ssh.Session) {
+	in := bufio.NewReader(s)
+	fmt.Fprint(s, racadmPrompt)
+	for {
+		line, err := in.ReadString('\n')
+		if err != nil {
+			s.Exit(1)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		fmt.Fprintf(s, "%s\n", line)
+
+		args := strings.Fields(line)
+		if len(args) > 0 && args[0] == "racadm" {
+			args = args[1:]
+		}
+		switch {
+		case len(args) > 0 && (args[0] == "exit" || args[0] == "quit"):
+			s.Exit(0)
+			return
+		case len(args) > 0 && args[0] == "console":
+			m.attach(s, in, args)
+		default:
+			m.mu.Lock()
+			out := m.exec(args)
+			m.mu.Unlock()
+			fmt.Fprint(s, out)
+		}
+		fmt.Fprint(s, racadmPrompt)
+	}
+}
+
+// exec - run a racadm command, return its output.  m.mu is held.
+func (m *MockIDRAC) exec(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	switch args[0] {
+	case "serveraction":
+		if len(args) != 2 {
+			return "ERROR: Invalid syntax.\nUsage: racadm serveraction <action>\n"
+		}
+		return m.serverAction(args[1])
+	case "getversion":
+		return " Bios Version                     = 2.12.2\n" +
+			" iDRAC Version                    = " + m.Attributes["iDRAC.Info.Version"] + "\n" +
+			" Lifecycle Controller Version     = " + m.Attributes["iDRAC.Info.Version"] + "\n"
+	case "getsysinfo":
+		return "System Information:\n" +
+			"System Model            = " + m.Attributes["BIOS.SysInformation.SystemModelName"] + "\n" +
+			"Service Tag             = 7XK2JN2\n" +
+			"Power Status            = " + m.Power + "\n"
+	case "get":
+		if len(args) != 2 {
+			return "ERROR: Invalid syntax.\nUsage: racadm get <attribute>\n"
+		}
+		if args[1] == "iDRAC.SerialRedirection" {
+			return m.group(args[1])
+		}
+		v, ok := m.Attributes[args[1]]
+		if !ok {
+			return "ERROR: SWC0242 : Incorrect input or invalid object/attribute name.\n"
+		}
+		return fmt.Sprintf("[Key=%s]\n%s=%s\n", m.key(args[1]), args[1][strings.LastIndex(args[1], ".")+1:], v)
+	case "set":
+		if len(args) != 3 {
+			return "ERROR: Invalid syntax.\nUsage: racadm set <attribute> <value>\n"
+		}
+		if _, ok := m.Attributes[args[1]]; !ok {
+			return "ERROR: SWC0242 : Incorrect input or invalid object/attribute name.\n"
+		}
+		m.Attributes[args[1]] = args[2]
+		return fmt.Sprintf("[Key=%s]\nObject value modified successfully\n", m.key(args[1]))
+	}
+	return "ERROR: Invalid subcommand specified.\n"
+}
+
+// key - the key racadm shows for attribute name, like
+// "iDRAC.Embedded.1#SerialRedirection.1".
+func (m *MockIDRAC) key(name string) string {
+	toks := strings.Split(name, ".")
+	if len(toks) < 3 {
+		return name
+	}
+	return fmt.Sprintf("%s.Embedded.1#%s.1", toks[0], toks[1])
+}
+
+// group - 'get' of a group shows all its attributes.
+func (m *MockIDRAC) group(group string) string {
+	names := []string{}
+	for name := range m.Attributes {
+		if strings.HasPrefix(name, group+".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := fmt.Sprintf("[Key=%s.1]\n", strings.Replace(group, ".", ".Embedded.1#", 1))
+	for _, name := range names {
+		out += strings.TrimPrefix(name, group+".") + "=" + m.Attributes[name] + "\n"
+	}
+	return out
+}
+
+func (m *MockIDRAC) serverAction(action string) string {
+	const ok = "Server power operation successful\n"
+	switch action {
+	case "powerstatus":
+		return "Server power status: " + m.Power + "\n"
+	case "powerup":
+		if m.Power == "ON" {
+			return "ERROR: Server is already powered ON.\n"
+		}
+		m.setPower("ON")
+	case "powerdown", "graceshutdown":
+		if m.Power == "OFF" {
+			return "ERROR: Server is already powered OFF.\n"
+		}
+		m.setPower("OFF")
+	case "powercycle", "hardreset":
+		if m.Power == "OFF" {
+			return "ERROR: Unable to perform the requested action because the server is powered OFF.\n"
+		}
+		m.setPower("OFF")
+		m.setPower("ON")
+	default:
+		return "ERROR: Invalid action specified.\n"
+	}
+	return ok
+}
+
+// setPower - power the server on or off.  m.mu is held.
+func (m *MockIDRAC) setPower(state string) {
+	if state == "OFF" {
+		m.host = nil
+	}
+	m.Power = state
+}
+
+// attach - 'console com2' passes what is typed to the host serial console
+// until ctrl-\.
+func (m *MockIDRAC) attach(s ssh.Session, in *bufio.Reader, args []string) {
+	m.mu.Lock()
+	switch {
+	case len(args) != 2 || args[1] != "com2":
+		m.mu.Unlock()
+		fmt.Fprint(s, "ERROR: Invalid syntax.\nUsage: console com2\n")
+		return
+	case m.Attributes["iDRAC.SerialRedirection.Enable"] != "Enabled":
+		m.mu.Unlock()
+		fmt.Fprint(s, "ERROR: Serial redirection is disabled.\n")
+		return
+	}
+	m.console = s
+	m.mu.Unlock()
+
+	fmt.Fprint(s, "Connected to Serial Device 2. To end type: ^\\\r\n")
+	for {
+		b, err := in.ReadByte()
+		if err != nil || b == ctrlBackslash {
+			break
+		}
+		m.mu.Lock()
+		if m.Power == "ON" {
+			m.hostShell().input([]byte{b})
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	m.console = nil
+	m.mu.Unlock()
+	fmt.Fprint(s, "\r\n")
+}
+
+// hostShell - return the shell on the serial console.  m.mu is held.
+func (m *MockIDRAC) hostShell() *hostShell {
+	if m.host == nil {
+		m.host = newHostShell(func(text string) {
+			if m.console != nil {
+				fmt.Fprint(m.console, text)
+			}
+		}, func() {
+			m.setPower("OFF")
+		})
+	}
+	return m.host
+}
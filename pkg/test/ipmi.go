@@ -0,0 +1,75 @@
+package test
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+)
+
+func ipmiScope() *Scope {
+	s := NewScope("ipmi", "IPMI over LAN Settings",
+		prop("enabled", "Enabled", "no", "yes", "no"),
+		&Prop{Name: "encryption-key", Label: "Encryption Key", Value: "0000000000000000000000000000000000000000",
+			Validate: encryptionKey},
+		prop("privilege-level", "Privilege Level Limit", "admin", "readonly", "user", "admin"),
+	)
+	return s
+}
+
+func encryptionKey(key string) error {
+	if _, err := hex.DecodeString(key); err != nil || len(key) > 40 {
+		return errors.New("Encryption key must be up to 40 hex characters")
+	}
+	return nil
+}
+
+// startIPMI - answer RMCP pings and serve IPMI v2.0 RMCP+ sessions on a
+// free udp port while ipmi over lan is enabled.
+func (m *MockCIMC) startIPMI() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return err
+	}
+	m.IPMIPort = conn.LocalAddr().(*net.UDPAddr).Port
+	m.ipmi = conn
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			m.mu.Lock()
+			if m.Root.Child("ipmi").Get("enabled") == "yes" {
+				if reply := rmcpPong(buf[:n]); reply != nil {
+					if _, err := conn.WriteToUDP(reply, addr); err != nil {
+						log.Printf("ipmi reply to %s failed: %v\n", addr, err)
+					}
+				} else {
+					m.ipmiPacket(append([]byte{}, buf[:n]...), addr)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// rmcpPong - the ASF presence pong for ping, nil if ping is not one.
+func rmcpPong(ping []byte) []byte {
+	if len(ping) < 12 || ping[0] != 0x06 || ping[3] != 0x06 || ping[8] != 0x80 {
+		return nil
+	}
+	pong := make([]byte, 28)
+	copy(pong, ping[:8])
+	pong[8] = 0x40
+	pong[9] = ping[9]
+	pong[11] = 16
+	binary.BigEndian.PutUint32(pong[12:16], 4542)
+	// supported entities: ipmi, asf 1.0; interactions: none.
+	pong[20] = 0x81
+	return pong
+}
@@ -0,0 +1,559 @@
+package test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+)
+
+// ipmiGUID - the system guid the mock bmc authenticates with.
+var ipmiGUID = []byte("axepect-mock-bmc")
+
+// ipmiRoles - ipmi privilege levels of the cimc privilege limits and user
+// roles.
+var ipmiRoles = map[string]byte{
+	"readonly":  2,
+	"read-only": 2,
+	"user":      3,
+	"admin":     4,
+}
+
+// ipmiSession - an RMCP+ session of the mock, which only does cipher suite
+// 3: RAKP-HMAC-SHA1, HMAC-SHA1-96 and AES-CBC-128.
+type ipmiSession struct {
+	addr      *net.UDPAddr
+	consoleID uint32
+	bmcID     uint32
+	// role is the byte of RAKP 1, its low bits the privilege level.
+	role   byte
+	user   string
+	kuid   []byte
+	rm, rc []byte
+	// k1 and k2 are set once RAKP 3 checked out.
+	k1, k2 []byte
+	seq    uint32
+	sol    *solHost
+}
+
+// ipmiPacket - handle an ipmi over lan packet, other than an RMCP ping.
+// The caller holds m.mu.
+func (m *MockCIMC) ipmiPacket(pkt []byte, addr *net.UDPAddr) {
+	if len(pkt) < 16 || pkt[0] != 0x06 || pkt[3] != 0x07 {
+		return
+	}
+	if pkt[4] == 0x00 {
+		// ipmi v1.5 without a session, only to ask for capabilities.
+		n := int(pkt[13])
+		if len(pkt) < 14+n || n < 7 {
+			return
+		}
+		msg := pkt[14 : 14+n]
+		if msg[1]>>2 != 0x06 || msg[5] != 0x38 {
+			return
+		}
+		reply := ipmiReply(msg, 0, m.channelAuthCaps())
+		out := append([]byte{0x06, 0, 0xff, 0x07, 0, 0, 0, 0, 0, 0, 0, 0, 0, byte(len(reply))}, reply...)
+		m.ipmi.WriteToUDP(out, addr)
+		return
+	}
+	if pkt[4] != 0x06 {
+		return
+	}
+	ptype := pkt[5]
+	n := int(binary.LittleEndian.Uint16(pkt[14:16]))
+	if len(pkt) < 16+n {
+		return
+	}
+	payload := pkt[16 : 16+n]
+	switch ptype {
+	case 0x10:
+		m.ipmiOpenSession(payload, addr)
+		return
+	case 0x12:
+		m.ipmiRAKP1(payload, addr)
+		return
+	case 0x14:
+		m.ipmiRAKP3(payload, addr)
+		return
+	}
+
+	s := m.ipmiSessions[binary.LittleEndian.Uint32(pkt[6:10])]
+	if s == nil || s.k1 == nil || ptype&0xc0 != 0xc0 || len(pkt) < 16+n+14 {
+		return
+	}
+	if !hmac.Equal(pkt[len(pkt)-12:], hmacSHA1(s.k1, pkt[4:len(pkt)-12])[:12]) {
+		return
+	}
+	data, ok := aesDecrypt(s.k2[:16], payload)
+	if !ok {
+		return
+	}
+	s.addr = addr
+	switch ptype & 0x3f {
+	case 0x00:
+		m.ipmiCommand(s, data)
+	case 0x01:
+		if s.sol != nil {
+			s.sol.receive(data)
+		}
+	}
+}
+
+// channelAuthCaps - the Get Channel Authentication Capabilities data: ipmi
+// v2.0 only, with user names, and whether the bmc key is set.
+func (m *MockCIMC) channelAuthCaps() []byte {
+	flags := byte(0x04)
+	if !bytes.Equal(m.ipmiKey(), make([]byte, 20)) {
+		flags |= 0x20
+	}
+	return []byte{0x01, 0x80, flags, 0x02, 0, 0, 0, 0}
+}
+
+// ipmiKey - the bmc key (kg), padded to 20 bytes.
+func (m *MockCIMC) ipmiKey() []byte {
+	kg, _ := hex.DecodeString(m.Root.Child("ipmi").Get("encryption-key"))
+	return pad20(kg)
+}
+
+func pad20(b []byte) []byte {
+	p := make([]byte, 20)
+	copy(p, b)
+	return p
+}
+
+// ipmiSendSetup - send a session setup payload, in the clear.
+func (m *MockCIMC) ipmiSendSetup(ptype byte, payload []byte, addr *net.UDPAddr) {
+	pkt := []byte{0x06, 0, 0xff, 0x07, 0x06, ptype, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(pkt[14:16], uint16(len(payload)))
+	m.ipmi.WriteToUDP(append(pkt, payload...), addr)
+}
+
+// ipmiSend - send a payload in session s, encrypted and signed.
+func (m *MockCIMC) ipmiSend(s *ipmiSession, ptype byte, payload []byte) error {
+	s.seq++
+	enc := aesEncrypt(s.k2[:16], payload)
+	pkt := []byte{0x06, 0, 0xff, 0x07, 0x06, ptype | 0xc0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(pkt[6:10], s.consoleID)
+	binary.LittleEndian.PutUint32(pkt[10:14], s.seq)
+	binary.LittleEndian.PutUint16(pkt[14:16], uint16(len(enc)))
+	pkt = append(pkt, enc...)
+	pad := (4 - (len(pkt)-4+2)%4) % 4
+	pkt = append(pkt, bytes.Repeat([]byte{0xff}, pad)...)
+	pkt = append(pkt, byte(pad), 0x07)
+	pkt = append(pkt, hmacSHA1(s.k1, pkt[4:])[:12]...)
+	_, err := m.ipmi.WriteToUDP(pkt, s.addr)
+	return err
+}
+
+func (m *MockCIMC) ipmiOpenSession(req []byte, addr *net.UDPAddr) {
+	if len(req) < 32 {
+		return
+	}
+	consoleID := binary.LittleEndian.Uint32(req[4:8])
+	reply := make([]byte, 36)
+	reply[0] = req[0]
+	if req[12] != 0x01 || req[20] != 0x01 || req[28] != 0x01 {
+		reply[1] = 0x11
+		m.ipmiSendSetup(0x11, reply[:8], addr)
+		return
+	}
+	id := make([]byte, 4)
+	rand.Read(id)
+	bmcID := binary.LittleEndian.Uint32(id) | 1
+	m.ipmiSessions[bmcID] = &ipmiSession{addr: addr, consoleID: consoleID, bmcID: bmcID}
+
+	reply[2] = ipmiRoles[m.Root.Child("ipmi").Get("privilege-level")]
+	binary.LittleEndian.PutUint32(reply[4:8], consoleID)
+	binary.LittleEndian.PutUint32(reply[8:12], bmcID)
+	copy(reply[12:], req[8:32])
+	m.ipmiSendSetup(0x11, reply, addr)
+}
+
+func (m *MockCIMC) ipmiRAKP1(req []byte, addr *net.UDPAddr) {
+	if len(req) < 28 || len(req) < 28+int(req[27]) {
+		return
+	}
+	s := m.ipmiSessions[binary.LittleEndian.Uint32(req[4:8])]
+	if s == nil || s.k1 != nil {
+		return
+	}
+	fail := func(status byte) {
+		reply := []byte{req[0], status, 0, 0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(reply[4:8], s.consoleID)
+		m.ipmiSendSetup(0x13, reply, addr)
+		delete(m.ipmiSessions, s.bmcID)
+	}
+
+	s.rm = append([]byte{}, req[8:24]...)
+	s.role = req[24]
+	s.user = string(req[28 : 28+int(req[27])])
+	u := m.ipmiUser(s.user)
+	if u == nil {
+		fail(0x0d)
+		return
+	}
+	limit := ipmiRoles[m.Root.Child("ipmi").Get("privilege-level")]
+	if r := ipmiRoles[u.Get("role")]; r < limit {
+		limit = r
+	}
+	if s.role&0x0f > limit {
+		fail(0x09)
+		return
+	}
+	s.kuid = pad20([]byte(u.Get("password")))
+	s.rc = make([]byte, 16)
+	rand.Read(s.rc)
+
+	reply := make([]byte, 40, 60)
+	reply[0] = req[0]
+	binary.LittleEndian.PutUint32(reply[4:8], s.consoleID)
+	copy(reply[8:24], s.rc)
+	copy(reply[24:40], ipmiGUID)
+	reply = append(reply, hmacSHA1(s.kuid, s.ids(), s.rm, s.rc, ipmiGUID, s.name())...)
+	m.ipmiSendSetup(0x13, reply, addr)
+}
+
+func (m *MockCIMC) ipmiRAKP3(req []byte, addr *net.UDPAddr) {
+	if len(req) < 8 {
+		return
+	}
+	s := m.ipmiSessions[binary.LittleEndian.Uint32(req[4:8])]
+	if s == nil || s.kuid == nil || s.k1 != nil {
+		return
+	}
+	reply := make([]byte, 8, 20)
+	reply[0] = req[0]
+	binary.LittleEndian.PutUint32(reply[4:8], s.consoleID)
+	if req[1] != 0 {
+		// the console gave up.
+		delete(m.ipmiSessions, s.bmcID)
+		return
+	}
+	if len(req) < 28 || !hmac.Equal(req[8:28], hmacSHA1(s.kuid, s.rc, s.ids()[0:4], s.name())) {
+		reply[1] = 0x0f
+		m.ipmiSendSetup(0x15, reply, addr)
+		delete(m.ipmiSessions, s.bmcID)
+		return
+	}
+	kg := m.ipmiKey()
+	if bytes.Equal(kg, make([]byte, 20)) {
+		kg = s.kuid
+	}
+	sik := hmacSHA1(kg, s.rm, s.rc, s.name())
+	s.k1 = hmacSHA1(sik, bytes.Repeat([]byte{1}, 20))
+	s.k2 = hmacSHA1(sik, bytes.Repeat([]byte{2}, 20))
+	reply = append(reply, hmacSHA1(sik, s.rm, s.ids()[4:8], ipmiGUID)[:12]...)
+	m.ipmiSendSetup(0x15, reply, addr)
+}
+
+// ids - the console and bmc session ids, as RAKP signs them.
+func (s *ipmiSession) ids() []byte {
+	ids := make([]byte, 8)
+	binary.LittleEndian.PutUint32(ids[0:4], s.consoleID)
+	binary.LittleEndian.PutUint32(ids[4:8], s.bmcID)
+	return ids
+}
+
+// name - the role, user name length and user name, as RAKP signs them.
+func (s *ipmiSession) name() []byte {
+	return append([]byte{s.role, byte(len(s.user))}, s.user...)
+}
+
+// ipmiUser - the enabled user account with name, or nil.
+func (m *MockCIMC) ipmiUser(name string) *Scope {
+	for _, u := range m.Root.Children {
+		if strings.HasPrefix(u.Name, "user ") && u.Get("name") == name && u.Get("enabled") == "yes" {
+			return u
+		}
+	}
+	return nil
+}
+
+// ipmiCommand - run an ipmi request of session s and send the response.
+func (m *MockCIMC) ipmiCommand(s *ipmiSession, msg []byte) {
+	if len(msg) < 7 {
+		return
+	}
+	netFn, cmd, data := msg[1]>>2, msg[5], msg[6:len(msg)-1]
+	role := s.role & 0x0f
+	cc, resp := byte(0), []byte{}
+	closed := false
+
+	switch {
+	case netFn == 0x06 && cmd == 0x38:
+		resp = m.channelAuthCaps()
+	case netFn == 0x06 && cmd == 0x3c:
+		closed = true
+	case netFn == 0x00 && cmd == 0x01:
+		resp = []byte{0, 0, 0}
+		if m.Root.Child("chassis").Get("Power") == "on" {
+			resp[0] = 0x01
+		}
+	case netFn == 0x00 && cmd == 0x02:
+		cc = m.ipmiChassisControl(role, data)
+		if cc == 0 {
+			m.IPMIChassisControls++
+		}
+		if m.IPMILostReplies > 0 {
+			m.IPMILostReplies--
+			return
+		}
+	case netFn == 0x06 && cmd == 0x48:
+		cc, resp = m.ipmiActivateSOL(s, data)
+	case netFn == 0x06 && cmd == 0x49:
+		if len(data) < 1 || data[0] != 0x01 {
+			cc = 0xcc
+		} else if s.sol == nil {
+			cc = 0x80
+		} else {
+			s.sol.stop()
+			s.sol = nil
+		}
+	default:
+		cc = 0xc1
+	}
+
+	m.ipmiSend(s, 0x00, ipmiReply(msg, cc, resp))
+	if closed {
+		if s.sol != nil {
+			s.sol.stop()
+		}
+		delete(m.ipmiSessions, s.bmcID)
+	}
+}
+
+// ipmiReply - the response message to the request msg.
+func ipmiReply(msg []byte, cc byte, data []byte) []byte {
+	reply := []byte{0x81, (msg[1]>>2 + 1) << 2, 0, 0x20, msg[4], msg[5], cc}
+	reply[2] = checksum(reply[0:2])
+	reply = append(reply, data...)
+	return append(reply, checksum(reply[3:]))
+}
+
+func checksum(b []byte) byte {
+	var c byte
+	for _, x := range b {
+		c += x
+	}
+	return -c
+}
+
+// ipmiChassisControl - power down, up, cycle or hard reset, which need
+// the operator privilege.
+func (m *MockCIMC) ipmiChassisControl(role byte, data []byte) byte {
+	if role < 3 {
+		return 0xd4
+	}
+	if len(data) < 1 {
+		return 0xc7
+	}
+	chassis := m.Root.Child("chassis")
+	was := chassis.Get("Power")
+	switch data[0] {
+	case 0x00, 0x05:
+		chassis.Set("Power", "off")
+	case 0x01:
+		chassis.Set("Power", "on")
+	case 0x02, 0x03:
+		if was != "on" {
+			return 0xd5
+		}
+	default:
+		return 0xcc
+	}
+	if now := chassis.Get("Power"); now != was {
+		m.redfishPowerEvent(now)
+	}
+	return 0
+}
+
+// ipmiActivateSOL - start the sol payload of s, if serial over lan is
+// enabled and no other session has it.
+func (m *MockCIMC) ipmiActivateSOL(s *ipmiSession, data []byte) (byte, []byte) {
+	if len(data) < 2 || data[0] != 0x01 {
+		return 0xcc, nil
+	}
+	if m.Root.Child("sol").Get("enabled") != "yes" {
+		return 0x81, nil
+	}
+	for _, o := range m.ipmiSessions {
+		if o.sol != nil {
+			return 0x80, nil
+		}
+	}
+	s.sol = newSOLHost(m, s)
+	// aux data, in and out payload sizes, port and no vlan.
+	resp := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff}
+	binary.LittleEndian.PutUint16(resp[4:6], solPayloadSize)
+	binary.LittleEndian.PutUint16(resp[6:8], solPayloadSize)
+	binary.LittleEndian.PutUint16(resp[8:10], uint16(m.IPMIPort))
+	return 0, resp
+}
+
+// solPayloadSize - the largest sol payload, with its 4 byte header.
+const solPayloadSize = 128
+
+// solHost - the host end of an active sol payload: a login shell on the
+// serial console.
+type solHost struct {
+	m      *MockCIMC
+	s      *ipmiSession
+	shell  *hostShell
+	out    chan []byte
+	acks   chan byte
+	done   chan struct{}
+	lastRx byte
+}
+
+func newSOLHost(m *MockCIMC, s *ipmiSession) *solHost {
+	h := &solHost{
+		m:    m,
+		s:    s,
+		out:  make(chan []byte, 1024),
+		acks: make(chan byte, 16),
+		done: make(chan struct{}),
+	}
+	h.shell = newHostShell(func(text string) {
+		select {
+		case h.out <- []byte(text):
+		default:
+		}
+	}, func() {
+		if m.Root.Child("chassis").Get("Power") == "on" {
+			m.Root.Child("chassis").Set("Power", "off")
+			m.redfishPowerEvent("off")
+		}
+	})
+	go h.send()
+	return h
+}
+
+// receive - take a sol packet of the console, m.mu is held.
+func (h *solHost) receive(pkt []byte) {
+	if len(pkt) < 4 {
+		return
+	}
+	seq, ack, data := pkt[0], pkt[1], pkt[4:]
+	if ack != 0 {
+		select {
+		case h.acks <- ack:
+		default:
+		}
+	}
+	if seq == 0 {
+		return
+	}
+	if seq != h.lastRx {
+		h.shell.input(data)
+	}
+	h.lastRx = seq
+	h.m.ipmiSend(h.s, 0x01, []byte{0, seq, byte(len(data)), 0})
+}
+
+// send - send what the host says, a packet at a time, until acked.
+func (h *solHost) send() {
+	seq := byte(0)
+	for {
+		var data []byte
+		select {
+		case data = <-h.out:
+		case <-h.done:
+			return
+		}
+	more:
+		for len(data) < solPayloadSize-4 {
+			select {
+			case d := <-h.out:
+				data = append(data, d...)
+			default:
+				break more
+			}
+		}
+		for len(data) > 0 {
+			n := len(data)
+			if n > solPayloadSize-4 {
+				n = solPayloadSize - 4
+			}
+			seq = seq%15 + 1
+			if !h.sendAcked(append([]byte{seq, 0, 0, 0}, data[:n]...)) {
+				return
+			}
+			data = data[n:]
+		}
+	}
+}
+
+func (h *solHost) sendAcked(pkt []byte) bool {
+	for i := 0; i < 10; i++ {
+		h.m.mu.Lock()
+		err := h.m.ipmiSend(h.s, 0x01, pkt)
+		h.m.mu.Unlock()
+		if err != nil {
+			return false
+		}
+		timer := time.NewTimer(200 * time.Millisecond)
+		for waiting := true; waiting; {
+			select {
+			case ack := <-h.acks:
+				if ack == pkt[0] {
+					timer.Stop()
+					return true
+				}
+			case <-timer.C:
+				waiting = false
+			case <-h.done:
+				timer.Stop()
+				return false
+			}
+		}
+	}
+	return false
+}
+
+func (h *solHost) stop() {
+	close(h.done)
+}
+
+func hmacSHA1(key []byte, parts ...[]byte) []byte {
+	h := hmac.New(sha1.New, key)
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+func aesEncrypt(key, data []byte) []byte {
+	block, _ := aes.NewCipher(key)
+	n := (aes.BlockSize - (len(data)+1)%aes.BlockSize) % aes.BlockSize
+	plain := append([]byte{}, data...)
+	for i := 1; i <= n; i++ {
+		plain = append(plain, byte(i))
+	}
+	plain = append(plain, byte(n))
+	out := make([]byte, aes.BlockSize+len(plain))
+	rand.Read(out[:aes.BlockSize])
+	cipher.NewCBCEncrypter(block, out[:aes.BlockSize]).CryptBlocks(out[aes.BlockSize:], plain)
+	return out
+}
+
+func aesDecrypt(key, data []byte) ([]byte, bool) {
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, false
+	}
+	block, _ := aes.NewCipher(key)
+	plain := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(plain, data[aes.BlockSize:])
+	n := int(plain[len(plain)-1])
+	if n >= len(plain) {
+		return nil, false
+	}
+	return plain[:len(plain)-1-n], true
+}
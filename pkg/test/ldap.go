@@ -0,0 +1,182 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// maxLDAPGroups - the number of ldap role group slots.
+const maxLDAPGroups = 28
+
+// directories - the running Directory stand-ins by address.
+var directories = struct {
+	sync.Mutex
+	byAddr map[string]*Directory
+}{byAddr: map[string]*Directory{}}
+
+// Directory - a stand-in for an ldap server.
+//   It listens on a local port, so its Addr can be configured as an ldap
+//   server of the mock cimc, but it does not speak ldap: the mock cimc
+//   looks it up by address and asks it directly.
+type Directory struct {
+	Addr   string
+	BaseDN string
+	// BindDN and BindPassword are the credentials the cimc must bind with
+	//   if its bind method is configured-credentials.
+	BindDN       string
+	BindPassword string
+
+	mu    sync.Mutex
+	ln    net.Listener
+	users map[string]dirUser
+}
+
+type dirUser struct {
+	password string
+	groups   []string
+}
+
+// NewDirectory - start an empty directory for baseDN on a local port.
+func NewDirectory(baseDN string) (*Directory, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	d := &Directory{Addr: ln.Addr().String(), BaseDN: baseDN, ln: ln, users: map[string]dirUser{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	directories.Lock()
+	directories.byAddr[d.Addr] = d
+	directories.Unlock()
+	return d, nil
+}
+
+// AddUser - add a user with a password, member of groups.
+func (d *Directory) AddUser(name, password string, groups ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.users[name] = dirUser{password: password, groups: groups}
+}
+
+// Close - stop the directory.
+func (d *Directory) Close() error {
+	directories.Lock()
+	delete(directories.byAddr, d.Addr)
+	directories.Unlock()
+	return d.ln.Close()
+}
+
+// authenticate - check a login against the directory, like the cimc does
+// with the settings of s, the /ldap scope.  Returns the groups of the user.
+func (d *Directory) authenticate(s *Scope, name, password string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if s.Get("base-dn") != d.BaseDN {
+		return nil, fmt.Errorf("no such base dn '%s'", s.Get("base-dn"))
+	}
+	switch s.Get("bind-method") {
+	case "anonymous":
+		if d.BindDN != "" {
+			return nil, errors.New("anonymous bind refused")
+		}
+	case "configured-credentials":
+		if s.Get("bind-dn") != d.BindDN || s.Get("password") != d.BindPassword {
+			return nil, errors.New("invalid bind credentials")
+		}
+	}
+	u, ok := d.users[name]
+	if !ok || u.password != password {
+		return nil, errors.New("invalid credentials")
+	}
+	return u.groups, nil
+}
+
+func ldapScope() *Scope {
+	yesNo := []string{"yes", "no"}
+	s := NewScope("ldap", "LDAP Settings",
+		prop("enabled", "Enabled", "no", yesNo...),
+		prop("domain", "Domain", ""),
+		prop("base-dn", "BaseDN", ""),
+		prop("timeout", "Timeout", "60"),
+		prop("encrypted", "Encrypted", "no", yesNo...),
+		prop("filter", "Filter", "sAMAccountName"),
+		prop("group-attribute", "Group Attribute", "memberOf"),
+		prop("group-auth", "Group Authorization", "no", yesNo...),
+		prop("bind-method", "Bind Method", "login-credentials",
+			"anonymous", "configured-credentials", "login-credentials"),
+		prop("bind-dn", "Bind DN", ""),
+		&Prop{Name: "password", Secret: true},
+	)
+
+	servers := NewScope("ldap-server", "LDAP Servers")
+	for i := 1; i <= 6; i++ {
+		servers.Props = append(servers.Props,
+			prop(fmt.Sprintf("ldap-server-%d", i), fmt.Sprintf("LDAP Server %d", i), ""),
+			prop(fmt.Sprintf("ldap-server-%d-port", i), fmt.Sprintf("LDAP Server %d Port", i), "389"))
+	}
+	s.Add(servers)
+
+	for i := 1; i <= maxLDAPGroups; i++ {
+		s.Add(NewScope(fmt.Sprintf("role-group %d", i), fmt.Sprintf("Role Group %d", i),
+			prop("name", "Name", ""),
+			prop("domain", "Domain", ""),
+			prop("role", "Role", "read-only", "admin", "user", "read-only"),
+		))
+	}
+	return s
+}
+
+// ldapLogin - check a login against the directories configured in /ldap.
+//   Users may log in as name or name@domain.  With group authorization,
+//   the user must be in a group mapped by a role group.
+func (m *MockCIMC) ldapLogin(name, password string) bool {
+	s := m.Root.Child("ldap")
+	if s == nil || s.Get("enabled") != "yes" {
+		return false
+	}
+	name = strings.TrimSuffix(name, "@"+s.Get("domain"))
+
+	servers := s.Child("ldap-server")
+	for i := 1; i <= 6; i++ {
+		host := servers.Get(fmt.Sprintf("ldap-server-%d", i))
+		if host == "" {
+			continue
+		}
+		port := servers.Get(fmt.Sprintf("ldap-server-%d-port", i))
+		directories.Lock()
+		d := directories.byAddr[net.JoinHostPort(host, port)]
+		directories.Unlock()
+		if d == nil {
+			continue
+		}
+
+		groups, err := d.authenticate(s, name, password)
+		if err != nil {
+			return false
+		}
+		if s.Get("group-auth") != "yes" {
+			return true
+		}
+		for _, g := range groups {
+			for _, rg := range s.Children {
+				if strings.HasPrefix(rg.Name, "role-group ") && rg.Get("name") == g &&
+					(rg.Get("domain") == "" || rg.Get("domain") == s.Get("domain")) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package test
+
+// disruptive - network settings that drop connections to the cimc.
+var disruptive = []string{
+	"dhcp-enabled", "v4-addr", "v4-netmask", "v4-gateway",
+	"v6-enabled", "v6-dhcp-enabled", "v6-addr", "v6-prefix", "v6-gateway",
+	"vlan-enabled", "vlan-id", "mode", "redundancy",
+}
+
+func networkScope() *Scope {
+	yesNo := []string{"yes", "no"}
+	s := NewScope("network", "Network Setting",
+		prop("v4-addr", "IPv4 Address", "127.0.0.1"),
+		prop("v4-netmask", "IPv4 Netmask", "255.0.0.0"),
+		prop("v4-gateway", "IPv4 Gateway", "127.0.0.254"),
+		prop("dhcp-enabled", "DHCP Enabled", "no", yesNo...),
+		ro("DDNS Enabled", "yes"),
+		prop("dns-use-dhcp", "Obtain DNS Server by DHCP", "no", yesNo...),
+		prop("preferred-dns-server", "Preferred DNS", "0.0.0.0"),
+		prop("alternate-dns-server", "Alternate DNS", "0.0.0.0"),
+		prop("v6-enabled", "IPv6 Enabled", "no", yesNo...),
+		prop("v6-addr", "IPv6 Address", "::"),
+		prop("v6-prefix", "IPv6 Prefix", "64"),
+		prop("v6-gateway", "IPv6 Gateway", "::"),
+		ro("IPv6 Link Local", "::"),
+		prop("v6-dhcp-enabled", "IPV6 DHCP Enabled", "no", yesNo...),
+		prop("vlan-enabled", "VLAN Enabled", "no", yesNo...),
+		prop("vlan-id", "VLAN ID", "1"),
+		prop("vlan-priority", "VLAN Priority", "0"),
+		prop("hostname", "Hostname", "C220-WZP2326007Q"),
+		ro("MAC Address", "70:0F:6A:D4:3B:12"),
+		prop("mode", "NIC Mode", "dedicated",
+			"dedicated", "shared_lom", "shared_lom_10g", "shared_lom_ext", "cisco_card"),
+		prop("redundancy", "NIC Redundancy", "none", "none", "active-active", "active-standby"),
+		ro("Auto Negotiate", "yes"),
+	)
+
+	s.OnCommit = func(t *Term, changed map[string]string) {
+		for _, name := range disruptive {
+			if _, ok := changed[name]; ok {
+				t.Confirm("Changes to the network settings will be applied immediately.\n" +
+					"You may lose connectivity to the Cisco IMC and may have to log in again.")
+				t.Hangup()
+				return
+			}
+		}
+	}
+	s.Add(NewScope("ntp", "NTP Service Settings",
+		prop("enabled", "NTP Enabled", "no", yesNo...),
+		prop("server-1", "NTP Server 1", ""),
+		prop("server-2", "NTP Server 2", ""),
+		prop("server-3", "NTP Server 3", ""),
+		prop("server-4", "NTP Server 4", ""),
+		ro("Status", "unsynchronised"),
+	))
+	return s
+}
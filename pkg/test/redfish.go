@@ -0,0 +1,347 @@
+package test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// redfishBootTargets - redfish boot override targets, to the bios
+// one-time-boot-device they stand for.
+var redfishBootTargets = map[string]string{
+	"None":      "",
+	"Pxe":       "PXE",
+	"Hdd":       "HDD",
+	"Cd":        "CIMC-Mapped-vDVD",
+	"UefiShell": "EFI",
+}
+
+func redfishScope(m *MockCIMC) *Scope {
+	s := NewScope("redfish", "REDFISH Settings",
+		prop("enabled", "Enabled", "no", "yes", "no"),
+		ro("Active Sessions", "0"),
+		ro("Max Sessions", "4"),
+		ro("Port", ""),
+		ro("Session Timeout", "1800"),
+	)
+	s.OnShow = func(s *Scope) {
+		s.Set("Active Sessions", fmt.Sprint(len(m.rfSessions)))
+		s.Set("Port", fmt.Sprint(m.HTTPSPort))
+	}
+	// turning redfish off ends its sessions.
+	s.OnCommit = func(t *Term, changed map[string]string) {
+		if changed["enabled"] == "no" {
+			m.rfSessions = map[string]string{}
+		}
+	}
+	return s
+}
+
+// RedfishHandler - the redfish api of the mock, as served on HTTPSPort.
+//   It is there once 'enabled' is set in the redfish scope, and supports
+//   sessions, the computer system with power actions and boot override,
+//   the firmware inventory and event subscriptions.
+func (m *MockCIMC) RedfishHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if m.Root.Child("redfish").Get("enabled") != "yes" {
+			redfishError(w, http.StatusServiceUnavailable, "Redfish service is disabled")
+			return
+		}
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		if path == "/redfish/v1" {
+			redfishReply(w, http.StatusOK, m.redfishRoot())
+			return
+		}
+		if path == "/redfish/v1/SessionService/Sessions" && r.Method == http.MethodPost {
+			m.redfishLogin(w, r)
+			return
+		}
+		if _, ok := m.rfSessions[r.Header.Get("X-Auth-Token")]; !ok {
+			redfishError(w, http.StatusUnauthorized, "No valid session established with the service")
+			return
+		}
+		m.redfishServe(w, r, path)
+	})
+}
+
+func (m *MockCIMC) systemPath() string {
+	return "/redfish/v1/Systems/" + m.Root.Child("chassis").Get("Serial Number")
+}
+
+func (m *MockCIMC) redfishRoot() map[string]interface{} {
+	return map[string]interface{}{
+		"@odata.id":      "/redfish/v1",
+		"Id":             "RootService",
+		"RedfishVersion": "1.2.0",
+		"Systems":        odataID("/redfish/v1/Systems"),
+		"SessionService": odataID("/redfish/v1/SessionService"),
+		"UpdateService":  odataID("/redfish/v1/UpdateService"),
+		"EventService":   odataID("/redfish/v1/EventService"),
+		"Links":          map[string]interface{}{"Sessions": odataID("/redfish/v1/SessionService/Sessions")},
+	}
+}
+
+// redfishLogin - create a session for the user and password in the body.
+func (m *MockCIMC) redfishLogin(w http.ResponseWriter, r *http.Request) {
+	creds := struct{ UserName, Password string }{}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		redfishError(w, http.StatusBadRequest, "Malformed JSON: "+err.Error())
+		return
+	}
+	if !m.login(creds.UserName, creds.Password) {
+		redfishError(w, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+	if len(m.rfSessions) >= atoi(m.Root.Child("redfish").Get("Max Sessions")) {
+		redfishError(w, http.StatusServiceUnavailable, "Maximum number of sessions reached")
+		return
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+	m.rfNextID++
+	id := fmt.Sprint(m.rfNextID)
+	m.rfSessions[token] = id
+
+	loc := "/redfish/v1/SessionService/Sessions/" + id
+	w.Header().Set("X-Auth-Token", token)
+	w.Header().Set("Location", loc)
+	redfishReply(w, http.StatusCreated, map[string]interface{}{
+		"@odata.id": loc, "Id": id, "UserName": creds.UserName,
+	})
+}
+
+func (m *MockCIMC) redfishServe(w http.ResponseWriter, r *http.Request, path string) {
+	system := m.systemPath()
+	sessions := "/redfish/v1/SessionService/Sessions"
+	inventory := "/redfish/v1/UpdateService/FirmwareInventory"
+
+	if m.redfishEventService(w, r, path) {
+		return
+	}
+	switch {
+	case path == "/redfish/v1/SessionService" && r.Method == http.MethodGet:
+		redfishReply(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":      path,
+			"ServiceEnabled": true,
+			"SessionTimeout": atoi(m.Root.Child("redfish").Get("Session Timeout")),
+			"Sessions":       odataID(sessions),
+		})
+	case path == sessions && r.Method == http.MethodGet:
+		members := []string{}
+		for _, id := range m.rfSessions {
+			members = append(members, sessions+"/"+id)
+		}
+		redfishReply(w, http.StatusOK, collection(path, members))
+	case strings.HasPrefix(path, sessions+"/") && r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, sessions+"/")
+		for token, sid := range m.rfSessions {
+			if sid == id {
+				delete(m.rfSessions, token)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		redfishError(w, http.StatusNotFound, "No session "+id)
+	case path == "/redfish/v1/Systems" && r.Method == http.MethodGet:
+		redfishReply(w, http.StatusOK, collection(path, []string{system}))
+	case path == system && r.Method == http.MethodGet:
+		redfishReply(w, http.StatusOK, m.redfishSystem())
+	case path == system && r.Method == http.MethodPatch:
+		m.redfishPatchSystem(w, r)
+	case path == system+"/Actions/ComputerSystem.Reset" && r.Method == http.MethodPost:
+		m.redfishReset(w, r)
+	case path == inventory && r.Method == http.MethodGet:
+		members := []string{}
+		for _, fw := range m.redfishFirmware() {
+			members = append(members, fw["@odata.id"].(string))
+		}
+		redfishReply(w, http.StatusOK, collection(path, members))
+	case strings.HasPrefix(path, inventory+"/") && r.Method == http.MethodGet:
+		for _, fw := range m.redfishFirmware() {
+			if fw["@odata.id"] == path {
+				redfishReply(w, http.StatusOK, fw)
+				return
+			}
+		}
+		redfishError(w, http.StatusNotFound, "No resource "+path)
+	default:
+		redfishError(w, http.StatusNotFound, fmt.Sprintf("No resource %s for %s", path, r.Method))
+	}
+}
+
+func (m *MockCIMC) redfishSystem() map[string]interface{} {
+	chassis, bios := m.Root.Child("chassis"), m.Root.Child("bios")
+	target, enabled := "None", "Disabled"
+	for t, dev := range redfishBootTargets {
+		if dev != "" && dev == bios.Get("one-time-boot-device") {
+			target, enabled = t, "Once"
+		}
+	}
+	targets := []string{}
+	for t := range redfishBootTargets {
+		targets = append(targets, t)
+	}
+	return map[string]interface{}{
+		"@odata.id":    m.systemPath(),
+		"Id":           chassis.Get("Serial Number"),
+		"Manufacturer": "Cisco Systems Inc",
+		"Model":        chassis.Get("Product Name"),
+		"SKU":          chassis.Get("PID "),
+		"SerialNumber": chassis.Get("Serial Number"),
+		"UUID":         chassis.Get("UUID"),
+		"BiosVersion":  bios.Get("BIOS Version"),
+		"PowerState":   map[string]string{"on": "On", "off": "Off"}[chassis.Get("Power")],
+		"ProcessorSummary": map[string]interface{}{
+			"Count": 2, "Model": "Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz",
+		},
+		"MemorySummary": map[string]interface{}{"TotalSystemMemoryGiB": 384},
+		"Boot": map[string]interface{}{
+			"BootSourceOverrideEnabled":                        enabled,
+			"BootSourceOverrideTarget":                         target,
+			"BootSourceOverrideTarget@Redfish.AllowableValues": targets,
+		},
+		"Actions": map[string]interface{}{
+			"#ComputerSystem.Reset": map[string]interface{}{
+				"target": m.systemPath() + "/Actions/ComputerSystem.Reset",
+				"ResetType@Redfish.AllowableValues": []string{
+					"On", "ForceOff", "GracefulShutdown", "ForceRestart", "PowerCycle",
+				},
+			},
+		},
+	}
+}
+
+// redfishPatchSystem - set the one time boot override.
+func (m *MockCIMC) redfishPatchSystem(w http.ResponseWriter, r *http.Request) {
+	patch := struct {
+		Boot struct{ BootSourceOverrideEnabled, BootSourceOverrideTarget string }
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		redfishError(w, http.StatusBadRequest, "Malformed JSON: "+err.Error())
+		return
+	}
+	dev, ok := redfishBootTargets[patch.Boot.BootSourceOverrideTarget]
+	if !ok {
+		redfishError(w, http.StatusBadRequest, fmt.Sprintf("The value %s for BootSourceOverrideTarget is not in the list of acceptable values",
+			patch.Boot.BootSourceOverrideTarget))
+		return
+	}
+	if patch.Boot.BootSourceOverrideEnabled == "Disabled" {
+		dev = ""
+	} else if patch.Boot.BootSourceOverrideEnabled != "Once" {
+		redfishError(w, http.StatusBadRequest, "Only a one time boot override is supported")
+		return
+	}
+	m.Root.Child("bios").Set("one-time-boot-device", dev)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *MockCIMC) redfishReset(w http.ResponseWriter, r *http.Request) {
+	action := struct{ ResetType string }{}
+	if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+		redfishError(w, http.StatusBadRequest, "Malformed JSON: "+err.Error())
+		return
+	}
+	chassis := m.Root.Child("chassis")
+	was := chassis.Get("Power")
+	switch action.ResetType {
+	case "On":
+		chassis.Set("Power", "on")
+	case "ForceOff", "GracefulShutdown":
+		chassis.Set("Power", "off")
+	case "ForceRestart", "PowerCycle":
+		if chassis.Get("Power") != "on" {
+			redfishError(w, http.StatusConflict, "Server is powered off")
+			return
+		}
+	default:
+		redfishError(w, http.StatusBadRequest, fmt.Sprintf("The value %s for ResetType is not in the list of acceptable values",
+			action.ResetType))
+		return
+	}
+	if now := chassis.Get("Power"); now != was {
+		m.redfishPowerEvent(now)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *MockCIMC) redfishFirmware() []map[string]interface{} {
+	inventory := "/redfish/v1/UpdateService/FirmwareInventory/"
+	chassis := m.Root.Child("chassis")
+	fw := func(id, name, version, related string) map[string]interface{} {
+		return map[string]interface{}{
+			"@odata.id":   inventory + id,
+			"Id":          id,
+			"Name":        name,
+			"Version":     version,
+			"Updateable":  true,
+			"RelatedItem": []interface{}{odataID(related)},
+		}
+	}
+	fws := []map[string]interface{}{
+		fw("CIMC", "Cisco IMC", m.Root.Child("cimc").Get("Firmware Version"), "/redfish/v1/Managers/CIMC"),
+		fw("BIOS", "BIOS", m.Root.Child("bios").Get("BIOS Version"), m.systemPath()),
+	}
+	for _, c := range chassis.Children {
+		slot := strings.TrimPrefix(strings.TrimPrefix(c.Name, "adapter "), "storageadapter ")
+		switch {
+		case strings.HasPrefix(c.Name, "adapter "):
+			fws = append(fws, fw("slot-"+slot, c.Get("Product Name"), c.Get("Current FW Version"),
+				"/redfish/v1/Chassis/1/NetworkAdapters/"+slot))
+		case strings.HasPrefix(c.Name, "storageadapter "):
+			fws = append(fws, fw("slot-"+slot, c.Get("Product Name"), c.Get("Firmware Package Build"),
+				m.systemPath()+"/Storage/"+slot))
+		}
+	}
+	return fws
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func odataID(path string) map[string]interface{} {
+	return map[string]interface{}{"@odata.id": path}
+}
+
+func collection(path string, members []string) map[string]interface{} {
+	ids := []interface{}{}
+	for _, p := range members {
+		ids = append(ids, odataID(p))
+	}
+	return map[string]interface{}{
+		"@odata.id":           path,
+		"Members":             ids,
+		"Members@odata.count": len(ids),
+	}
+}
+
+func redfishReply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("OData-Version", "4.0")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// redfishError - reply with a redfish error message.
+func redfishError(w http.ResponseWriter, status int, msg string) {
+	redfishReply(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    "Base.1.4.GeneralError",
+			"message": "A general error has occurred. See ExtendedInfo for more information.",
+			"@Message.ExtendedInfo": []interface{}{
+				map[string]interface{}{"MessageId": "Base.1.4.GeneralError", "Message": msg},
+			},
+		},
+	})
+}
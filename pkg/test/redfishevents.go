@@ -0,0 +1,169 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redfishEventTypes - the event types a subscription can ask for.
+var redfishEventTypes = []string{"StatusChange", "ResourceUpdated", "ResourceAdded", "ResourceRemoved", "Alert"}
+
+// rfSubscription - a redfish event subscription.
+type rfSubscription struct {
+	Destination string
+	EventTypes  []string
+	Context     string
+	Protocol    string
+}
+
+const subscriptions = "/redfish/v1/EventService/Subscriptions"
+
+// redfishEventService - serve the event service and its subscriptions,
+// return false if path is not one of them.
+func (m *MockCIMC) redfishEventService(w http.ResponseWriter, r *http.Request, path string) bool {
+	switch {
+	case path == "/redfish/v1/EventService" && r.Method == http.MethodGet:
+		redfishReply(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":                 path,
+			"ServiceEnabled":            true,
+			"EventTypesForSubscription": redfishEventTypes,
+			"Subscriptions":             odataID(subscriptions),
+		})
+	case path == subscriptions && r.Method == http.MethodGet:
+		members := []string{}
+		for id := range m.rfSubs {
+			members = append(members, subscriptions+"/"+id)
+		}
+		redfishReply(w, http.StatusOK, collection(path, members))
+	case path == subscriptions && r.Method == http.MethodPost:
+		m.redfishSubscribe(w, r)
+	case strings.HasPrefix(path, subscriptions+"/") && r.Method == http.MethodGet:
+		id := strings.TrimPrefix(path, subscriptions+"/")
+		sub, ok := m.rfSubs[id]
+		if !ok {
+			redfishError(w, http.StatusNotFound, "No subscription "+id)
+			return true
+		}
+		redfishReply(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":   path,
+			"Id":          id,
+			"Destination": sub.Destination,
+			"EventTypes":  sub.EventTypes,
+			"Context":     sub.Context,
+			"Protocol":    sub.Protocol,
+		})
+	case strings.HasPrefix(path, subscriptions+"/") && r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, subscriptions+"/")
+		if _, ok := m.rfSubs[id]; !ok {
+			redfishError(w, http.StatusNotFound, "No subscription "+id)
+			return true
+		}
+		delete(m.rfSubs, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *MockCIMC) redfishSubscribe(w http.ResponseWriter, r *http.Request) {
+	sub := rfSubscription{}
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		redfishError(w, http.StatusBadRequest, "Malformed JSON: "+err.Error())
+		return
+	}
+	if u, err := url.Parse(sub.Destination); err != nil || u.Host == "" ||
+		u.Scheme != "http" && u.Scheme != "https" {
+		redfishError(w, http.StatusBadRequest, fmt.Sprintf("The value %s for Destination is not valid", sub.Destination))
+		return
+	}
+	if sub.Protocol == "" {
+		sub.Protocol = "Redfish"
+	}
+	if sub.Protocol != "Redfish" {
+		redfishError(w, http.StatusBadRequest, fmt.Sprintf("The value %s for Protocol is not supported", sub.Protocol))
+		return
+	}
+	if len(sub.EventTypes) == 0 {
+		sub.EventTypes = redfishEventTypes
+	}
+	for _, t := range sub.EventTypes {
+		if !containsString(redfishEventTypes, t) {
+			redfishError(w, http.StatusBadRequest, fmt.Sprintf("The value %s for EventTypes is not in the list of acceptable values", t))
+			return
+		}
+	}
+
+	m.rfNextID++
+	id := fmt.Sprint(m.rfNextID)
+	m.rfSubs[id] = sub
+	w.Header().Set("Location", subscriptions+"/"+id)
+	redfishReply(w, http.StatusCreated, map[string]interface{}{"@odata.id": subscriptions + "/" + id, "Id": id})
+}
+
+// RedfishAlert - post an Alert event, like a fault of the server, to the
+// redfish event subscribers.
+func (m *MockCIMC) RedfishAlert(severity, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.redfishEvent("Alert", severity, "Alert.1.0.Fault", message, m.systemPath())
+}
+
+// redfishEvent - post an event to the subscribers of its type, if redfish
+// is enabled.  The caller holds m.mu, the posts run in the background, so
+// a subscriber may see events out of order.
+func (m *MockCIMC) redfishEvent(typ, severity, msgID, message, origin string) {
+	if m.Root.Child("redfish").Get("enabled") != "yes" {
+		return
+	}
+	m.rfEventID++
+	event := map[string]interface{}{
+		"EventType":         typ,
+		"EventId":           fmt.Sprint(m.rfEventID),
+		"Severity":          severity,
+		"Message":           message,
+		"MessageId":         msgID,
+		"EventTimestamp":    time.Now().UTC().Format(time.RFC3339),
+		"OriginOfCondition": odataID(origin),
+	}
+	for _, sub := range m.rfSubs {
+		if !containsString(sub.EventTypes, typ) {
+			continue
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"@odata.type": "#Event.v1_3_0.Event",
+			"Id":          fmt.Sprint(m.rfEventID),
+			"Name":        "Event Array",
+			"Context":     sub.Context,
+			"Events":      []interface{}{event},
+		})
+		go func(dest string) {
+			client := http.Client{Timeout: 5 * time.Second}
+			resp, err := client.Post(dest, "application/json", bytes.NewReader(body))
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+		}(sub.Destination)
+	}
+}
+
+// redfishPowerEvent - post the StatusChange event of a power change.
+func (m *MockCIMC) redfishPowerEvent(state string) {
+	m.redfishEvent("StatusChange", "OK", "ResourceEvent.1.0.ResourceChanged",
+		fmt.Sprintf("The power state of the server changed to %s", state), m.systemPath())
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"strings"
+)
+
+// rebootCIMC - '/cimc/reboot', which asks for confirmation and drops all
+// connections.
+func rebootCIMC(t *Term, args []string) {
+	if !t.Confirm("This operation will reboot the Cisco IMC.") {
+		return
+	}
+	t.Printf("Cisco IMC is rebooting.\n")
+	t.Reboot()
+}
+
+// factoryDefault - '/cimc/factory-default', which asks for confirmation,
+// resets every scope to the default and reboots.  Only the first of Users,
+// admin, is left.
+func factoryDefault(t *Term, args []string) {
+	if !t.Confirm("This operation will reset the Cisco IMC configuration to factory default.\nAll your configuration will be lost.") {
+		return
+	}
+	m := t.Mock
+	m.Root = defaultRoot(m)
+	for _, s := range m.Root.Children {
+		if strings.HasPrefix(s.Name, "user ") && s.Get("name") != Users[0][0] {
+			s.Set("name", "")
+			s.Set("password", "")
+			s.Set("role", "read-only")
+			s.Set("enabled", "no")
+		}
+	}
+	t.Printf("Cisco IMC configuration reset to factory default, Cisco IMC is rebooting.\n")
+	t.Reboot()
+}
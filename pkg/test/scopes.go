@@ -0,0 +1,108 @@
+package test
+
+import (
+	"time"
+)
+
+// The default scopes of the mock cimc.  Property labels follow what a
+// UCS C-series CIMC (4.x) prints for 'show detail'.
+
+func chassisScope() *Scope {
+	s := NewScope("chassis", "Chassis",
+		ro("Power", "on"),
+		ro("Serial Number", "WZP2326007Q"),
+		ro("Product Name", "UCS C220 M5SX"),
+		ro("PID ", "UCSC-C220-M5SX"),
+		ro("UUID", "13AA6335-143A-4FBE-AD2D-20487959A59B"),
+		prop("locator-led", "Locator LED", "off", "on", "off"),
+		prop("description", "Description", ""),
+		prop("asset-tag", "Asset Tag", "Unknown"),
+	)
+	s.Commands["power"] = power
+
+	s.Add(
+		NewScope("adapter MLOM", "PCI Slot MLOM",
+			ro("Product Name", "UCS VIC 1457"),
+			ro("Serial Number", "FCH233770L7"),
+			ro("Product ID", "UCSC-MLOM-C25Q-04"),
+			ro("Vendor", "Cisco Systems Inc"),
+			ro("Current FW Version", "5.1(2d)"),
+			ro("Bootloader Version", "5.1(2d)"),
+			ro("FW Image 1 Version", "5.1(2d)"),
+			ro("FW Image 1 State", "RUNNING ACTIVATED"),
+			ro("FW Image 2 Version", "5.1(1f)"),
+			ro("FW Image 2 State", "BACKUP INACTIVATED"),
+			ro("FW Update Status", "Idle"),
+		),
+		storageScope(),
+	)
+	addVNICs(s.Child("adapter MLOM"))
+	return s
+}
+
+// power - '/chassis/power <op>', which asks for confirmation.
+func power(t *Term, args []string) {
+	states := map[string]string{
+		"on":         "on",
+		"off":        "off",
+		"shutdown":   "off",
+		"cycle":      "on",
+		"hard-reset": "on",
+	}
+	if len(args) != 2 {
+		t.Errorf("Usage: power on|off|shutdown|cycle|hard-reset")
+		return
+	}
+	state, ok := states[args[1]]
+	if !ok {
+		t.Errorf("Invalid power operation '%s'", args[1])
+		return
+	}
+	if !t.Confirm("This operation will change the server's power state.") {
+		return
+	}
+	if t.Scope.Get("Power") != state {
+		t.Scope.Set("Power", state)
+		t.Mock.redfishPowerEvent(state)
+	}
+}
+
+func cimcScope(m *MockCIMC) *Scope {
+	s := NewScope("cimc", "Cisco IMC",
+		ro("Firmware Version", "4.1(2f)"),
+		ro("Current Time (UTC)", ""),
+		ro("Boot-loader Version", "4.1(2f).36"),
+		prop("description", "Description", ""),
+	)
+	s.OnShow = func(s *Scope) {
+		s.Set("Current Time (UTC)", time.Now().UTC().Add(m.ClockOffset).Format(time.ANSIC))
+	}
+	s.Commands["reboot"] = rebootCIMC
+	s.Commands["factory-default"] = factoryDefault
+	s.Add(cimcFirmwareScope(), networkScope(), logScope(), techSupportScope(m), importExportScope(m))
+	return s
+}
+
+func timeScope() *Scope {
+	return NewScope("time", "Time",
+		prop("timezone", "Timezone", "Etc/UTC"),
+	)
+}
+
+func biosScope() *Scope {
+	s := NewScope("bios", "BIOS",
+		ro("BIOS Version", "C220M5.4.1.2a.0.0613200524"),
+		ro("Backup BIOS Version", "C220M5.4.1.1c.0.0202200518"),
+		ro("Boot Order", "(none)"),
+		ro("FW Update/Recovery Status", "None, OK"),
+		ro("FW Update/Recovery Progress", "100"),
+		ro("UEFI Secure Boot", "disabled"),
+		ro("Configured Boot Mode", "Uefi"),
+		ro("Actual Boot Mode", "Uefi"),
+		prop("one-time-boot-device", "One time boot device", "",
+			"", "CIMC-Mapped-vDVD", "KVM-Mapped-vDVD", "PXE", "HDD", "EFI"),
+	)
+	addBiosUpdate(s)
+	addBiosTokens(s)
+	return s
+}
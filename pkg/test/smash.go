@@ -0,0 +1,339 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/phayes/freeport"
+)
+
+// SMASHUser and SMASHPassword - the login of the mock SMASH-CLP bmc.
+const (
+	SMASHUser     = "Administrator"
+	SMASHPassword = "password"
+)
+
+const esc = 0x1b
+
+// MockSMASH - a simulated DMTF SMASH-CLP command line, like that of HPE
+// iLO, served over ssh.
+//   It knows cd, show, set, start, stop, reset and exit on Targets, and
+//   'vsp', which attaches to the host serial console until 'ESC ('.
+//   /system1 is the host, its enabledstate is its power.
+type MockSMASH struct {
+	Port int
+	// Name shows in the prompt with the current target, like
+	// '</system1>hpiLO-> '.  If empty the prompt is just '-> '.
+	Name string
+	// Targets are the properties of each target, by path.
+	Targets map[string]map[string]string
+
+	mu     sync.Mutex
+	server *ssh.Server
+	// host is the shell on the serial console, it starts again at the
+	// login prompt when the power goes off or the system is reset.
+	host *hostShell
+	// console is where the host writes, the session on 'vsp', if any.
+	console io.Writer
+}
+
+// NewMockSMASH - return a MockSMASH of an iLO, with /system1 powered on.
+func NewMockSMASH() *MockSMASH {
+	return &MockSMASH{
+		Name: "hpiLO",
+		Targets: map[string]map[string]string{
+			"/system1": {
+				"name":         "ProLiant DL360 Gen10",
+				"number":       "MXQ8290JKL",
+				"enabledstate": "enabled",
+			},
+			"/system1/cpu1":   {"name": "Intel(R) Xeon(R) Gold 6130 CPU @ 2.10GHz", "number_cores": "16"},
+			"/map1":           {"name": "iLO 5"},
+			"/map1/firmware1": {"version": "2.44", "date": "Apr 22 2021"},
+		},
+	}
+}
+
+// Start - listen on a free port and serve ssh in the background.
+func (m *MockSMASH) Start() error {
+	port, err := freeport.GetFreePort()
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	m.Port = port
+	m.server = &ssh.Server{
+		Handler: m.handle,
+		PasswordHandler: func(ctx ssh.Context, password string) bool {
+			return ctx.User() == SMASHUser && password == SMASHPassword
+		},
+	}
+	go func() {
+		log.Printf("smash-clp ssh server listening on port %d\n", port)
+		if err := m.server.Serve(ln); err != nil && err != ssh.ErrServerClosed {
+			log.Printf("smash-clp ssh server on port %d failed: %v\n", port, err)
+		}
+	}()
+	return nil
+}
+
+// Close - stop the ssh server and drop all connections.
+func (m *MockSMASH) Close() error {
+	return m.server.Close()
+}
+
+// Get - return property name of target, with the mock locked.
+func (m *MockSMASH) Get(target, name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Targets[target][name]
+}
+
+// Set - set property name of target, with the mock locked.
+func (m *MockSMASH) Set(target, name, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "enabledstate" && value == "disabled" {
+		m.host = nil
+	}
+	m.Targets[target][name] = value
+}
+
+// smashTerm - one ssh session on the clp.
+type smashTerm struct {
+	m   *MockSMASH
+	s   ssh.Session
+	in  *bufio.Reader
+	cwd string
+}
+
+func (m *MockSMASH) handle(s ssh.Session) {
+	t := &smashTerm{m: m, s: s, in: bufio.NewReader(s), cwd: "/"}
+	m.mu.Lock()
+	fmt.Fprintf(s, "\nUser:%s logged-in to %s.(127.0.0.1)\n", s.User(), m.Targets["/system1"]["number"])
+	fmt.Fprintf(s, "%s Firmware Version %s\n\n", m.Targets["/map1"]["name"], m.Targets["/map1/firmware1"]["version"])
+	m.mu.Unlock()
+	t.prompt()
+	for {
+		line, err := t.in.ReadString('\n')
+		if err != nil {
+			s.Exit(1)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		fmt.Fprintf(s, "%s\n", line)
+
+		args := strings.Fields(line)
+		switch {
+		case len(args) > 0 && args[0] == "exit":
+			fmt.Fprint(s, "\nCLI session stopped\n")
+			s.Exit(0)
+			return
+		case len(args) > 0 && args[0] == "vsp":
+			t.vsp()
+		default:
+			m.mu.Lock()
+			out := t.exec(args)
+			m.mu.Unlock()
+			fmt.Fprint(s, out)
+		}
+		t.prompt()
+	}
+}
+
+func (t *smashTerm) prompt() {
+	if t.m.Name == "" {
+		fmt.Fprint(t.s, "-> ")
+		return
+	}
+	fmt.Fprintf(t.s, "<%s>%s-> ", t.cwd, t.m.Name)
+}
+
+// completed - the output of a command that worked.
+func completed(body string) string {
+	return "\nstatus=0\nstatus_tag=COMMAND COMPLETED\n" + time.Now().Format(time.ANSIC) + "\n\n" + body + "\n\n"
+}
+
+// failed - the output of a command that did not, with the error tag and
+// why.
+func failed(tag, why string) string {
+	return "\nstatus=2\nstatus_tag=COMMAND PROCESSING FAILED\nerror_tag=" + tag + "\n" +
+		time.Now().Format(time.ANSIC) + "\n\n" + why + "\n\n"
+}
+
+// exec - run a clp command, return its output.  m.mu is held.
+func (t *smashTerm) exec(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	verb, args := args[0], args[1:]
+	force := false
+	if len(args) > 0 && (args[0] == "-f" || args[0] == "-force") {
+		force, args = true, args[1:]
+	}
+	target := t.cwd
+	if len(args) > 0 && !strings.Contains(args[0], "=") {
+		target, args = t.resolve(args[0]), args[1:]
+	}
+	props, ok := t.m.Targets[target]
+	if !ok && target != "/" {
+		return failed("COMMAND ERROR-UNSPECIFIED", "Invalid target "+target+".")
+	}
+
+	switch verb {
+	case "cd":
+		t.cwd = target
+		return completed(target)
+	case "show":
+		return t.show(target, args)
+	case "set":
+		for _, a := range args {
+			kv := strings.SplitN(a, "=", 2)
+			if _, ok := props[kv[0]]; !ok || len(kv) != 2 || kv[0] == "enabledstate" {
+				return failed("COMMAND ERROR-UNSPECIFIED", "Unrecognized or read-only property "+kv[0]+".")
+			}
+		}
+		for _, a := range args {
+			kv := strings.SplitN(a, "=", 2)
+			props[kv[0]] = kv[1]
+		}
+		return completed("")
+	case "start", "stop", "reset":
+		if target != "/system1" {
+			return failed("COMMAND ERROR-UNSPECIFIED", "Verb "+verb+" is not supported on "+target+".")
+		}
+		return t.power(verb, force)
+	}
+	return failed("COMMAND NOT RECOGNIZED", "Invalid command.")
+}
+
+// resolve - the absolute path of target from the current one.
+func (t *smashTerm) resolve(target string) string {
+	if !strings.HasPrefix(target, "/") {
+		target = t.cwd + "/" + target
+	}
+	return path.Clean(target)
+}
+
+func (t *smashTerm) show(target string, names []string) string {
+	props := t.m.Targets[target]
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", target)
+	if len(names) == 0 {
+		fmt.Fprintf(&b, "  Targets\n")
+		children := []string{}
+		for p := range t.m.Targets {
+			if p != target && path.Dir(p) == target {
+				children = append(children, path.Base(p))
+			}
+		}
+		sort.Strings(children)
+		for _, c := range children {
+			fmt.Fprintf(&b, "    %s\n", c)
+		}
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	fmt.Fprintf(&b, "  Properties\n")
+	for _, name := range names {
+		v, ok := props[name]
+		if !ok {
+			return failed("COMMAND ERROR-UNSPECIFIED", "Unrecognized property "+name+".")
+		}
+		fmt.Fprintf(&b, "    %s=%s\n", name, v)
+	}
+	fmt.Fprintf(&b, "  Verbs\n    cd version exit show set start stop reset vsp")
+	return completed(b.String())
+}
+
+// power - start, stop or reset /system1.  m.mu is held.
+func (t *smashTerm) power(verb string, force bool) string {
+	sys := t.m.Targets["/system1"]
+	on := sys["enabledstate"] == "enabled"
+	switch {
+	case verb == "start" && on:
+		return failed("COMMAND ERROR-UNSPECIFIED", "Server power already on.")
+	case verb == "start":
+		sys["enabledstate"] = "enabled"
+		return completed("Server powering on .......")
+	case !on:
+		return failed("COMMAND ERROR-UNSPECIFIED", "Server power already off.")
+	case verb == "stop":
+		// without -f the host is asked to shut down, which ours does at once.
+		t.m.host = nil
+		sys["enabledstate"] = "disabled"
+		return completed("Server powering off .......")
+	}
+	t.m.host = nil
+	return completed("Server resetting .......")
+}
+
+// vsp - pass what is typed to the host serial console until 'ESC ('.
+//   Only one session can have the console at a time.
+func (t *smashTerm) vsp() {
+	m := t.m
+	m.mu.Lock()
+	if m.console != nil {
+		m.mu.Unlock()
+		fmt.Fprint(t.s, failed("COMMAND ERROR-UNSPECIFIED", "Virtual Serial Port is currently in use by another session."))
+		return
+	}
+	m.console = t.s
+	m.mu.Unlock()
+
+	fmt.Fprint(t.s, "\nVirtual Serial Port Active: COM2\r\n Starting virtual serial port.\r\n Press 'ESC (' to return to the CLI Session.\r\n")
+	for {
+		b, err := t.in.ReadByte()
+		if err != nil {
+			break
+		}
+		data := []byte{b}
+		if b == esc {
+			next, err := t.in.ReadByte()
+			if err != nil || next == '(' {
+				break
+			}
+			data = append(data, next)
+		}
+		m.mu.Lock()
+		if m.Targets["/system1"]["enabledstate"] == "enabled" {
+			m.hostShell().input(data)
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	m.console = nil
+	m.mu.Unlock()
+	fmt.Fprint(t.s, "\r\n")
+}
+
+// hostShell - return the shell on the serial console.  m.mu is held.
+func (m *MockSMASH) hostShell() *hostShell {
+	if m.host == nil {
+		m.host = newHostShell(func(text string) {
+			if m.console != nil {
+				fmt.Fprint(m.console, text)
+			}
+		}, func() {
+			m.host = nil
+			m.Targets["/system1"]["enabledstate"] = "disabled"
+		})
+	}
+	return m.host
+}
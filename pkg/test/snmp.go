@@ -0,0 +1,82 @@
+package test
+
+import (
+	"fmt"
+	"net"
+)
+
+// maxSNMPUsers, maxSNMPTraps - the number of snmp v3 user and trap
+// destination slots.
+const (
+	maxSNMPUsers = 15
+	maxSNMPTraps = 15
+)
+
+func snmpScope() *Scope {
+	s := NewScope("snmp", "SNMP Settings",
+		prop("enabled", "SNMP Enabled", "no", "yes", "no"),
+		prop("snmp-port", "SNMP Port", "161"),
+		prop("community-str", "Access Community String", ""),
+		prop("community-access", "SNMP Community access", "Disabled", "Disabled", "Limited", "Full"),
+		prop("trap-community-str", "Trap Community String", "public"),
+		prop("sys-contact", "System Contact", "who@where"),
+		prop("sys-location", "System Location", "unknown"),
+		ro("Engine ID", "80 00 00 09 03 70 0F 6A D4 3B 12"),
+	)
+
+	for i := 1; i <= maxSNMPUsers; i++ {
+		u := NewScope(fmt.Sprintf("v3users %d", i), fmt.Sprintf("User %d", i),
+			prop("v3security-name", "Security Name", ""),
+			prop("v3security-level", "Security Level", "authpriv", "noauthnopriv", "authnopriv", "authpriv"),
+			prop("v3proto", "Auth Type", "SHA", "MD5", "SHA"),
+			&Prop{Name: "v3auth-key", Secret: true, Validate: passwordPolicy},
+			prop("v3priv-proto", "Privacy Type", "AES", "DES", "AES"),
+			&Prop{Name: "v3priv-auth-key", Secret: true, Validate: passwordPolicy},
+		)
+		// like the cimc, clearing the name deletes the user.
+		u.OnCommit = func(t *Term, changed map[string]string) {
+			if name, ok := changed["v3security-name"]; ok && name == "" {
+				u.Set("v3security-level", "authpriv")
+				u.Set("v3proto", "SHA")
+				u.Set("v3auth-key", "")
+				u.Set("v3priv-proto", "AES")
+				u.Set("v3priv-auth-key", "")
+			}
+		}
+		s.Add(u)
+	}
+
+	for i := 1; i <= maxSNMPTraps; i++ {
+		d := NewScope(fmt.Sprintf("trap-destinations %d", i), fmt.Sprintf("Trap Destination %d", i),
+			prop("enabled", "Enabled", "no", "yes", "no"),
+			prop("version", "SNMP version", "2", "1", "2", "3"),
+			prop("type", "Trap type", "trap", "trap", "inform"),
+			prop("user", "User", ""),
+			prop("trap-addr", "Trap Destination Address", "0.0.0.0"),
+			prop("trap-port", "Trap Destination Port", "162"),
+		)
+		d.Commands["sendSNMPtrap"] = sendSNMPTrap
+		s.Add(d)
+	}
+	return s
+}
+
+// sendSNMPTrap - send a test trap to the trap destination in scope.  The
+// mock does not encode snmp, the datagram only says what it is.
+func sendSNMPTrap(t *Term, args []string) {
+	if t.Scope.Parent().Get("enabled") != "yes" {
+		t.Errorf("SNMP is not enabled")
+		return
+	}
+	if t.Scope.Get("enabled") != "yes" {
+		t.Errorf("Trap destination is not enabled")
+		return
+	}
+	addr := net.JoinHostPort(t.Scope.Get("trap-addr"), t.Scope.Get("trap-port"))
+	msg := fmt.Sprintf("snmp v%s %s from %s: test trap", t.Scope.Get("version"), t.Scope.Get("type"), t.Mock.Serial)
+	if err := sendUDP(addr, msg); err != nil {
+		t.Errorf("Failed to send test trap to %s: %v", addr, err)
+		return
+	}
+	t.Printf("SNMP Test Trap sent to the destination\n")
+}
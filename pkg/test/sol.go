@@ -0,0 +1,44 @@
+package test
+
+import "strings"
+
+const ctrlX = 0x18
+
+func solScope() *Scope {
+	return NewScope("sol", "Serial Over LAN",
+		prop("enabled", "Enabled", "no", "yes", "no"),
+		prop("baud-rate", "Baud Rate(bps)", "115200", "9600", "19200", "38400", "57600", "115200"),
+		prop("comport", "Com Port", "com0", "com0", "com1"),
+		prop("ssh-port", "SSH Port", "2400"),
+	)
+}
+
+// connect - 'connect host' attaches to the host's serial console, which
+// echoes what it is sent, until ctrl-X.  With serial over lan disabled the
+// cimc says nothing and the session hangs, like the real one.
+func connect(t *Term, args []string) {
+	if len(args) != 2 || args[1] != "host" {
+		t.Errorf("Usage: connect host")
+		return
+	}
+	if t.Mock.Root.Child("sol").Get("enabled") != "yes" {
+		t.ReadUntil(ctrlX)
+		return
+	}
+	t.Printf("CISCO Serial Over LAN:\nPress Ctrl+x to Exit the session\n")
+	for {
+		b, err := t.in.ReadByte()
+		if err != nil {
+			return
+		}
+		if b == ctrlX {
+			break
+		}
+		t.Printf("%s", strings.Replace(string(b), "\n", "\r\n", -1))
+	}
+	// drop whatever else came with ctrl-X on its line.
+	if t.in.Buffered() > 0 {
+		t.ReadLine()
+	}
+	t.Printf("\n")
+}
@@ -0,0 +1,242 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// driveSizeMB - the size of every mock physical drive.
+const driveSizeMB = 1143455
+
+// storageScope - the raid controller in slot MRAID with eight drives, the
+// last two with a foreign configuration, and no virtual drives.
+func storageScope() *Scope {
+	s := NewScope("storageadapter MRAID", "PCI Slot MRAID",
+		ro("Health", "Good"),
+		ro("Controller Status", "Optimal"),
+		ro("Product Name", "Cisco 12G Modular Raid Controller with 2GB cache"),
+		ro("Serial Number", "SK93460773"),
+		ro("Firmware Package Build", "51.10.0-3151"),
+		ro("Product ID", "Broadcom / LSI"),
+	)
+	s.Kinds = []string{"virtual-drive"}
+	for i := 1; i <= 8; i++ {
+		foreign := "no"
+		if i > 6 {
+			foreign = "yes"
+		}
+		s.Add(NewScope(fmt.Sprintf("physical-drive %d", i), fmt.Sprintf("Physical Drive %d", i),
+			ro("Controller", "MRAID"),
+			ro("Health", "Good"),
+			ro("Status", "Unconfigured Good"),
+			ro("Manufacturer", "SEAGATE"),
+			ro("Model", "ST1200MM0088"),
+			ro("Type", "HDD"),
+			ro("Interface Type", "SAS"),
+			ro("Coerced Size", fmt.Sprintf("%d MB", driveSizeMB)),
+			ro("Foreign Config", foreign),
+		))
+	}
+	s.Commands["create-virtual-drive"] = createVirtualDrive
+	s.Commands["clear-foreign-config"] = clearForeignConfig
+	return s
+}
+
+// unusedDrives - the drives of the controller s a virtual drive can use.
+func unusedDrives(s *Scope) map[string]*Scope {
+	pds := map[string]*Scope{}
+	for _, c := range s.Children {
+		if strings.HasPrefix(c.Name, "physical-drive ") &&
+			c.Get("Status") == "Unconfigured Good" && c.Get("Foreign Config") == "no" {
+			pds[strings.TrimPrefix(c.Name, "physical-drive ")] = c
+		}
+	}
+	return pds
+}
+
+// createVirtualDrive - the 'create-virtual-drive' wizard, asking for the
+// raid level, the drives of each span, the name and the size.
+func createVirtualDrive(t *Term, args []string) {
+	s := t.Scope
+	ask := func(q string) (string, bool) {
+		t.Printf("%s--> ", q)
+		a, err := t.ReadLine()
+		if err != nil {
+			return "", false
+		}
+		t.Printf("%s\n", a)
+		return strings.TrimSpace(a), true
+	}
+
+	a, ok := ask("Please enter RAID level (0, 1, 5, 6, 10)")
+	if !ok {
+		return
+	}
+	level, err := strconv.Atoi(a)
+	min := map[int]int{0: 1, 1: 2, 5: 3, 6: 4, 10: 2}
+	if _, ok := min[level]; err != nil || !ok {
+		t.Errorf("Invalid RAID level '%s'", a)
+		return
+	}
+
+	unused := unusedDrives(s)
+	ids := []string{}
+	for id := range unused {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	t.Printf("Please choose from the following %d unused physical drives:\n", len(ids))
+	t.Printf("    ID  Size(MB)  Model\n")
+	for _, id := range ids {
+		t.Printf("    %2s  %d   %s\n", id, driveSizeMB, unused[id].Get("Model"))
+	}
+
+	spans := 1
+	if level == 10 {
+		spans = 2
+	}
+	used := []string{}
+	for i := 0; i < spans; i++ {
+		a, ok := ask(fmt.Sprintf("Enter comma-separated PDs for span %d from above list", i))
+		if !ok {
+			return
+		}
+		span := strings.Split(a, ",")
+		for _, id := range span {
+			if unused[strings.TrimSpace(id)] == nil {
+				t.Errorf("Physical drive '%s' is not available", id)
+				return
+			}
+			used = append(used, strings.TrimSpace(id))
+		}
+		if len(span) < min[level] || level == 1 && len(span)%2 != 0 || level == 10 && len(span)%2 != 0 {
+			t.Errorf("Invalid number of physical drives %d for RAID %d", len(span), level)
+			return
+		}
+	}
+
+	data := len(used)
+	switch level {
+	case 1, 10:
+		data /= 2
+	case 5:
+		data--
+	case 6:
+		data -= 2
+	}
+	max := data * driveSizeMB
+
+	name, ok := ask("Please enter Virtual Drive name (15 characters maximum)")
+	if !ok {
+		return
+	}
+	if name == "" || len(name) > 15 || strings.ContainsAny(name, " \t") {
+		t.Errorf("Invalid virtual drive name '%s'", name)
+		return
+	}
+	a, ok = ask(fmt.Sprintf("Please enter Virtual Drive size in MB, GB, or TB (Default: %d MB)", max))
+	if !ok {
+		return
+	}
+	size := max
+	if a != "" {
+		f := strings.Fields(a)
+		n, err := strconv.Atoi(f[0])
+		if err == nil && len(f) == 2 {
+			switch strings.ToUpper(f[1]) {
+			case "GB":
+				n *= 1024
+			case "TB":
+				n *= 1024 * 1024
+			case "MB":
+			default:
+				err = fmt.Errorf("bad unit")
+			}
+		}
+		if err != nil || n <= 0 || n > max {
+			t.Errorf("Invalid virtual drive size '%s'", a)
+			return
+		}
+		size = n
+	}
+
+	t.Printf("New virtual drive will have the following characteristics:\n")
+	t.Printf("  - Spans: '[%s]'\n", strings.Join(used, "."))
+	t.Printf("  - RAID level: '%d'\n", level)
+	t.Printf("  - Name: '%s'\n", name)
+	t.Printf("  - Size: %d MB\n\n", size)
+	if a, ok := ask("OK? (y or n)"); !ok || a != "y" {
+		return
+	}
+
+	id := 0
+	for s.Child(fmt.Sprintf("virtual-drive %d", id)) != nil {
+		id++
+	}
+	for _, pd := range used {
+		unused[pd].Set("Status", "Online")
+	}
+	vd := NewScope(fmt.Sprintf("virtual-drive %d", id), fmt.Sprintf("Virtual Drive %d", id),
+		ro("Name", name),
+		ro("RAID Level", fmt.Sprintf("RAID %d", level)),
+		ro("Size", fmt.Sprintf("%d MB", size)),
+		ro("Status", "Optimal"),
+		ro("Health", "Good"),
+		ro("Boot Drive", "false"),
+		ro("Physical Drives", strings.Join(used, ",")),
+	)
+	vd.Commands["delete-virtual-drive"] = deleteVirtualDrive
+	vd.Commands["set-boot-drive"] = setBootDrive
+	s.Add(vd)
+}
+
+// deleteVirtualDrive - '/chassis/storageadapter X/virtual-drive N/delete-virtual-drive'
+func deleteVirtualDrive(t *Term, args []string) {
+	vd := t.Scope
+	if !t.Confirm(fmt.Sprintf("This operation will delete %s, all data on it will be lost.", vd.Title)) {
+		return
+	}
+	ctrl := vd.Parent()
+	for _, pd := range strings.Split(vd.Get("Physical Drives"), ",") {
+		if c := ctrl.Child("physical-drive " + pd); c != nil {
+			c.Set("Status", "Unconfigured Good")
+		}
+	}
+	ctrl.Remove(vd.Name)
+	t.Scope = ctrl
+}
+
+// setBootDrive - '/chassis/storageadapter X/virtual-drive N/set-boot-drive'
+func setBootDrive(t *Term, args []string) {
+	if !t.Confirm(fmt.Sprintf("This operation will make %s the boot drive.", t.Scope.Title)) {
+		return
+	}
+	for _, c := range t.Scope.Parent().Children {
+		if strings.HasPrefix(c.Name, "virtual-drive ") {
+			c.Set("Boot Drive", "false")
+		}
+	}
+	t.Scope.Set("Boot Drive", "true")
+}
+
+// clearForeignConfig - '/chassis/storageadapter X/clear-foreign-config'
+func clearForeignConfig(t *Term, args []string) {
+	foreign := []*Scope{}
+	for _, c := range t.Scope.Children {
+		if c.Get("Foreign Config") == "yes" {
+			foreign = append(foreign, c)
+		}
+	}
+	if len(foreign) == 0 {
+		t.Errorf("No foreign configuration found")
+		return
+	}
+	if !t.Confirm("This operation will clear the foreign configuration of all physical drives.") {
+		return
+	}
+	for _, c := range foreign {
+		c.Set("Foreign Config", "no")
+	}
+}
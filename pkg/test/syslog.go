@@ -0,0 +1,59 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// maxSyslogServers - the number of remote syslog server slots.
+const maxSyslogServers = 3
+
+func logScope() *Scope {
+	s := NewScope("log", "Log Settings",
+		prop("local-syslog-severity", "Local Syslog Severity", "debug", severities...),
+		prop("remote-syslog-severity", "Remote Syslog Severity", "informational", severities...),
+	)
+	for i := 1; i <= maxSyslogServers; i++ {
+		s.Add(NewScope(fmt.Sprintf("server %d", i), fmt.Sprintf("Syslog Server %d", i),
+			prop("server-ip", "Server IP Address", "0.0.0.0"),
+			prop("server-port", "Server Port", "514"),
+			prop("protocol", "Protocol", "udp", "udp", "tcp"),
+			prop("enabled", "Enabled", "no", "yes", "no"),
+		))
+	}
+
+	// send-test-syslog - send a test message to the enabled udp servers.
+	s.Commands["send-test-syslog"] = func(t *Term, args []string) {
+		sent := 0
+		for _, srv := range t.Scope.Children {
+			if srv.Get("enabled") != "yes" || srv.Get("protocol") != "udp" {
+				continue
+			}
+			addr := net.JoinHostPort(srv.Get("server-ip"), srv.Get("server-port"))
+			if err := sendUDP(addr, "<14>"+t.Mock.Serial+" CIMC: test syslog message"); err != nil {
+				t.Errorf("Failed to send test syslog to %s: %v", addr, err)
+				return
+			}
+			sent++
+		}
+		if sent == 0 {
+			t.Errorf("No remote syslog server enabled")
+			return
+		}
+		t.Printf("Test syslog sent to %d server(s)\n", sent)
+	}
+	return s
+}
+
+var severities = strings.Fields("emergency alert critical error warning notice informational debug")
+
+func sendUDP(addr, msg string) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(msg))
+	return err
+}
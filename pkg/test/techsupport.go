@@ -0,0 +1,108 @@
+package test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// techSupportStep - how far a tech-support collection gets between two
+// 'show'.
+const techSupportStep = 25
+
+func techSupportScope(m *MockCIMC) *Scope {
+	s := NewScope("tech-support", "Tech Support",
+		prop("remote-ip", "Server Address", "0.0.0.0"),
+		prop("remote-port", "Server Port", "80"),
+		prop("remote-path", "Path", ""),
+		prop("remote-protocol", "Protocol", "tftp", "tftp", "ftp", "sftp", "scp", "http"),
+		prop("remote-username", "Username", ""),
+		&Prop{Name: "remote-password", Secret: true},
+		ro("Progress (%)", "0"),
+		ro("Status", "NOT STARTED"),
+	)
+
+	s.Commands["start"] = func(t *Term, args []string) {
+		if st := s.Get("Status"); st == "COLLECTING" || st == "UPLOADING" {
+			t.Errorf("Tech support collection is already in progress")
+			return
+		}
+		if s.Get("remote-ip") == "0.0.0.0" || s.Get("remote-path") == "" {
+			t.Errorf("Remote server and path must be set")
+			return
+		}
+		s.Set("Progress (%)", "0")
+		s.Set("Status", "COLLECTING")
+		t.Printf("Tech Support upload started.\n")
+	}
+
+	// progress on every look, and upload when collected.
+	s.OnShow = func(s *Scope) {
+		if s.Get("Status") != "COLLECTING" {
+			return
+		}
+		pct, _ := strconv.Atoi(s.Get("Progress (%)"))
+		if pct += techSupportStep; pct < 100 {
+			s.Set("Progress (%)", strconv.Itoa(pct))
+			return
+		}
+		s.Set("Progress (%)", "100")
+		if err := uploadFile(s, "techsupport", techSupportBundle(m)); err != nil {
+			s.Set("Status", "FAILED: "+err.Error())
+			return
+		}
+		s.Set("Status", "COMPLETED")
+	}
+	return s
+}
+
+// uploadFile - send data to the remote server of scope s.  The mock only
+// speaks http, with PUT.
+func uploadFile(s *Scope, what string, data []byte) error {
+	if proto := s.Get("remote-protocol"); proto != "http" {
+		return fmt.Errorf("%s upload over %s is not supported by the mock", what, proto)
+	}
+	url := "http://" + net.JoinHostPort(s.Get("remote-ip"), s.Get("remote-port")) +
+		"/" + strings.TrimPrefix(s.Get("remote-path"), "/")
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if user := s.Get("remote-username"); user != "" {
+		req.SetBasicAuth(user, s.Get("remote-password"))
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s upload failed: %s", what, resp.Status)
+	}
+	return nil
+}
+
+// techSupportBundle - a small tar.gz with what a bundle has, sort of.
+func techSupportBundle(m *MockCIMC) []byte {
+	files := map[string]string{
+		"CIMC/messages":      "kernel: cimc booted\nsshd: accepted password for test\n",
+		"CIMC/show-tech.txt": m.Root.Child("cimc").detail() + m.Root.Child("chassis").detail(),
+		"HOST/sel.log":       "1 | 10/19/2026 10:00:00 | System Event | OEM System boot event\n",
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"CIMC/messages", "CIMC/show-tech.txt", "HOST/sel.log"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), ModTime: time.Now()})
+		tw.Write([]byte(files[name]))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxUsers - the number of user account slots.
+const maxUsers = 15
+
+// Users - the accounts the mock starts with, name to password.  Tests log
+// in as "test".
+var Users = [][2]string{
+	{"admin", "password"},
+	{"test", "test123"},
+}
+
+// userScopes - the user account slots, 'scope user <n>'.  Unused slots
+// have an empty name.
+func userScopes() []*Scope {
+	scopes := []*Scope{}
+	for i := 1; i <= maxUsers; i++ {
+		s := NewScope(fmt.Sprintf("user %d", i), fmt.Sprintf("User %d", i),
+			prop("name", "Name", ""),
+			prop("role", "Role", "read-only", "admin", "user", "read-only"),
+			prop("enabled", "Enabled", "no", "yes", "no"),
+			&Prop{Name: "password", Secret: true, Validate: passwordPolicy},
+		)
+		if i <= len(Users) {
+			s.Set("name", Users[i-1][0])
+			s.Set("password", Users[i-1][1])
+			s.Set("role", "admin")
+			s.Set("enabled", "yes")
+		}
+
+		s.Commands["clear"] = func(t *Term, args []string) {
+			t.Scope.Set("name", "")
+			t.Scope.Set("password", "")
+			t.Scope.Set("role", "read-only")
+			t.Scope.Set("enabled", "no")
+		}
+		scopes = append(scopes, s)
+	}
+	return scopes
+}
+
+func passwordPolicy(password string) error {
+	if len(password) < 8 {
+		return errors.New("Password must be at least 8 characters")
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func vmediaScope() *Scope {
+	s := NewScope("vmedia", "Virtual Media",
+		prop("enabled", "Enabled", "yes", "yes", "no"),
+		ro("Active Sessions", "0"),
+		prop("encryption-enabled", "Encryption Enabled", "no", "yes", "no"),
+		prop("low-power-usb-enabled", "Low Power USB Enabled", "yes", "yes", "no"),
+	)
+	s.Kinds = []string{"mappings"}
+	for _, kind := range []string{"www", "nfs", "cifs"} {
+		s.Commands["map-"+kind] = mapVMedia
+	}
+	s.Commands["unmap"] = func(t *Term, args []string) {
+		if len(args) != 2 || t.Scope.Child("mappings "+args[1]) == nil {
+			t.Errorf("Volume '%s' is not mapped", strings.Join(args[1:], " "))
+			return
+		}
+		t.Scope.Remove("mappings " + args[1])
+	}
+	return s
+}
+
+// mapVMedia - 'map-<type> <volume> <share> <file> [--mountOptions o]
+// [--username u] [--password]'.  The image is checked in the background,
+// like the cimc mounts it.
+func mapVMedia(t *Term, args []string) {
+	kind := strings.TrimPrefix(args[0], "map-")
+	if len(args) < 4 {
+		t.Errorf("Usage: %s <volume> <remote-share> <remote-file> [--mountOptions <options>] [--username <user>] [--password]", args[0])
+		return
+	}
+	vol, share, file := args[1], unquote(args[2]), unquote(args[3])
+	opts := map[string]string{}
+	for i := 4; i < len(args); i++ {
+		switch args[i] {
+		case "--mountOptions", "--username":
+			if i+1 == len(args) {
+				t.Errorf("Missing value for %s", args[i])
+				return
+			}
+			opts[args[i]] = unquote(args[i+1])
+			i++
+		case "--password":
+			pass, err := t.readSecret()
+			if err != nil {
+				t.Errorf("%v", err)
+				return
+			}
+			opts[args[i]] = pass
+		default:
+			t.Errorf("Invalid option '%s'", args[i])
+			return
+		}
+	}
+	if t.Scope.Child("mappings "+vol) != nil {
+		t.Errorf("Volume '%s' is already mapped", vol)
+		return
+	}
+
+	drive := "Removable"
+	if strings.HasSuffix(strings.ToLower(file), ".iso") {
+		drive = "CD"
+	}
+	mapping := NewScope("mappings "+vol, "Volume "+vol,
+		ro("Map-status", "In Progress"),
+		ro("Drive-type", drive),
+		ro("Remote-share", share),
+		ro("Remote-file", file),
+		ro("Mount-type", kind),
+		ro("Mount-options", opts["--mountOptions"]),
+		ro("Map-error", ""),
+	)
+	t.Scope.Add(mapping)
+
+	m := t.Mock
+	go func() {
+		err := mountImage(kind, share, file, opts["--username"], opts["--password"])
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if err != nil {
+			mapping.Set("Map-status", "Error")
+			mapping.Set("Map-error", err.Error())
+			return
+		}
+		mapping.Set("Map-status", "OK")
+	}()
+}
+
+// mountImage - check the image can be fetched.  For nfs and cifs we only
+// check the server port answers.
+func mountImage(kind, share, file, user, pass string) error {
+	switch kind {
+	case "www":
+		req, err := http.NewRequest("HEAD", strings.TrimSuffix(share, "/")+"/"+file, nil)
+		if err != nil {
+			return err
+		}
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("HTTP %s", resp.Status)
+		}
+		return nil
+	case "nfs":
+		host := strings.SplitN(share, ":", 2)[0]
+		return dialCheck(net.JoinHostPort(host, "2049"))
+	default:
+		host := strings.SplitN(strings.TrimPrefix(share, "//"), "/", 2)[0]
+		return dialCheck(net.JoinHostPort(host, "445"))
+	}
+}
+
+func dialCheck(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return fmt.Errorf("Unable to mount: %v", err)
+	}
+	return conn.Close()
+}
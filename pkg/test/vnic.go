@@ -0,0 +1,58 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// addVNICs - give the vic adapter s the default vNICs eth0 and eth1, and
+// 'create host-eth-if <name>'.  New vNICs get the next free MAC address.
+func addVNICs(s *Scope) {
+	s.Kinds = []string{"host-eth-if"}
+	next := 0x20
+	newVNIC := func(name string) *Scope {
+		v := vnicScope(name, fmt.Sprintf("70:0F:6A:D4:3B:%02X", next))
+		next++
+		return v
+	}
+	s.Add(newVNIC("eth0"), newVNIC("eth1"))
+
+	s.Commands["create"] = func(t *Term, args []string) {
+		if len(args) != 3 || args[1] != "host-eth-if" {
+			t.Errorf("Usage: create host-eth-if <name>")
+			return
+		}
+		if s.Child("host-eth-if "+args[2]) != nil {
+			t.Errorf("host-eth-if %s already exists", args[2])
+			return
+		}
+		v := newVNIC(args[2])
+		s.Add(v)
+		t.Scope = v
+	}
+}
+
+func vnicScope(name, mac string) *Scope {
+	return NewScope("host-eth-if "+name, "Name "+name,
+		&Prop{Name: "mtu", Label: "MTU", Value: "1500", Validate: func(v string) error {
+			if n, err := strconv.Atoi(v); err != nil || n < 1500 || n > 9000 {
+				return fmt.Errorf("MTU must be between 1500 and 9000")
+			}
+			return nil
+		}},
+		prop("uplink", "Uplink Port", "0", "0", "1"),
+		&Prop{Name: "mac-addr", Label: "MAC Address", Value: mac, Validate: func(v string) error {
+			_, err := net.ParseMAC(v)
+			return err
+		}},
+		&Prop{Name: "vlan", Label: "VLAN", Value: "NONE", Validate: func(v string) error {
+			if n, err := strconv.Atoi(v); v != "NONE" && (err != nil || n < 1 || n > 4094) {
+				return fmt.Errorf("VLAN must be NONE or between 1 and 4094")
+			}
+			return nil
+		}},
+		prop("vlan-mode", "VLAN Mode", "TRUNK", "TRUNK", "ACCESS"),
+		prop("boot", "PXE Boot", "disabled", "enabled", "disabled"),
+	)
+}